@@ -0,0 +1,52 @@
+package markov
+
+import (
+	"errors"
+	"math"
+)
+
+// Additive returns a Smoother estimating probabilities by add-k, or Laplace,
+// smoothing: the probability of a word following a prefix is as if it had
+// been observed k more times following it, as has every other word the Chain
+// knows and one more standing for all the words it does not, so that no text
+// has probability zero. k must be positive and finite; 1 is Laplace
+// smoothing, and the smaller k is, the less the probability of the words
+// observed is spread over those that were not.
+func Additive(k float64) Smoother {
+	return additive{k}
+}
+
+// additive is the Smoother of Additive.
+type additive struct {
+	k float64
+}
+
+func (a additive) estimator(src source) (estimator, error) {
+	if !(a.k > 0 && !math.IsInf(a.k, 1)) {
+		return nil, errors.New("markov: additive smoothing must add a positive, finite count")
+	}
+	return additiveEstimator{src, a.k}, nil
+}
+
+// additiveEstimator is the estimator of Additive.
+type additiveEstimator struct {
+	src source
+	k   float64
+}
+
+func (a additiveEstimator) prob(window Prefix[uint32], id uint32) (float64, error) {
+	s, err := a.src.get(window)
+	if err != nil {
+		return 0, err
+	}
+	// The words that may follow window, and one for any word not known.
+	words := a.src.words + 1
+	var n, total int
+	if s != nil {
+		total = s.total
+		if id != noID {
+			n = s.count(id)
+		}
+	}
+	return (float64(n) + a.k) / (float64(total) + a.k*float64(words)), nil
+}
@@ -0,0 +1,57 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChainAdditiveSmoothing(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a b. a c.")
+
+	// a, b., c. and the end may follow a prefix, and one word stands for any
+	// other.
+	for _, tt := range []struct {
+		tokens []string
+		want   float64
+	}{
+		{[]string{"a", "b."}, math.Log(3.0/7) + math.Log(2.0/7) + math.Log(2.0/6)},
+		{[]string{"b."}, math.Log(1.0/7) + math.Log(2.0/6)},
+		{[]string{"zebra"}, math.Log(1.0/7) + math.Log(1.0/5)},
+	} {
+		if got := c.Score(tt.tokens, WithSmoother(Additive(1))); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Score(%q) = %v, want %v", tt.tokens, got, tt.want)
+		}
+	}
+
+	// The probabilities of every word following a prefix, and of one not
+	// known, sum to 1.
+	a, err := Additive(0.5).estimator(c.source())
+	if err != nil {
+		t.Fatalf("estimator error: %v", err)
+	}
+	for _, window := range []Prefix[uint32]{{c.vocab.id("a")}, {beginID}, {c.vocab.id("b.")}} {
+		sum := 0.0
+		for _, id := range []uint32{endID, noID} {
+			p, _ := a.prob(window, id)
+			sum += p
+		}
+		for id := firstID; int(id) < len(c.vocab.words); id++ {
+			p, _ := a.prob(window, id)
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("probabilities following %v sum to %v, want 1", window, sum)
+		}
+	}
+
+	if p, err := c.Perplexity(strings.NewReader("c. a zebra."), WithSmoother(Additive(0.1))); err != nil || math.IsInf(p, 1) {
+		t.Errorf("Perplexity of unseen words = %v, %v, want finite", p, err)
+	}
+	for _, k := range []float64{0, -1, math.Inf(1)} {
+		if _, err := c.Perplexity(strings.NewReader("a"), WithSmoother(Additive(k))); err == nil {
+			t.Errorf("Perplexity with Additive(%v) succeeded, want error", k)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package markov
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// GenerateAround writes to w a text holding seed in its middle, extended
+// backwards by at most n/2 words generated from reverse, a Chain returned
+// by Reverse of this one, and forwards by words generated from the Chain up
+// to n words in all besides seed, as Generate would write them. The words
+// before seed start a text or sentence unless n/2 of them run out first.
+//
+// opts apply to both directions, the prompt of each being seed, and a stop
+// sequence WithStop ends either. WithSentenceCase capitalizes the whole
+// text, seed included. GenerateAround returns an error if opts include
+// WithMaxChars or WithCompleteSentences, which would cut both directions
+// short, or if seed has fewer words than the Chain's prefixes, since it
+// would stand for the start of a text going forwards and the end of one
+// going backwards, and so could be neither preceded nor followed.
+func (c *Chain[T]) GenerateAround(w io.Writer, reverse *Chain[T], n int, seed string, opts ...GenerateOption) error {
+	if !reverse.reversed || c.reversed {
+		return errors.New("markov: GenerateAround needs a chain and its reverse")
+	}
+	cfg, err := newGenerateConfig(opts)
+	if err != nil {
+		return err
+	}
+	if cfg.maxChars > 0 || cfg.completeSentences {
+		return errors.New("markov: GenerateAround does not support WithMaxChars or WithCompleteSentences")
+	}
+	seedWords := c.split(seed)
+	if len(seedWords) < c.prefixLen {
+		return fmt.Errorf("markov: seed of %d words is shorter than the chain's prefixes of %d", len(seedWords), c.prefixLen)
+	}
+	sub := *cfg
+	sub.prompt = seed
+	sub.sentenceCase = false
+
+	ctx := context.Background()
+	var words []T
+	collect := func(word T) bool {
+		words = append(words, word)
+		return true
+	}
+	if err := reverse.walk(ctx, n/2, &sub, collect); err != nil {
+		return err
+	}
+	before := len(words)
+	words = append(words, seedWords...)
+	if err := c.walk(ctx, n-before, &sub, collect); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var caser *sentenceCaser[T]
+	if cfg.sentenceCase {
+		caser = c.newSentenceCaser(nil, cfg.tidy)
+	}
+	space := c.spacer(cfg.tidy)
+	for _, word := range words {
+		if caser != nil {
+			word = caser.capitalize(word)
+		}
+		if _, err := bw.WriteString(space(word)); err != nil {
+			return err
+		}
+		if err := c.writeToken(bw, word); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,54 @@
+package markov
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestChainGenerateAround(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "i will love you forever and ever")
+	rev, err := c.Reverse()
+	if err != nil {
+		t.Fatalf("Reverse error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		n    int
+		want string
+	}{
+		{10, "i will love you forever and ever"},
+		{4, "i will love you forever and"},
+		{1, "love you forever"},
+		{0, "love you"},
+	} {
+		var b strings.Builder
+		if err := c.GenerateAround(&b, rev, tt.n, "love you", WithRand(rand.New(rand.NewSource(1)))); err != nil {
+			t.Fatalf("GenerateAround(%d) error: %v", tt.n, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("GenerateAround(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+
+	var b strings.Builder
+	if err := c.GenerateAround(&b, rev, 10, "love you", WithSentenceCase()); err != nil {
+		t.Fatalf("GenerateAround error: %v", err)
+	}
+	if got, want := b.String(), "I will love you forever and ever"; got != want {
+		t.Errorf("GenerateAround with sentence case = %q, want %q", got, want)
+	}
+
+	if err := c.GenerateAround(&b, c, 10, "you"); err == nil {
+		t.Error("GenerateAround with a chain not reversed succeeded, want error")
+	}
+	for _, opt := range []GenerateOption{WithMaxChars(10), WithCompleteSentences()} {
+		if err := c.GenerateAround(&b, rev, 10, "love you", opt); err == nil {
+			t.Error("GenerateAround with a limit on the text succeeded, want error")
+		}
+	}
+	if err := c.GenerateAround(&b, rev, 10, "you"); err == nil {
+		t.Error("GenerateAround with a seed shorter than the prefixes succeeded, want error")
+	}
+}
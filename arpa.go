@@ -0,0 +1,287 @@
+package markov
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// arpaUnknown is the word ARPA files reserve for unknown words.
+const arpaUnknown = "<unk>"
+
+// ExportARPA writes the Chain to w as an n-gram language model in the ARPA
+// format read by speech recognition and NLP toolkits, with n-grams of every
+// order up to one more than the prefix length.
+//
+// The start of a text or sentence is written as Begin, and its end, recorded
+// by a Chain created WithMarkers, as End. Each n-gram's probability is the
+// number of times it was observed divided by the number of times its first
+// n-1 words were observed followed by any word, which is the probability
+// Generate picks with at the highest order. Since these probabilities leave
+// none over for shorter n-grams, no backoff weights are written.
+//
+// Words are written as fmt.Sprint formats them, and must be nonempty, free
+// of white space and distinct from Begin, End and "<unk>", so Chains created
+// in Lines or Paragraphs mode, for example, cannot be exported.
+func (c *Chain[T]) ExportARPA(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, len(c.vocab.words))
+	names[beginID], names[endID] = Begin, End
+	for i, word := range c.vocab.words[firstID:] {
+		name := fmt.Sprint(word)
+		if name == "" || strings.ContainsFunc(name, unicode.IsSpace) ||
+			name == Begin || name == End || name == arpaUnknown {
+			return fmt.Errorf("markov: word %q cannot be written in ARPA format", name)
+		}
+		names[firstID+uint32(i)] = name
+	}
+
+	// counts[m-1] holds the count of each m-gram, and totals[m-1] the count
+	// of each m-gram's first m-1 words followed by any word, by key.
+	order := c.prefixLen + 1
+	counts := make([]map[string]int, order)
+	totals := make([]map[string]int, order)
+	for m := range counts {
+		counts[m], totals[m] = make(map[string]int), make(map[string]int)
+	}
+	err := c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			// The shorter n-grams are counted from the full prefixes.
+			return true
+		}
+		gram := arpaHistory(unkey(k))
+		for i, id := range s.words {
+			gram := append(slices.Clip(gram), id)
+			for m := 1; m <= len(gram); m++ {
+				g := gram[len(gram)-m:]
+				counts[m-1][key(g)] += s.counts[i]
+				totals[m-1][key(g[:m-1])] += s.counts[i]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("markov: exporting chain: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `\data\`)
+	for m, grams := range counts {
+		n := len(grams)
+		if m == 0 {
+			n++ // Begin
+		}
+		fmt.Fprintf(bw, "ngram %d=%d\n", m+1, n)
+	}
+	for m, grams := range counts {
+		fmt.Fprintf(bw, "\n\\%d-grams:\n", m+1)
+		if m == 0 {
+			// Begin is never predicted, so it has the conventional
+			// probability of 10^-99.
+			fmt.Fprintf(bw, "-99\t%s\n", Begin)
+		}
+		entries := make([][]string, 0, len(grams))
+		for k := range grams {
+			ids := unkey(k)
+			entry := make([]string, len(ids)+1)
+			for i, id := range ids {
+				entry[i+1] = names[id]
+			}
+			p := float64(grams[k]) / float64(totals[m][key(ids[:m])])
+			entry[0] = strconv.FormatFloat(math.Log10(p), 'f', 6, 64)
+			entries = append(entries, entry)
+		}
+		slices.SortFunc(entries, func(a, b []string) int {
+			return slices.Compare(a[1:], b[1:])
+		})
+		for _, entry := range entries {
+			fmt.Fprintf(bw, "%s\t%s\n", entry[0], strings.Join(entry[1:], " "))
+		}
+	}
+	fmt.Fprintln(bw, "\n\\end\\")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("markov: exporting chain: %w", err)
+	}
+	return nil
+}
+
+// arpaHistory returns the word IDs of prefix as they start an n-gram, with
+// the padding at the start of a text left out but for a single beginID.
+func arpaHistory(prefix Prefix[uint32]) Prefix[uint32] {
+	start := 0
+	for start < len(prefix) && prefix[start] == beginID {
+		start++
+	}
+	return prefix[max(start-1, 0):]
+}
+
+// arpaScale is the count ImportARPA gives a suffix of probability one.
+const arpaScale = 1_000_000
+
+// ImportARPA reads an n-gram language model in the ARPA format from r and
+// returns a Chain of strings that generates from it, created WithMarkers and
+// then configured by opts, so that models trained by other toolkits can be
+// generated from.
+//
+// The Chain's prefix length is one less than the model's highest order. Its
+// prefixes are the histories of the n-grams of that order, along with the
+// shorter histories starting with Begin, which start a sentence; each
+// history's suffixes are counted in proportion to their probabilities.
+// Shorter n-grams not starting with Begin, backoff weights, and n-grams
+// containing "<unk>" are not needed for generation and are ignored.
+func ImportARPA(r io.Reader, opts ...Option) (*Chain[string], error) {
+	p := &arpaParser{sc: bufio.NewScanner(r)}
+	order, err := p.header()
+	if err != nil {
+		return nil, err
+	}
+	if order < 2 {
+		return nil, errors.New("markov: importing ARPA model: a model of unigrams has no prefixes")
+	}
+
+	var (
+		c        = New[string](order-1, append([]Option{WithMarkers()}, opts...)...)
+		prefixes = make(map[string]*savedPrefix)
+		keys     []string
+	)
+	for m := 1; m <= order; m++ {
+		if err := p.section(m); err != nil {
+			return nil, err
+		}
+		for p.next() && !strings.HasPrefix(p.line, `\`) {
+			fields := strings.Fields(p.line)
+			if len(fields) != m+1 && len(fields) != m+2 {
+				return nil, p.errorf("want %d words and a probability", m)
+			}
+			logprob, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil || logprob > 0 {
+				return nil, p.errorf("invalid log probability %q", fields[0])
+			}
+			words := fields[1 : m+1]
+			if m == 1 || (m < order && words[0] != Begin) || slices.Contains(words, arpaUnknown) {
+				continue
+			}
+			if slices.Contains(words[1:], Begin) {
+				return nil, p.errorf("%s within an n-gram", Begin)
+			}
+			if slices.Contains(words[:m-1], End) {
+				return nil, p.errorf("%s within an n-gram", End)
+			}
+
+			prefix := make(Prefix[uint32], order-m, order-1)
+			for _, word := range words[:m-1] {
+				prefix = append(prefix, arpaID(c, word))
+			}
+			k := key(prefix)
+			sp := prefixes[k]
+			if sp == nil {
+				sp = &savedPrefix{Words: prefix}
+				prefixes[k] = sp
+				keys = append(keys, k)
+			}
+			sp.Next = append(sp.Next, arpaID(c, words[m-1]))
+			sp.Counts = append(sp.Counts, max(1, int(math.Round(math.Pow(10, logprob)*arpaScale))))
+		}
+		if err := p.err(); err != nil {
+			return nil, err
+		}
+	}
+	if p.line != `\end\` {
+		return nil, p.errorf(`want \end\`)
+	}
+
+	for _, k := range keys {
+		if err := c.insert(*prefixes[k]); err != nil {
+			return nil, fmt.Errorf("markov: importing ARPA model: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// arpaID returns the ID in c of word, a word of an ARPA model, which names
+// the start and end of a sentence Begin and End.
+func arpaID(c *Chain[string], word string) uint32 {
+	switch word {
+	case Begin:
+		return beginID
+	case End:
+		return endID
+	}
+	return c.vocab.intern(word)
+}
+
+// arpaParser reads the lines of an ARPA file, skipping blank lines.
+type arpaParser struct {
+	sc   *bufio.Scanner
+	line string
+	n    int // the number of the current line
+}
+
+// next advances to the next nonblank line, reporting whether there is one.
+func (p *arpaParser) next() bool {
+	for p.sc.Scan() {
+		p.n++
+		if p.line = strings.TrimSpace(p.sc.Text()); p.line != "" {
+			return true
+		}
+	}
+	p.line = ""
+	return false
+}
+
+// header reads the \data\ section and returns the model's highest order.
+func (p *arpaParser) header() (int, error) {
+	// Anything before \data\ is a comment.
+	for p.next() && p.line != `\data\` {
+	}
+	if p.line != `\data\` {
+		if err := p.err(); err != nil {
+			return 0, err
+		}
+		return 0, p.errorf(`missing \data\`)
+	}
+	order := 0
+	for p.next() && strings.HasPrefix(p.line, "ngram ") {
+		m, _, ok := strings.Cut(strings.TrimPrefix(p.line, "ngram "), "=")
+		if n, err := strconv.Atoi(strings.TrimSpace(m)); !ok || err != nil || n != order+1 {
+			return 0, p.errorf("invalid n-gram count %q", p.line)
+		}
+		order++
+	}
+	if order == 0 {
+		return 0, p.errorf("no n-gram counts")
+	}
+	return order, p.err()
+}
+
+// section checks that the current line starts the section of m-grams.
+func (p *arpaParser) section(m int) error {
+	if p.line != fmt.Sprintf(`\%d-grams:`, m) {
+		if err := p.err(); err != nil {
+			return err
+		}
+		return p.errorf(`want \%d-grams:`, m)
+	}
+	return nil
+}
+
+// err returns the error, if any, reading the lines.
+func (p *arpaParser) err() error {
+	if err := p.sc.Err(); err != nil {
+		return fmt.Errorf("markov: importing ARPA model: %w", err)
+	}
+	return nil
+}
+
+// errorf returns an error describing a problem with the current line.
+func (p *arpaParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("markov: importing ARPA model: line %d: %s", p.n, fmt.Sprintf(format, args...))
+}
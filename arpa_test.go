@@ -0,0 +1,92 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportARPA(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a b. a c.")
+
+	var b strings.Builder
+	if err := c.ExportARPA(&b); err != nil {
+		t.Fatalf("ExportARPA error: %v", err)
+	}
+	want := `\data\
+ngram 1=5
+ngram 2=5
+
+\1-grams:
+-99	<s>
+-0.477121	</s>
+-0.477121	a
+-0.778151	b.
+-0.778151	c.
+
+\2-grams:
+0.000000	<s> a
+-0.301030	a b.
+-0.301030	a c.
+0.000000	b. </s>
+0.000000	c. </s>
+
+\end\
+`
+	if got := b.String(); got != want {
+		t.Errorf("ExportARPA wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportARPAInvalidWord(t *testing.T) {
+	c := NewChain(1, WithMode(Lines))
+	mustBuild(t, c, "a b\nc d\n")
+	if err := c.ExportARPA(new(strings.Builder)); err == nil {
+		t.Error("ExportARPA of words with spaces succeeded, want error")
+	}
+}
+
+func TestImportARPA(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "a b. a c. b a.")
+	var b strings.Builder
+	if err := c.ExportARPA(&b); err != nil {
+		t.Fatalf("ExportARPA error: %v", err)
+	}
+
+	imported, err := ImportARPA(strings.NewReader("A comment.\n" + b.String()))
+	if err != nil {
+		t.Fatalf("ImportARPA error: %v", err)
+	}
+	if got := imported.PrefixLen(); got != 2 {
+		t.Errorf("PrefixLen() = %d, want 2", got)
+	}
+	want := map[string]map[string]int{
+		"":     {"a": 666667, "b": 333334}, // from rounded log probabilities,
+		"a":    {"b.": arpaScale / 2, "c.": arpaScale / 2},
+		"a b.": {End: arpaScale},
+		"a c.": {End: arpaScale},
+		"b":    {"a.": arpaScale},
+		"b a.": {End: arpaScale},
+	}
+	if got := chainCounts(imported); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported chain = %v, want %v", got, want)
+	}
+}
+
+func TestImportARPAInvalid(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"\\data\\\nngram 1=1\n\n\\1-grams:\n-1\ta\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\nngram 2=1\n\n\\1-grams:\n-1\ta\n\n\\2-grams:\n-1\ta\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\nngram 2=1\n\n\\1-grams:\n-1\ta\n\n\\2-grams:\nx\ta a\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\nngram 2=1\n\n\\1-grams:\n-1\ta\n\n\\2-grams:\n-1\ta <s>\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\nngram 2=1\n\n\\1-grams:\n-1\ta\n\n\\2-grams:\n-1\ta a\n",
+		"\\data\\\nngram 1=1\nngram 2=1\n\n\\1-grams:\n-1\ta\n\n\\2-grams:\n-1\ta a\n-1\ta a\n\n\\end\\\n",
+	} {
+		if _, err := ImportARPA(strings.NewReader(text)); err == nil {
+			t.Errorf("ImportARPA(%q) succeeded, want error", text)
+		}
+	}
+}
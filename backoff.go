@@ -0,0 +1,104 @@
+package markov
+
+import "slices"
+
+// WithBackoff makes the Chain also count the words following each shorter
+// context while it is built, from the last prefixLen-1 words down to none,
+// and makes Generate back off to the longest of them that has been observed
+// when a prefix has not, rather than stop, so that Chains built from small
+// corpora stop generating at an end instead of at the first prefix they
+// have never seen. Generating from a prefix the Chain has observed is
+// unchanged.
+//
+// A Chain backing off takes up to prefixLen+1 times the space.
+func WithBackoff() Option {
+	return func(o *options) {
+		o.backoff = true
+	}
+}
+
+// backingOff returns observe and add, which record the words passed them by
+// scan, wrapped to also pass add each word as a suffix of every context
+// shorter than its window, longest first, if the Chain backs off.
+func (c *Chain[T]) backingOff(observe func(Prefix[uint32], T) (uint32, error), add func(Prefix[uint32], uint32) error) (func(Prefix[uint32], T) (uint32, error), func(Prefix[uint32], uint32) error) {
+	if !c.backoff {
+		return observe, add
+	}
+	shorter := make(Prefix[uint32], c.prefixLen)
+	addShorter := func(window Prefix[uint32], id uint32) error {
+		for n := len(window) - 1; n >= 0; n-- {
+			if c.skips(n + 1) {
+				// The context is that of n+1 words, which skips its first.
+				continue
+			}
+			if err := add(shortened(shorter, window, n), id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	observeAll := func(window Prefix[uint32], word T) (uint32, error) {
+		id, err := observe(window, word)
+		if err != nil {
+			return id, err
+		}
+		return id, addShorter(window, id)
+	}
+	addAll := func(window Prefix[uint32], id uint32) error {
+		if err := add(window, id); err != nil {
+			return err
+		}
+		return addShorter(window, id)
+	}
+	return observeAll, addAll
+}
+
+// addCount records count more occurrences of the word with the given ID
+// following window, and following each shorter context of window if the
+// Chain backs off, as backingOff records them. c.mu must be held for
+// writing.
+func (c *Chain[T]) addCount(window Prefix[uint32], id uint32, count int) error {
+	if err := c.store.add(key(c.masked(window)), id, count); err != nil || !c.backoff {
+		return err
+	}
+	shorter := make(Prefix[uint32], len(window))
+	for n := len(window) - 1; n >= 0; n-- {
+		if c.skips(n + 1) {
+			continue
+		}
+		if err := c.store.add(key(c.masked(shortened(shorter, window, n))), id, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortened sets dst, which is as long as window, to the context of the
+// last n words of window, filling the rest with endID, which never precedes
+// a word of a text, and returns it.
+func shortened(dst, window Prefix[uint32], n int) Prefix[uint32] {
+	fill := len(window) - n
+	for i := range fill {
+		dst[i] = endID
+	}
+	copy(dst[fill:], window[fill:])
+	return dst
+}
+
+// isBackoffContext reports whether k is the key of one of the shorter
+// contexts a Chain created WithBackoff keeps among its prefixes, which
+// whatever reads the prefixes themselves leaves out.
+func (c *Chain[T]) isBackoffContext(k string) bool {
+	return contextLen(unkey(k)) != c.prefixLen
+}
+
+// fullPrefixes returns prefixes, those of a model of the Chain, without the
+// shorter contexts of a Chain created WithBackoff.
+func (c *Chain[T]) fullPrefixes(prefixes []savedPrefix) []savedPrefix {
+	if !c.backoff {
+		return prefixes
+	}
+	return slices.DeleteFunc(slices.Clone(prefixes), func(p savedPrefix) bool {
+		return contextLen(p.Words) != c.prefixLen
+	})
+}
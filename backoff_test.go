@@ -0,0 +1,181 @@
+package markov
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainBackoffCounts(t *testing.T) {
+	c := NewChain(2, WithBackoff())
+	mustBuild(t, c, "a b c x b d")
+
+	ids := func(words ...string) Prefix[uint32] {
+		window := make(Prefix[uint32], len(words))
+		for i, w := range words {
+			window[i] = c.vocab.id(w)
+		}
+		return window
+	}
+	for _, tt := range []struct {
+		window Prefix[uint32]
+		word   string
+		want   int
+	}{
+		{ids("a", "b"), "c", 1},
+		{Prefix[uint32]{endID, c.vocab.id("b")}, "c", 1},
+		{Prefix[uint32]{endID, c.vocab.id("b")}, "d", 1},
+		{Prefix[uint32]{endID, endID}, "b", 2},
+		{Prefix[uint32]{endID, endID}, "a", 1},
+		{Prefix[uint32]{endID, beginID}, "a", 1},
+	} {
+		s, err := c.get(tt.window)
+		if err != nil {
+			t.Fatalf("get(%v) error: %v", tt.window, err)
+		}
+		if got := s.count(c.vocab.id(tt.word)); got != tt.want {
+			t.Errorf("count of %s following %v = %d, want %d", tt.word, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestChainBackoffGenerate(t *testing.T) {
+	plain := NewChain(2)
+	mustBuild(t, plain, "a b c d")
+	if got, _ := plain.GenerateString(10); got != "a b c d" {
+		t.Fatalf("GenerateString without backoff = %q, want %q", got, "a b c d")
+	}
+
+	c := NewChain(2, WithBackoff())
+	mustBuild(t, c, "a b c d")
+	got, err := c.GenerateString(10)
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if words := strings.Fields(got); len(words) != 10 || strings.Join(words[:4], " ") != "a b c d" {
+		t.Errorf("GenerateString with backoff = %q, want 10 words starting with a b c d", got)
+	}
+
+	// A prompt the Chain has seen only the end of backs off to it.
+	got, _ = c.GenerateString(1, WithPrompt("z b"))
+	if got != "c" {
+		t.Errorf("GenerateString with prompt z b = %q, want %q", got, "c")
+	}
+
+	var buf bytes.Buffer
+	if err := c.SaveBinary(&buf); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loaded, err := LoadChain(&buf)
+	if err != nil {
+		t.Fatalf("LoadChain error: %v", err)
+	}
+	if !loaded.backoff {
+		t.Error("loaded chain does not back off")
+	}
+}
+
+func TestChainBackoffBuilds(t *testing.T) {
+	text := "The cat sat. The dog sat on the cat. A bird sang."
+	want := NewChain(2, WithMarkers(), WithBackoff())
+	mustBuild(t, want, text)
+
+	parallel := NewChain(2, WithMarkers(), WithBackoff())
+	if err := parallel.BuildParallel(context.Background(), strings.NewReader(text), 2); err != nil {
+		t.Fatalf("BuildParallel error: %v", err)
+	}
+	external := NewChain(2, WithMarkers(), WithBackoff())
+	if err := external.BuildExternal(context.Background(), strings.NewReader(text), WithMemoryLimit(100), WithTempDir(t.TempDir())); err != nil {
+		t.Fatalf("BuildExternal error: %v", err)
+	}
+	for name, c := range map[string]*Chain[string]{"parallel": parallel, "external": external} {
+		// Diff leaves out the shorter contexts, so compare them directly.
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: chain = %v, want %v as Build builds it", name, got, want)
+		}
+	}
+
+	// Scoring counts only the words of the text, not their shorter contexts.
+	plain := NewChain(2, WithMarkers())
+	mustBuild(t, plain, text)
+	if got, want := want.Score([]string{"The", "cat", "sat."}), plain.Score([]string{"The", "cat", "sat."}); got != want {
+		t.Errorf("Score with backoff = %v, want %v as without", got, want)
+	}
+}
+
+func TestChainBackoffReports(t *testing.T) {
+	// The shorter contexts are left out of what reports the prefixes, which
+	// are those of a Chain not backing off.
+	const text = "the cat sat. the dog sat."
+	c := NewChain(2, WithMarkers(), WithBackoff())
+	mustBuild(t, c, text)
+	plain := NewChain(2, WithMarkers())
+	mustBuild(t, plain, text)
+
+	gotStats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats error: %v", err)
+	}
+	if wantStats, _ := plain.Stats(); gotStats != wantStats {
+		t.Errorf("Stats = %+v, want %+v", gotStats, wantStats)
+	}
+	gotTop, err := c.TopPrefixes(0)
+	if err != nil {
+		t.Fatalf("TopPrefixes error: %v", err)
+	}
+	if wantTop, _ := plain.TopPrefixes(0); !reflect.DeepEqual(gotTop, wantTop) {
+		t.Errorf("TopPrefixes = %v, want %v", gotTop, wantTop)
+	}
+	if !c.Equal(plain) {
+		d, _ := c.Diff(plain)
+		t.Errorf("Diff = %+v, want none", d)
+	}
+
+	exports := map[string]func(*Chain[string], *bytes.Buffer) error{
+		"ARPA":    func(c *Chain[string], b *bytes.Buffer) error { return c.ExportARPA(b) },
+		"DOT":     func(c *Chain[string], b *bytes.Buffer) error { return c.ExportDOT(b) },
+		"GraphML": func(c *Chain[string], b *bytes.Buffer) error { return c.ExportGraphML(b) },
+	}
+	for name, export := range exports {
+		var got, want bytes.Buffer
+		if err := export(c, &got); err != nil {
+			t.Fatalf("Export%s error: %v", name, err)
+		}
+		if err := export(plain, &want); err != nil {
+			t.Fatalf("Export%s error: %v", name, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("Export%s with backoff = %q, want %q as without", name, got.String(), want.String())
+		}
+	}
+}
+
+func TestChainBackoffMergeMismatch(t *testing.T) {
+	const text = "the cat sat. the dog sat."
+	backoff := NewChain(2, WithMarkers(), WithBackoff())
+	mustBuild(t, backoff, text)
+	plain := NewChain(2, WithMarkers())
+	mustBuild(t, plain, text)
+	wantPlain, wantBackoff := chainCounts(plain), chainCounts(backoff)
+
+	if err := plain.Merge(backoff); err == nil {
+		t.Error("Merge of a chain backing off into one that is not succeeded, want error")
+	}
+	if err := backoff.Merge(plain); err == nil {
+		t.Error("Merge of a chain not backing off into one that is succeeded, want error")
+	}
+	if err := plain.Subtract(backoff); err == nil {
+		t.Error("Subtract of a chain backing off from one that is not succeeded, want error")
+	}
+	if err := backoff.Subtract(plain); err == nil {
+		t.Error("Subtract of a chain not backing off from one that is succeeded, want error")
+	}
+	if got := chainCounts(plain); !reflect.DeepEqual(got, wantPlain) {
+		t.Errorf("chain not backing off = %v after failing, want %v", got, wantPlain)
+	}
+	if got := chainCounts(backoff); !reflect.DeepEqual(got, wantBackoff) {
+		t.Errorf("chain backing off = %v after failing, want %v", got, wantBackoff)
+	}
+}
@@ -0,0 +1,42 @@
+package markov
+
+// WithBannedWords keeps Generate from ever writing any of the words of
+// texts, split into words as Build splits its input, such as profanity or
+// names to leave out. A banned word is never picked, other suffixes of its
+// prefix being picked in its stead, and where every suffix of a prefix is
+// banned a Chain created WithBackoff backs off to a shorter context and any
+// other Chain ends generation. It applies to beam search too, but not to the
+// words of a prompt.
+func WithBannedWords(texts ...string) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.banned = append(cfg.banned, texts...)
+	}
+}
+
+// bannedIDs returns the set of the IDs of the words of texts the Chain has
+// observed, or nil if there are none. c.mu must be held.
+func (c *Chain[T]) bannedIDs(texts []string) map[uint32]bool {
+	var ids map[uint32]bool
+	for _, text := range texts {
+		for _, word := range c.split(text) {
+			id := c.vocab.id(word)
+			if id == noID {
+				continue
+			}
+			if ids == nil {
+				ids = make(map[uint32]bool)
+			}
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// forbidBanned zeroes the weights w of s's words that are banned.
+func (cfg *generateConfig) forbidBanned(w []float64, s *suffixes) {
+	for i, id := range s.words {
+		if cfg.bannedIDs[id] {
+			w[i] = 0
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package markov
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChainBannedWords(t *testing.T) {
+	c := New[string](1)
+	if err := c.Build(strings.NewReader("the cat sat on the mat and the dog sat on the rug")); err != nil {
+		t.Fatal(err)
+	}
+	for seed := int64(0); seed < 20; seed++ {
+		for _, beam := range []int{0, 2} {
+			text, err := c.GenerateString(30,
+				WithRand(rand.New(rand.NewSource(seed))), WithBeam(beam), WithBannedWords("cat mat"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			words := strings.Fields(text)
+			if slices.Contains(words, "cat") || slices.Contains(words, "mat") {
+				t.Errorf("seed %d, beam %d: GenerateString = %q, which holds a banned word", seed, beam, text)
+			}
+		}
+	}
+}
+
+func TestChainBannedWordsOnlySuffix(t *testing.T) {
+	// "cat" is the only word following "the".
+	text := "the cat sat on the mat"
+	c := New[string](1)
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GenerateString(4, WithPrompt("on"), WithBannedWords("cat", "mat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "the"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+
+	// Backing off to no context at all picks other words.
+	c = New[string](1, WithBackoff())
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = c.GenerateString(4, WithPrompt("on"), WithRand(rand.New(rand.NewSource(1))), WithBannedWords("cat", "mat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields(got)
+	if len(words) != 4 || words[0] != "the" || slices.Contains(words, "cat") || slices.Contains(words, "mat") {
+		t.Errorf("with backoff, GenerateString = %q, want 4 words after \"the\" and none banned", got)
+	}
+}
@@ -0,0 +1,108 @@
+package markov
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+)
+
+// WithBeam makes Generate search for the sequence of words with the highest
+// joint probability using a beam search that keeps the width most probable
+// partial sequences at each step, instead of sampling words one at a time.
+// Sequences that reach a prefix with no suffixes stop growing; the longest
+// sequence found wins, with ties resolved by joint probability. Any
+// temperature and top-k or top-p restriction reshape the probabilities the
+// search ranks by. A width of 0, the default, disables beam search.
+func WithBeam(width int) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.beamWidth = width
+	}
+}
+
+// hypothesis is a candidate sequence of words considered by a beam search.
+// Sequences share their common beginnings through parent.
+type hypothesis struct {
+	parent  *hypothesis
+	word    uint32
+	len     int
+	logProb float64
+	window  Prefix[uint32]
+}
+
+// words returns the IDs of the sequence of words ending at h.
+func (h *hypothesis) words() []uint32 {
+	words := make([]uint32, h.len)
+	for ; h != nil && h.len > 0; h = h.parent {
+		words[h.len-1] = h.word
+	}
+	return words
+}
+
+// better reports whether h is a better final result than o.
+func (h *hypothesis) better(o *hypothesis) bool {
+	if h.len != o.len {
+		return h.len > o.len
+	}
+	return h.logProb > o.logProb
+}
+
+// beamSearch returns the IDs of the best sequence of at most n words
+// following the prefix of word IDs window found by a beam search of width
+// cfg.beamWidth.
+func (c *Chain[T]) beamSearch(ctx context.Context, window Prefix[uint32], n int, cfg *generateConfig) ([]uint32, error) {
+	best := &hypothesis{window: slices.Clone(window)}
+	beam := []*hypothesis{best}
+
+	for i := 0; i < n && len(beam) > 0; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var next []*hypothesis
+		c.mu.RLock()
+		for _, h := range beam {
+			s, err := c.get(h.window)
+			if err != nil {
+				c.mu.RUnlock()
+				return nil, err
+			}
+			if s == nil {
+				if h.better(best) {
+					best = h
+				}
+				continue
+			}
+			for j, p := range cfg.probabilities(s) {
+				if p == 0 || cfg.bannedIDs[s.words[j]] {
+					continue
+				}
+				window := slices.Clone(h.window)
+				window.Shift(s.words[j])
+				next = append(next, &hypothesis{
+					parent:  h,
+					word:    s.words[j],
+					len:     h.len + 1,
+					logProb: h.logProb + math.Log(p),
+					window:  window,
+				})
+			}
+		}
+		c.mu.RUnlock()
+
+		sort.SliceStable(next, func(a, b int) bool {
+			return next[a].logProb > next[b].logProb
+		})
+		if len(next) > cfg.beamWidth {
+			next = next[:cfg.beamWidth]
+		}
+		beam = next
+	}
+
+	for _, h := range beam {
+		if h.better(best) {
+			best = h
+		}
+	}
+	return best.words(), nil
+}
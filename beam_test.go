@@ -0,0 +1,61 @@
+package markov
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGenerateBeam(t *testing.T) {
+	// Greedily, "x" is followed by "a" but "a" then splits evenly, while
+	// "b" is always followed by "c", making "x b c" the most probable
+	// three-word continuation.
+	c := NewChain(1)
+	mustBuild(t, c, "x a d x a e x a f x b c x b c")
+
+	tests := []struct {
+		width int
+		n     int
+		want  string
+	}{
+		{1, 3, "x a d"},
+		{2, 3, "x b c"},
+		{5, 3, "x b c"},
+		{5, 0, ""},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := c.Generate(&b, tt.n, WithBeam(tt.width)); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("width %d, n %d: Generate = %q, want %q", tt.width, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateBeamPrefersLongest(t *testing.T) {
+	// "a" ends the text 3 times out of 4 but the only way to reach four
+	// words is through "b".
+	c := NewChain(1)
+	mustBuild(t, c, "s a")
+	mustBuild(t, c, "s a")
+	mustBuild(t, c, "s a")
+	mustBuild(t, c, "s b c d")
+
+	var b strings.Builder
+	if err := c.Generate(&b, 10, WithBeam(3)); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got, want := b.String(), "s b c d"; got != want {
+		t.Errorf("Generate = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateInvalidBeam(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	if err := c.Generate(io.Discard, 10, WithBeam(-1)); err == nil {
+		t.Error("Generate with beam width -1 succeeded, want error")
+	}
+}
@@ -0,0 +1,461 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// The binary model format written by SaveBinary is, with every integer an
+// unsigned varint unless noted otherwise:
+//
+//	magic          "MKV\x00"
+//	version        binaryVersion
+//	prefix length
+//	options        mode, flags (a bitmask of option* flags),
+//	               normalization form + 1 (0 for none),
+//	               placeholder, stop word count, stop words, and if
+//	               optionSkipGram is set, a position count and positions
+//	word kind      one of the word* kinds
+//	word count     the number of words after the reserved IDs
+//	words          each a string, signed varint or varint by word kind
+//	prefix count
+//	prefixes       each prefix length word IDs, a suffix count, and
+//	               then a word ID and count for each suffix
+//
+// A string is its length in bytes followed by its bytes. Readers reject
+// versions and option flags newer than they know, since they could not
+// honor them.
+const (
+	binaryMagic   = "MKV\x00"
+	binaryVersion = 1
+)
+
+// Flags of the options saved in the binary model format.
+const (
+	optionPunctuation = 1 << iota
+	optionSegmented
+	optionMarkers
+	optionSentenceReset
+	optionLowercase
+	optionCollapse
+	optionBackoff
+	optionSkipGram
+	optionReversed
+
+	knownOptions = optionReversed<<1 - 1
+)
+
+// Kinds of words in the binary model format.
+const (
+	wordString = iota
+	wordInt
+	wordUint
+)
+
+// SaveBinary is like Save but writes the Chain in a compact binary format,
+// typically several times smaller than Save's. LoadChain and Load read
+// either format. Words must be strings or integers.
+func (c *Chain[T]) SaveBinary(w io.Writer) error {
+	kind, err := wordKind[T]()
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+
+	e := &binaryEncoder{w: bufio.NewWriter(w)}
+	encodeBinaryHeader(e, m, kind)
+
+	e.uint(uint64(len(m.Prefixes)))
+	for _, p := range m.Prefixes {
+		for _, id := range p.Words {
+			e.uint(uint64(id))
+		}
+		e.uint(uint64(len(p.Next)))
+		for i, id := range p.Next {
+			e.uint(uint64(id))
+			e.uint(uint64(p.Counts[i]))
+		}
+	}
+
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	if e.err != nil {
+		return fmt.Errorf("markov: saving chain: %w", e.err)
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the Chain as
+// SaveBinary writes it.
+func (c *Chain[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.SaveBinary(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the
+// content of the Chain with the Chain encoded by MarshalBinary, along with
+// its options. Options holding functions, which are not encoded, are kept.
+func (c *Chain[T]) UnmarshalBinary(data []byte) error {
+	loaded, err := loadBinary[T](context.Background(), bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replace(loaded)
+	return nil
+}
+
+// encodeBinaryHeader encodes everything that precedes the prefixes of m,
+// whose words are of the given kind, in the binary model format.
+func encodeBinaryHeader[T comparable](e *binaryEncoder, m model[T], kind uint64) {
+	e.w.WriteString(binaryMagic)
+	e.uint(binaryVersion)
+	e.uint(uint64(m.PrefixLen))
+
+	o := m.Options
+	e.uint(uint64(o.Mode))
+	flags := 0
+	for flag, set := range map[int]bool{
+		optionPunctuation:   o.Punctuation,
+		optionSegmented:     o.Segmented,
+		optionMarkers:       o.Markers,
+		optionSentenceReset: o.SentenceReset,
+		optionLowercase:     o.Lowercase,
+		optionCollapse:      o.Collapse,
+		optionBackoff:       o.Backoff,
+		optionSkipGram:      len(o.SkipGram) > 0,
+		optionReversed:      o.Reversed,
+	} {
+		if set {
+			flags |= flag
+		}
+	}
+	e.uint(uint64(flags))
+	e.uint(uint64(slices.Index(formNames, o.Normalization) + 1))
+	e.string(o.Placeholder)
+	e.uint(uint64(len(o.StopWords)))
+	for _, word := range o.StopWords {
+		e.string(word)
+	}
+	if len(o.SkipGram) > 0 {
+		e.uint(uint64(len(o.SkipGram)))
+		for _, p := range o.SkipGram {
+			e.uint(uint64(p))
+		}
+	}
+
+	e.uint(kind)
+	e.uint(uint64(len(m.Words) - int(firstID)))
+	for _, word := range m.Words[firstID:] {
+		switch v := any(word).(type) {
+		case string:
+			e.string(v)
+		default:
+			if kind == wordInt {
+				e.int(toInt64(v))
+			} else {
+				e.uint(toUint64(v))
+			}
+		}
+	}
+}
+
+// loadBinary reads a Chain written by SaveBinary from r, configured by its
+// saved options followed by opts, a prefix at a time. It returns ctx.Err()
+// if ctx is done first.
+func loadBinary[T comparable](ctx context.Context, r *bufio.Reader, opts []Option) (*Chain[T], error) {
+	d := &binaryDecoder{r: r}
+	m, err := decodeBinaryHeader[T](d)
+	if err != nil {
+		return nil, fmt.Errorf("markov: loading chain: %w", err)
+	}
+	c, err := m.empty(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := d.len(); n > 0 && d.err == nil; n-- {
+		if n%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		var p savedPrefix
+		for i := 0; i < m.PrefixLen && d.err == nil; i++ {
+			p.Words = append(p.Words, d.id())
+		}
+		for n := d.len(); n > 0 && d.err == nil; n-- {
+			p.Next = append(p.Next, d.id())
+			p.Counts = append(p.Counts, d.len())
+		}
+		if d.err == nil {
+			if err := c.insert(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("markov: loading chain: %w", d.err)
+	}
+	return c, ctx.Err()
+}
+
+// decodeBinaryHeader decodes everything that precedes the prefixes of a
+// model written by SaveBinary from d.
+func decodeBinaryHeader[T comparable](d *binaryDecoder) (*model[T], error) {
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(d.r, magic); err != nil || string(magic) != binaryMagic {
+		return nil, errors.New("not a binary model")
+	}
+	if v := d.uint(); d.err == nil && v != binaryVersion {
+		return nil, fmt.Errorf("unsupported binary model version %d; this package supports version %d", v, binaryVersion)
+	}
+
+	m := &model[T]{PrefixLen: d.len()}
+	o := &m.Options
+	o.Mode = Mode(d.uint())
+	if d.err == nil && int(o.Mode) >= len(modeNames) {
+		return nil, fmt.Errorf("unsupported mode %d", o.Mode)
+	}
+	flags := d.uint()
+	if d.err == nil && flags&^knownOptions != 0 {
+		return nil, fmt.Errorf("unsupported options %#x", flags&^knownOptions)
+	}
+	o.Punctuation = flags&optionPunctuation != 0
+	o.Segmented = flags&optionSegmented != 0
+	o.Markers = flags&optionMarkers != 0
+	o.SentenceReset = flags&optionSentenceReset != 0
+	o.Lowercase = flags&optionLowercase != 0
+	o.Collapse = flags&optionCollapse != 0
+	o.Backoff = flags&optionBackoff != 0
+	o.Reversed = flags&optionReversed != 0
+	if form := d.uint(); form > uint64(len(formNames)) {
+		return nil, fmt.Errorf("unsupported normalization form %d", form-1)
+	} else if form > 0 {
+		o.Normalization = formNames[form-1]
+	}
+	o.Placeholder = d.string()
+	for n := d.len(); n > 0 && d.err == nil; n-- {
+		o.StopWords = append(o.StopWords, d.string())
+	}
+	if flags&optionSkipGram != 0 {
+		for n := d.len(); n > 0 && d.err == nil; n-- {
+			o.SkipGram = append(o.SkipGram, d.len())
+		}
+	}
+
+	want, err := wordKind[T]()
+	if err != nil {
+		return nil, err
+	}
+	if kind := d.uint(); d.err == nil && kind != want {
+		return nil, fmt.Errorf("words of kind %d cannot be read as %T", kind, *new(T))
+	}
+	m.Words = make([]T, firstID)
+	for n := d.len(); n > 0 && d.err == nil; n-- {
+		var word T
+		switch p := any(&word).(type) {
+		case *string:
+			*p = d.string()
+		default:
+			if want == wordInt {
+				setInt(p, d.int())
+			} else {
+				setUint(p, d.uint())
+			}
+		}
+		m.Words = append(m.Words, word)
+	}
+
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+	return m, d.err
+}
+
+// binaryEncoder writes the values of the binary model format, keeping the
+// first error encountered.
+type binaryEncoder struct {
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+func (e *binaryEncoder) uint(v uint64) {
+	e.write(binary.AppendUvarint(e.buf[:0], v))
+}
+
+func (e *binaryEncoder) int(v int64) {
+	e.write(binary.AppendVarint(e.buf[:0], v))
+}
+
+func (e *binaryEncoder) string(s string) {
+	e.uint(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *binaryEncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+	e.buf = b
+}
+
+// binaryDecoder reads the values of the binary model format, keeping the
+// first error encountered, after which it reads only zeros.
+type binaryDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *binaryDecoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *binaryDecoder) int() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.err = err
+	return v
+}
+
+// len reads a length or count, which must fit in an int32.
+func (d *binaryDecoder) len() int {
+	v := d.uint()
+	if v > 1<<31-1 && d.err == nil {
+		d.err = fmt.Errorf("length %d out of range", v)
+	}
+	return int(v)
+}
+
+// id reads a word ID.
+func (d *binaryDecoder) id() uint32 {
+	v := d.uint()
+	if v >= noID && d.err == nil {
+		d.err = fmt.Errorf("word ID %d out of range", v)
+	}
+	return uint32(v)
+}
+
+func (d *binaryDecoder) string() string {
+	n := d.len()
+	if d.err != nil {
+		return ""
+	}
+	var b []byte
+	for n > 0 && d.err == nil {
+		// Grow gradually so that a corrupt length cannot exhaust memory.
+		chunk := make([]byte, min(n, 1<<16))
+		_, d.err = io.ReadFull(d.r, chunk)
+		b = append(b, chunk...)
+		n -= len(chunk)
+	}
+	return string(b)
+}
+
+// wordKind returns the kind of words of type T in the binary model format.
+func wordKind[T comparable]() (uint64, error) {
+	switch any(*new(T)).(type) {
+	case string:
+		return wordString, nil
+	case int, int8, int16, int32, int64:
+		return wordInt, nil
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		return wordUint, nil
+	}
+	return 0, fmt.Errorf("markov: cannot save words of type %T in binary", *new(T))
+}
+
+// toInt64 returns the signed integer v as an int64.
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	panic("markov: not a signed integer")
+}
+
+// toUint64 returns the unsigned integer v as a uint64.
+func toUint64(v any) uint64 {
+	switch v := v.(type) {
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	case uintptr:
+		return uint64(v)
+	}
+	panic("markov: not an unsigned integer")
+}
+
+// setInt sets the signed integer p points to to v.
+func setInt(p any, v int64) {
+	switch p := p.(type) {
+	case *int:
+		*p = int(v)
+	case *int8:
+		*p = int8(v)
+	case *int16:
+		*p = int16(v)
+	case *int32:
+		*p = int32(v)
+	case *int64:
+		*p = v
+	}
+}
+
+// setUint sets the unsigned integer p points to to v.
+func setUint(p any, v uint64) {
+	switch p := p.(type) {
+	case *uint:
+		*p = uint(v)
+	case *uint8:
+		*p = uint8(v)
+	case *uint16:
+		*p = uint16(v)
+	case *uint32:
+		*p = uint32(v)
+	case *uint64:
+		*p = v
+	case *uintptr:
+		*p = uintptr(v)
+	}
+}
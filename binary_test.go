@@ -0,0 +1,146 @@
+package markov
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestSaveBinary(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithPunctuation(), WithNormalization(norm.NFKC), WithCollapsedStopWords("_", "a", "the"))
+	mustBuild(t, c, strings.Repeat("The quick brown fox jumps over a lazy dog. Who saw it? ", 20))
+
+	var bin, gob bytes.Buffer
+	if err := c.SaveBinary(&bin); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	if err := c.Save(&gob); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if bin.Len() >= gob.Len()/2 {
+		t.Errorf("SaveBinary wrote %d bytes, want under half of Save's %d", bin.Len(), gob.Len())
+	}
+
+	loaded, err := LoadChain(&bin)
+	if err != nil {
+		t.Fatalf("LoadChain error: %v", err)
+	}
+	if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded chain = %v, want %v", got, want)
+	}
+	if got, want := loaded.options.saved(), c.options.saved(); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded options = %+v, want %+v", got, want)
+	}
+}
+
+func TestSaveBinaryIntegers(t *testing.T) {
+	ints := New[int](1)
+	ints.Add(-1, 2, -1, 300)
+	var buf bytes.Buffer
+	if err := ints.SaveBinary(&buf); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loaded, err := Load[int](bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if got := loaded.Count(Prefix[int]{-1}, 300); got != 1 {
+		t.Errorf("Count({-1}, 300) = %d, want 1", got)
+	}
+	if _, err := Load[uint8](bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("Load of ints as uint8 succeeded, want error")
+	}
+
+	bytesChain := New[byte](1, WithMode(Bytes))
+	mustBuildBytes(t, bytesChain, "\x00\xff\x00")
+	buf.Reset()
+	if err := bytesChain.SaveBinary(&buf); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loadedBytes, err := Load[byte](&buf)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if got := loadedBytes.Count(Prefix[byte]{0xff}, 0); got != 1 {
+		t.Errorf("Count({0xff}, 0) = %d, want 1", got)
+	}
+
+	type symbol struct{ a, b int }
+	if err := New[symbol](1).SaveBinary(&buf); err == nil {
+		t.Error("SaveBinary of structs succeeded, want error")
+	}
+}
+
+func TestLoadBinaryInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	if err := c.SaveBinary(&buf); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	valid := buf.Bytes()
+
+	// The version, mode and flags follow the magic and the prefix length.
+	header := len(binaryMagic)
+	tests := map[string][]byte{
+		"newer version": replaceAt(valid, header, 2),
+		"unknown mode":  replaceAt(valid, header+2, 99),
+		"unknown flag":  slices.Insert(replaceAt(valid, header+3, 0x80), header+4, 0x04), // 1<<9 as a varint
+		"truncated":     valid[:len(valid)-1],
+		"no magic":      []byte("MKV"),
+	}
+	for name, data := range tests {
+		if _, err := LoadChain(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: LoadChain succeeded, want error", name)
+		}
+	}
+}
+
+// replaceAt returns a copy of b with the byte at i replaced by v.
+func replaceAt(b []byte, i int, v byte) []byte {
+	b = bytes.Clone(b)
+	b[i] = v
+	return b
+}
+
+func mustBuildBytes(t *testing.T, c *Chain[byte], text string) {
+	t.Helper()
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build(%q) error: %v", text, err)
+	}
+}
+
+func TestChainBinaryMarshaler(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "The end. The start.")
+
+	// A Chain is encoded through its MarshalBinary method as a field of a
+	// gob-encoded value.
+	type record struct {
+		Name  string
+		Chain *Chain[string]
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record{"test", c}); err != nil {
+		t.Fatalf("gob Encode error: %v", err)
+	}
+	var got record
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("gob Decode error: %v", err)
+	}
+	if got.Name != "test" || !reflect.DeepEqual(chainCounts(got.Chain), chainCounts(c)) {
+		t.Errorf("decoded %q with chain %v, want %q with chain %v", got.Name, chainCounts(got.Chain), "test", chainCounts(c))
+	}
+	if !got.Chain.markers {
+		t.Error("decoded chain lost its markers option")
+	}
+
+	if err := got.Chain.UnmarshalBinary([]byte("garbage")); err == nil {
+		t.Error("UnmarshalBinary of garbage succeeded, want error")
+	}
+}
@@ -0,0 +1,265 @@
+// Package boltstore keeps Markov chains of package markov in bbolt
+// databases.
+package boltstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/saclark/markov"
+	"github.com/saclark/markov/internal/batch"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The buckets a Store keeps a Chain in. The words bucket maps each ID,
+// big-endian so that the words are ordered by ID, to its word. The prefixes
+// bucket maps each prefix key to the encoded distribution of its suffixes:
+// their number followed by each suffix's ID and count, in the order they
+// were first observed, all as unsigned varints.
+var (
+	metaBucket     = []byte("markov_meta")
+	wordsBucket    = []byte("markov_words")
+	prefixesBucket = []byte("markov_prefixes")
+)
+
+// OpenChain is like markov.OpenSQLChain but keeps the Chain in the bbolt
+// database db. Each batch of observations is written in a single
+// transaction, so a Chain trained incrementally across many runs is left
+// consistent by a crash, missing at most the batch that was being written.
+func OpenChain(db *bolt.DB, prefixLen int, opts ...markov.Option) (*markov.Chain[string], error) {
+	return Open[string](db, prefixLen, opts...)
+}
+
+// Open is like OpenChain but returns a Chain of words of type T, which must
+// be strings or integers.
+func Open[T comparable](db *bolt.DB, prefixLen int, opts ...markov.Option) (*markov.Chain[T], error) {
+	s, err := New(db)
+	if err != nil {
+		return nil, err
+	}
+	return markov.Open[T](s, prefixLen, opts...)
+}
+
+// New returns a Store that keeps a Chain in the bbolt database db, as
+// OpenChain does, creating the buckets it needs if they do not exist.
+func New(db *bolt.DB) (markov.Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metaBucket, wordsBucket, prefixesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+	return &store{db: db}, nil
+}
+
+// store is the Store returned by New.
+type store struct {
+	db      *bolt.DB
+	pending batch.Batch
+}
+
+func (s *store) Meta() ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(metaBucket).Get([]byte("chain")); value != nil {
+			data = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (s *store) SetMeta(data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put([]byte("chain"), data)
+	})
+}
+
+func (s *store) Get(prefix string) ([]markov.Suffix, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	var list []markov.Suffix
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		list, err = decodeSuffixes(tx.Bucket(prefixesBucket).Get([]byte(prefix)))
+		return err
+	})
+	return list, err
+}
+
+func (s *store) Increment(prefix string, id uint32, n int) error {
+	return s.pending.Add(prefix, id, n, s.write)
+}
+
+func (s *store) Intern(word []byte, next uint32) (uint32, error) {
+	s.pending.AddWord(next, word)
+	return next, nil
+}
+
+func (s *store) Words(from uint32) ([][]byte, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	var words [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(wordsBucket).Cursor()
+		for k, v := cur.Seek(binary.BigEndian.AppendUint32(nil, from)); k != nil; k, v = cur.Next() {
+			want := from + uint32(len(words))
+			if len(k) != 4 || binary.BigEndian.Uint32(k) != want {
+				return fmt.Errorf("missing word with ID %d", want)
+			}
+			words = append(words, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	return words, err
+}
+
+func (s *store) Remove(prefix string) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(prefixesBucket).Delete([]byte(prefix))
+	})
+}
+
+func (s *store) Iterate(fn func(prefix string, suffixes []markov.Suffix) bool) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	stop := errors.New("stop")
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(prefixesBucket).ForEach(func(k, v []byte) error {
+			list, err := decodeSuffixes(v)
+			if err != nil {
+				return err
+			}
+			if !fn(string(k), list) {
+				return stop
+			}
+			return nil
+		})
+	})
+	if err == stop {
+		return nil
+	}
+	return err
+}
+
+func (s *store) Flush() error {
+	return s.pending.Flush(s.write)
+}
+
+// write writes the words and observations of b in a single transaction,
+// merging the observations of each prefix into its stored distribution.
+func (s *store) write(b *batch.Batch) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		words := tx.Bucket(wordsBucket)
+		for _, w := range b.Words {
+			if err := words.Put(binary.BigEndian.AppendUint32(nil, w.ID), w.Word); err != nil {
+				return err
+			}
+		}
+
+		prefixes := tx.Bucket(prefixesBucket)
+		merged := make(map[string]*distribution)
+		for i, o := range b.Observations {
+			d := merged[o.Prefix]
+			if d == nil {
+				list, err := decodeSuffixes(prefixes.Get([]byte(o.Prefix)))
+				if err != nil {
+					return err
+				}
+				d = newDistribution(list)
+				merged[o.Prefix] = d
+			}
+			d.add(o.ID, b.Counts[i])
+		}
+		for k, d := range merged {
+			if err := prefixes.Put([]byte(k), encodeSuffixes(d.list)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// A distribution is the suffixes of a prefix being merged with a batch of
+// observations, indexed by ID.
+type distribution struct {
+	list  []markov.Suffix
+	index map[uint32]int
+}
+
+// newDistribution returns the distribution of the suffixes listed.
+func newDistribution(list []markov.Suffix) *distribution {
+	d := &distribution{list: list, index: make(map[uint32]int, len(list))}
+	for i, suf := range list {
+		d.index[suf.ID] = i
+	}
+	return d
+}
+
+// add adds n to the count of the suffix with the given ID, appending the
+// suffix if it is new.
+func (d *distribution) add(id uint32, n int) {
+	if i, ok := d.index[id]; ok {
+		d.list[i].Count += n
+		return
+	}
+	d.index[id] = len(d.list)
+	d.list = append(d.list, markov.Suffix{ID: id, Count: n})
+}
+
+// encodeSuffixes returns the encoding of list in the prefixes bucket.
+func encodeSuffixes(list []markov.Suffix) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(list)))
+	for _, suf := range list {
+		b = binary.AppendUvarint(b, uint64(suf.ID))
+		b = binary.AppendUvarint(b, uint64(suf.Count))
+	}
+	return b
+}
+
+// decodeSuffixes returns the suffixes encoded as b by encodeSuffixes, or nil
+// if b is nil.
+func decodeSuffixes(b []byte) ([]markov.Suffix, error) {
+	if b == nil {
+		return nil, nil
+	}
+	next := func() uint64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			b = nil
+			return 0
+		}
+		b = b[n:]
+		return v
+	}
+	invalid := errors.New("invalid suffixes")
+	n := next()
+	if b == nil || n > uint64(len(b)) {
+		return nil, invalid
+	}
+	list := make([]markov.Suffix, 0, n)
+	for ; n > 0; n-- {
+		id, count := next(), next()
+		if b == nil || id >= math.MaxUint32 || count == 0 || count > 1<<62 {
+			return nil, invalid
+		}
+		list = append(list, markov.Suffix{ID: uint32(id), Count: int(count)})
+	}
+	if len(b) != 0 {
+		return nil, invalid
+	}
+	return list, nil
+}
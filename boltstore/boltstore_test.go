@@ -0,0 +1,106 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/saclark/markov"
+	"github.com/saclark/markov/internal/batch"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestBolt(t *testing.T, path string) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustBuild(t *testing.T, c *markov.Chain[string], text string) {
+	t.Helper()
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build(%q) error: %v", text, err)
+	}
+}
+
+func TestOpenChain(t *testing.T) {
+	const text = "I am a c. I am a d. You are a c."
+	path := filepath.Join(t.TempDir(), "chain.db")
+	db := openTestBolt(t, path)
+	c, err := OpenChain(db, 2, markov.WithMarkers())
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, text)
+
+	mem := markov.NewChain(2, markov.WithMarkers())
+	mustBuild(t, mem, text)
+	if !c.Equal(mem) {
+		t.Error("chain differs from chain built in memory")
+	}
+	got, err := c.GenerateString(20, markov.WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want, _ := mem.GenerateString(20, markov.WithGreedy()); got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+
+	// Train further after reopening the database. The chain built in memory
+	// has markers, so the reopened chain only equals it if its saved options
+	// are restored.
+	db.Close()
+	db = openTestBolt(t, path)
+	if _, err := OpenChain(db, 3); err == nil {
+		t.Error("OpenChain with another prefix length succeeded, want error")
+	}
+	c, err = OpenChain(db, 2)
+	if err != nil {
+		t.Fatalf("OpenChain error reopening: %v", err)
+	}
+	mustBuild(t, c, "I am a e.")
+	mustBuild(t, mem, "I am a e.")
+	if !c.Equal(mem) {
+		t.Error("chain after reopening differs from chain built in memory")
+	}
+}
+
+func TestOpenChainBatches(t *testing.T) {
+	var words []string
+	for i := range 3 * batch.Size {
+		words = append(words, strconv.Itoa(i%(2*batch.Size)))
+	}
+	text := strings.Join(words, " ")
+
+	c, err := OpenChain(openTestBolt(t, filepath.Join(t.TempDir(), "chain.db")), 2)
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, text)
+	mem := markov.NewChain(2)
+	mustBuild(t, mem, text)
+
+	// The JSON lists the suffixes of each prefix in the order they were
+	// first observed, which merging batches must keep.
+	got, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if want, _ := json.Marshal(mem); string(got) != string(want) {
+		t.Error("chain built in batches differs from chain built in memory")
+	}
+}
+
+func TestDecodeSuffixesInvalid(t *testing.T) {
+	for _, b := range [][]byte{{}, {1}, {1, 2}, {1, 2, 0}, {1, 2, 3, 4}, {0x80}} {
+		if _, err := decodeSuffixes(b); err == nil {
+			t.Errorf("decodeSuffixes(%x) succeeded, want error", b)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package markov
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithMaxChars makes Generate write at most n characters, counting the
+// spaces between words but not a prompt, so that the text fits a limit such
+// as that of a social media post. Generation stops before the first word
+// that would not fit, or WithCompleteSentences at the end of the last
+// sentence that fits. n must not be negative, and 0 sets no limit.
+func WithMaxChars(n int) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.maxChars = n
+	}
+}
+
+// WithCompleteSentences makes Generate, when it stops short of the end of a
+// text because of the number of words or characters it may write, leave
+// out the words after the last sentence it wrote in full, if it wrote one.
+// A sentence ends with a word ending in '.', '!' or '?', as WithSentenceReset
+// detects them, or a line or paragraph break.
+func WithCompleteSentences() GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.completeSentences = true
+	}
+}
+
+// checkBudget returns an error if cfg limits the text in a way the Chain
+// cannot apply.
+func (c *Chain[T]) checkBudget(cfg *generateConfig) error {
+	if c.reversed && (cfg.maxChars > 0 || cfg.completeSentences) {
+		return errors.New("markov: a reversed chain does not support WithMaxChars or WithCompleteSentences")
+	}
+	return nil
+}
+
+// A budget passes the words walk generates to yield as long as they fit
+// within the number of characters allowed WithMaxChars, holding back those
+// of an unfinished sentence WithCompleteSentences.
+type budget[T comparable] struct {
+	c     *Chain[T]
+	cfg   *generateConfig
+	yield func(T) bool
+	space func(T) string
+
+	used    int  // the number of characters yielded or held
+	held    []T  // the words of the unfinished sentence
+	ended   bool // whether a whole sentence was yielded
+	stopped bool // whether yield returned false
+}
+
+// newBudget returns a budget passing words to yield as configured by cfg.
+func (c *Chain[T]) newBudget(cfg *generateConfig, yield func(T) bool) *budget[T] {
+	return &budget[T]{c: c, cfg: cfg, yield: yield, space: c.spacer(cfg.tidy)}
+}
+
+// add passes word to yield or holds it back, reporting false if yield
+// returned false or the word did not fit.
+func (b *budget[T]) add(word T) bool {
+	if b.cfg.maxChars > 0 {
+		n := utf8.RuneCountInString(b.space(word)) + b.c.width(word)
+		if b.used+n > b.cfg.maxChars {
+			return false
+		}
+		b.used += n
+	}
+	if !b.cfg.completeSentences {
+		b.stopped = !b.yield(word)
+		return !b.stopped
+	}
+	b.held = append(b.held, word)
+	if b.c.isBreak(word) || endsSentence(word) {
+		b.ended = true
+		return b.release()
+	}
+	return true
+}
+
+// finish passes the words held back to yield, unless generation was cut
+// short after a whole sentence.
+func (b *budget[T]) finish(cut bool) {
+	if !b.stopped && (!cut || !b.ended) {
+		b.release()
+	}
+}
+
+// release passes the words held back to yield, reporting false if it
+// returned false.
+func (b *budget[T]) release() bool {
+	for _, word := range b.held {
+		if !b.yield(word) {
+			b.stopped = true
+			return false
+		}
+	}
+	b.held = b.held[:0]
+	return true
+}
+
+// width returns the number of characters writeToken writes of word.
+func (c *Chain[T]) width(word T) int {
+	var s strings.Builder
+	w := bufio.NewWriter(&s)
+	c.writeToken(w, word)
+	w.Flush()
+	return utf8.RuneCountInString(s.String())
+}
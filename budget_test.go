@@ -0,0 +1,90 @@
+package markov
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChainMaxChars(t *testing.T) {
+	c := New[string](2)
+	if err := c.Build(strings.NewReader("the cat sat on a mat. one dog lay by the rug.")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts []GenerateOption
+		want string
+	}{
+		{"unlimited", nil, "the cat sat on a mat. one dog lay by the rug."},
+		{"words", []GenerateOption{WithMaxChars(14)}, "the cat sat on"},
+		{"within a word", []GenerateOption{WithMaxChars(13)}, "the cat sat"},
+		{"sentences", []GenerateOption{WithMaxChars(40), WithCompleteSentences()}, "the cat sat on a mat."},
+		{"no whole sentence", []GenerateOption{WithMaxChars(13), WithCompleteSentences()}, "the cat sat"},
+		{"end of text", []GenerateOption{WithCompleteSentences()}, "the cat sat on a mat. one dog lay by the rug."},
+		{"prompt", []GenerateOption{WithPrompt("one dog"), WithMaxChars(10)}, "lay by the"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := c.GenerateString(100, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("GenerateString = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestChainCompleteSentencesWords(t *testing.T) {
+	c := New[string](2)
+	if err := c.Build(strings.NewReader("one two. three four five. six.")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GenerateString(4, WithCompleteSentences())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one two."; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+
+	// Iteration may stop while words are held back.
+	for word := range c.Tokens(100, WithCompleteSentences()) {
+		if word == "two." {
+			break
+		}
+	}
+}
+
+func TestChainMaxCharsRunes(t *testing.T) {
+	c := New[rune](2, WithMode(Chars))
+	if err := c.Build(strings.NewReader("héllo wörld")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GenerateString(100, WithMaxChars(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "héllo w"; got != want || utf8.RuneCountInString(got) != 7 {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
+
+func TestChainMaxCharsUnsupported(t *testing.T) {
+	if _, err := New[string](1).GenerateString(10, WithMaxChars(-1)); err == nil {
+		t.Error("GenerateString with a negative maximum succeeded")
+	}
+	c := New[string](1, WithMarkers())
+	if err := c.Build(strings.NewReader("the cat sat.")); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := c.Reverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rev.GenerateString(10, WithMaxChars(5)); err == nil {
+		t.Error("GenerateString of a reversed chain WithMaxChars succeeded")
+	}
+}
@@ -0,0 +1,118 @@
+package markov
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WithLowercase makes the Chain fold its input, and the words given to Add
+// and Count, to lower case, so that words such as "The" and "the" share the
+// same entries. This shrinks the Chain and connects more of its prefixes, at
+// the cost of the original capitalization, which WithSentenceCase can
+// partly restore when generating.
+func WithLowercase() Option {
+	return func(o *options) {
+		o.lowercase = true
+	}
+}
+
+// Lowercase reports whether the Chain folds its input to lower case, as
+// WithLowercase makes it, so that a Chain loaded can be generated from
+// WithSentenceCase.
+func (c *Chain[T]) Lowercase() bool {
+	return c.lowercase
+}
+
+// WithSentenceCase makes Generate capitalize the first letter of the first
+// word of each sentence, line and paragraph it generates. It suits Chains
+// created WithLowercase.
+func WithSentenceCase() GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.sentenceCase = true
+	}
+}
+
+// WithTidy makes Generate tidy the text it writes so that it reads as
+// prose: it capitalizes the first word of each sentence, as
+// WithSentenceCase does, and the pronoun "i", attaches punctuation marks to
+// the words they follow or precede, as for a Chain created
+// WithPunctuation, and collapses runs of whitespace within words, such as
+// those a Tokenizer given WithTokenizer may keep, to single spaces. It
+// applies only to Chains of strings.
+func WithTidy() GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.sentenceCase = true
+		cfg.tidy = true
+	}
+}
+
+// fold returns word folded to lower case as configured WithLowercase.
+func (c *Chain[T]) fold(word T) T {
+	if !c.lowercase {
+		return word
+	}
+	switch w := any(word).(type) {
+	case string:
+		return any(strings.ToLower(w)).(T)
+	case rune:
+		return any(unicode.ToLower(w)).(T)
+	}
+	return word
+}
+
+// sentenceCaser capitalizes the first word of each sentence of a text, and
+// tidies each word WithTidy.
+type sentenceCaser[T comparable] struct {
+	c     *Chain[T]
+	tidy  bool
+	start bool // whether the next word with a letter starts a sentence
+}
+
+// newSentenceCaser returns a sentenceCaser for a text continuing prompt,
+// tidying words if tidy is true.
+func (c *Chain[T]) newSentenceCaser(prompt []T, tidy bool) *sentenceCaser[T] {
+	sc := &sentenceCaser[T]{c: c, tidy: tidy, start: true}
+	for _, word := range prompt {
+		sc.capitalize(word)
+	}
+	return sc
+}
+
+// capitalize returns word, capitalized if it starts a sentence.
+func (sc *sentenceCaser[T]) capitalize(word T) T {
+	s, ok := any(word).(string)
+	if !ok {
+		return word
+	}
+	if sc.tidy && !sc.c.isBreak(word) {
+		s = tidyWord(s)
+		word = any(s).(T)
+	}
+	if i := strings.IndexFunc(s, unicode.IsLetter); i >= 0 && sc.start {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		word = any(s[:i] + string(unicode.ToTitle(r)) + s[i+size:]).(T)
+		sc.start = false
+	}
+	if sc.c.isBreak(word) || isSentenceEnd(s) {
+		sc.start = true
+	}
+	return word
+}
+
+// tidyWord returns word with its runs of whitespace collapsed and the
+// pronoun "i" capitalized, alone or contracted as in "i'm".
+func tidyWord(word string) string {
+	if strings.ContainsFunc(word, unicode.IsSpace) {
+		word = strings.Join(strings.Fields(word), " ")
+	}
+	rest, ok := strings.CutPrefix(word, "i")
+	if !ok {
+		return word
+	}
+	if strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, "’") ||
+		!strings.ContainsFunc(rest, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return "I" + rest
+	}
+	return word
+}
@@ -0,0 +1,124 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithLowercase(t *testing.T) {
+	c := NewChain(1, WithLowercase())
+	mustBuild(t, c, "The cat saw the Dog.")
+	c.Add("THE", "end")
+
+	want := map[string]map[string]int{
+		"":    {"the": 2},
+		"the": {"cat": 1, "dog.": 1, "end": 1},
+		"cat": {"saw": 1},
+		"saw": {"the": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+	if got := c.Count(Prefix[string]{"The"}, "Cat"); got != 1 {
+		t.Errorf("Count(The, Cat) = %d, want 1", got)
+	}
+	if !c.Lowercase() || NewChain(1).Lowercase() {
+		t.Error("Lowercase does not report WithLowercase")
+	}
+}
+
+func TestWithSentenceCase(t *testing.T) {
+	c := NewChain(2, WithLowercase(), WithPunctuation())
+	mustBuild(t, c, `one. "two?" three`)
+
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"", `One. "Two?" Three`},
+		{"one", `. "Two?" Three`},
+		{`one. "two?"`, "Three"},
+	}
+	for _, tt := range tests {
+		got, err := c.GenerateString(10, WithSentenceCase(), WithPrompt(tt.prompt))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("prompt %q: GenerateString = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestSentenceCaserChars(t *testing.T) {
+	c := NewChain(1, WithMode(Chars))
+	sc := c.newSentenceCaser(nil, false)
+
+	var got string
+	for _, word := range c.split("hi. ok") {
+		got += sc.capitalize(word)
+	}
+	if want := "Hi. Ok"; got != want {
+		t.Errorf("capitalized = %q, want %q", got, want)
+	}
+}
+
+func TestWithTidy(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *Chain[string]
+		text string
+		n    int
+		want string
+	}{
+		{
+			"words",
+			NewChain(3),
+			`i think , therefore i'm . ( really ) he said " in time "`,
+			20,
+			`I think, therefore I'm. (Really) he said "in time"`,
+		},
+		{
+			"punctuation",
+			NewChain(3, WithLowercase(), WithPunctuation()),
+			`i.e. i think, so i am. i?`,
+			20,
+			`I.e. I think, so I am. I?`,
+		},
+		{
+			"lines",
+			NewChain(3, WithMode(Lines)),
+			"( not ) tidy .\n",
+			11,
+			"(Not) tidy.\n(Not) tidy.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustBuild(t, tt.c, tt.text)
+			got, err := tt.c.GenerateString(tt.n, WithTidy())
+			if err != nil {
+				t.Fatalf("GenerateString error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateString = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTidyWord(t *testing.T) {
+	for word, want := range map[string]string{
+		"i":       "I",
+		"i'll":    "I'll",
+		"i’ve":    "I’ve",
+		"i,":      "I,",
+		"in":      "in",
+		"i.e.":    "i.e.",
+		"a  b\tc": "a b c",
+	} {
+		if got := tidyWord(word); got != want {
+			t.Errorf("tidyWord(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package markov
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A SegmentFunc splits a run of Chinese, Japanese or Korean text, which is
+// written without spaces between words, into words. It suits word
+// segmentation libraries, which usually need a dictionary of the language.
+type SegmentFunc func(text string) []string
+
+// WithSegmenter makes the Chain split runs of Chinese, Japanese and Korean
+// characters in its input into words with segment, and whitespace-separated
+// text otherwise, as by SegmentTokenizer. Generate writes those words
+// without spaces between them. If segment is nil, RuneBigrams is used so
+// that such text works without a dictionary.
+func WithSegmenter(segment SegmentFunc) Option {
+	return func(o *options) {
+		o.tokenizer = SegmentTokenizer(segment)
+		o.segmented = true
+	}
+}
+
+// SegmentTokenizer returns a function for WithTokenizer whose Tokenizers
+// split text into whitespace-separated words, further splitting each into
+// runs of Chinese, Japanese or Korean characters, which are split into words
+// by segment, and runs of other characters, which are words as they are.
+// Chinese, Japanese and Korean punctuation marks are words of their own. If
+// segment is nil, RuneBigrams is used.
+func SegmentTokenizer(segment SegmentFunc) func(r io.Reader) Tokenizer {
+	if segment == nil {
+		segment = RuneBigrams
+	}
+	words := ScanTokenizer(bufio.ScanWords)
+	return func(r io.Reader) Tokenizer {
+		return &segmentTokenizer{next: words(r), segment: segment}
+	}
+}
+
+// RuneBigrams is a SegmentFunc splitting text into successive pairs of
+// characters, the last of which may be a single character. Pairs of
+// characters are a crude but dictionary-free stand-in for words, as most
+// Chinese words are two characters long.
+func RuneBigrams(text string) []string {
+	var words []string
+	for text != "" {
+		_, n := utf8.DecodeRuneInString(text)
+		if n < len(text) {
+			_, size := utf8.DecodeRuneInString(text[n:])
+			n += size
+		}
+		words = append(words, text[:n])
+		text = text[n:]
+	}
+	return words
+}
+
+// segmentTokenizer is a Tokenizer segmenting Chinese, Japanese and Korean
+// text.
+type segmentTokenizer struct {
+	next    Tokenizer
+	segment SegmentFunc
+	words   []string
+}
+
+func (t *segmentTokenizer) Next() (string, error) {
+	for len(t.words) == 0 {
+		field, err := t.next.Next()
+		if err != nil {
+			return "", err
+		}
+		t.words = t.split(field)
+	}
+	word := t.words[0]
+	t.words = t.words[1:]
+	return word, nil
+}
+
+// split splits a whitespace-separated field into words.
+func (t *segmentTokenizer) split(field string) []string {
+	var words []string
+	for field != "" {
+		r, _ := utf8.DecodeRuneInString(field)
+		class := cjkClass(r)
+		end := strings.IndexFunc(field, func(r rune) bool { return cjkClass(r) != class })
+		if class == cjkMark {
+			_, end = utf8.DecodeRuneInString(field)
+		} else if end < 0 {
+			end = len(field)
+		}
+
+		run := field[:end]
+		field = field[end:]
+		if class == cjkLetter {
+			for _, word := range t.segment(run) {
+				if word != "" {
+					words = append(words, word)
+				}
+			}
+		} else {
+			words = append(words, run)
+		}
+	}
+	return words
+}
+
+// Classes of characters told apart by SegmentTokenizer.
+const (
+	otherChar = iota
+	cjkLetter
+	cjkMark
+)
+
+// cjkClass returns the class of r.
+func cjkClass(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー':
+		return cjkLetter
+	case r >= 0x3000 && r <= 0x303f, r >= 0xff00 && r <= 0xffef && unicode.IsPunct(r):
+		// CJK Symbols and Punctuation, and full-width punctuation.
+		return cjkMark
+	}
+	return otherChar
+}
+
+// joinsCJK reports whether Generate writes word directly after prev,
+// without a space, in a Chain created WithSegmenter.
+func joinsCJK(prev, word string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(word)
+	return cjkClass(last) != otherChar || cjkClass(first) != otherChar
+}
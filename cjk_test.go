@@ -0,0 +1,75 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRuneBigrams(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"我", []string{"我"}},
+		{"我爱北京", []string{"我爱", "北京"}},
+		{"天安门广场", []string{"天安", "门广", "场"}},
+	}
+	for _, tt := range tests {
+		if got := RuneBigrams(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RuneBigrams(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentTokenizer(t *testing.T) {
+	// dict segments text by the longest known word, or else by character.
+	dict := func(text string) []string {
+		var words []string
+	outer:
+		for text != "" {
+			for _, w := range []string{"北京", "我", "爱"} {
+				if strings.HasPrefix(text, w) {
+					words = append(words, w)
+					text = text[len(w):]
+					continue outer
+				}
+			}
+			_, size := utf8.DecodeRuneInString(text)
+			words = append(words, text[:size])
+			text = text[size:]
+		}
+		return words
+	}
+
+	tests := []struct {
+		segment SegmentFunc
+		text    string
+		want    []string
+	}{
+		{nil, "我爱北京。Go语言 ok", []string{"我爱", "北京", "。", "Go", "语言", "ok"}},
+		{dict, "我爱北京，2008年", []string{"我", "爱", "北京", "，", "2008", "年"}},
+	}
+	for _, tt := range tests {
+		c := NewChain(1, WithTokenizer(SegmentTokenizer(tt.segment)))
+		if got := c.split(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWithSegmenter(t *testing.T) {
+	c := NewChain(2, WithSegmenter(nil))
+	text := "我爱北京天安门。I love Go语言!"
+	mustBuild(t, c, text)
+
+	got, err := c.GenerateString(20)
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if got != text {
+		t.Errorf("GenerateString = %q, want %q", got, text)
+	}
+}
@@ -0,0 +1,272 @@
+package markov
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A ClassChain is a Chain of word classes, returned by Cluster: it picks
+// the class of each word by the classes of the words before it, and then
+// the word among the words of its class by their frequencies. Its prefixes
+// of classes are observed far more often than prefixes of the words
+// themselves, so on a small corpus it generates word sequences the corpus
+// never held, where a Chain can only repeat those it did. A ClassChain is a
+// snapshot, unchanged by training the Chain further, and is safe for
+// concurrent use.
+type ClassChain[T comparable] struct {
+	// c holds the options and prefix length of the Chain clustered, and none
+	// of its words, so that its methods can format the generated text.
+	c *Chain[T]
+
+	words   []T               // each word of the Chain, by ID
+	classOf map[T]int         // the class of each word
+	classes *Chain[int]       // the Chain of the classes of the words
+	members map[int]*suffixes // the IDs of the words of each class, counted
+}
+
+// FrequencyClasses returns k classes of the words of the Chain by their
+// frequencies, for Cluster: the most frequent words fall into classes of
+// their own, and rarer ones share classes, each class holding about the
+// same share of all the words observed. Words are numbered by class from 0,
+// most frequent first.
+func (c *Chain[T]) FrequencyClasses(k int) (map[T]int, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("markov: invalid number of classes %d", k)
+	}
+	counts, total, err := c.wordCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]uint32, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b uint32) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	classes := make(map[T]int, len(ids))
+	seen := 0
+	for _, id := range ids {
+		// Each word falls into the class the share of the words more
+		// frequent than it reaches.
+		classes[c.vocab.words[id]] = min(k*seen/total, k-1)
+		seen += counts[id]
+	}
+	return classes, nil
+}
+
+// wordCounts returns the number of times each word was observed, by ID,
+// and the number of words observed.
+func (c *Chain[T]) wordCounts() (map[uint32]int, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.learn(); err != nil {
+		return nil, 0, err
+	}
+	counts := make(map[uint32]int)
+	total := 0
+	err := c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			return true
+		}
+		for i, id := range s.words {
+			if id != endID {
+				counts[id] += s.counts[i]
+				total += s.counts[i]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("markov: counting words: %w", err)
+	}
+	return counts, total, nil
+}
+
+// Cluster returns a ClassChain of the Chain's words grouped into classes,
+// derived from the Chain's counts, so that it generalizes beyond the word
+// sequences the Chain observed. classes gives each word a class, such as
+// those of FrequencyClasses, Brown clusters computed elsewhere, or parts of
+// speech, and each word it leaves out has a class of its own.
+//
+// The ClassChain has the prefix length and the options of the Chain that
+// shape its prefixes, namely WithMarkers, WithSentenceReset, WithBackoff and
+// WithSkipGram.
+func (c *Chain[T]) Cluster(classes map[T]int) (*ClassChain[T], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reversed {
+		return nil, errors.New("markov: cannot cluster a reversed chain")
+	}
+	chain, err := c.emptyWithWords(c.prefixLen)
+	if err != nil {
+		return nil, err
+	}
+	cc := &ClassChain[T]{
+		c:       chain,
+		words:   chain.vocab.words,
+		classOf: make(map[T]int, len(c.vocab.words)),
+		classes: New[int](c.prefixLen),
+		members: make(map[int]*suffixes),
+	}
+	o := &cc.classes.options
+	o.markers, o.sentenceReset, o.backoff, o.skipGram = c.markers, c.sentenceReset, c.backoff, c.skipGram
+
+	// Words left out of classes are numbered after every class given.
+	next := 0
+	for _, class := range classes {
+		next = max(next, class+1)
+	}
+	classIDs := make([]uint32, len(cc.words))
+	classIDs[beginID], classIDs[endID] = beginID, endID
+	for id := firstID; int(id) < len(cc.words); id++ {
+		word := cc.words[id]
+		class, ok := classes[word]
+		if !ok {
+			class = next
+			next++
+		}
+		cc.classOf[word] = class
+		var err error
+		if classIDs[id], err = cc.classes.intern(class); err != nil {
+			return nil, err
+		}
+	}
+
+	window := make(Prefix[uint32], c.prefixLen)
+	var addErr error
+	err = c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			return true
+		}
+		words := unkey(k)
+		for i, id := range words {
+			window[i] = classIDs[id]
+		}
+		for i, id := range s.words {
+			if addErr = cc.classes.addCount(window, classIDs[id], s.counts[i]); addErr != nil {
+				return false
+			}
+			if id == endID {
+				continue
+			}
+			class := cc.classOf[cc.words[id]]
+			m := cc.members[class]
+			if m == nil {
+				m = newSuffixes()
+				cc.members[class] = m
+			}
+			m.addCount(id, s.counts[i])
+		}
+		return true
+	})
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("markov: clustering chain: %w", err)
+	}
+	return cc, nil
+}
+
+// Class returns the class of word, reporting false if the Chain never
+// observed it.
+func (cc *ClassChain[T]) Class(word T) (int, bool) {
+	class, ok := cc.classOf[cc.c.normalized([]T{word})[0]]
+	return class, ok
+}
+
+// Generate writes a string of at most n words generated from the
+// ClassChain to w, as the Chain's Generate would write them. opts are those
+// of Generate, applied to the classes picked, except that WithWordFunc,
+// WithBannedWords, WithMaxChars and WithCompleteSentences are not
+// supported. A prompt and stop sequences are of words, and a stop
+// sequence stops at any words of the same classes.
+func (cc *ClassChain[T]) Generate(w io.Writer, n int, opts ...GenerateOption) error {
+	cfg, err := newGenerateConfig(opts)
+	if err != nil {
+		return err
+	}
+	if cfg.onWord != nil {
+		return errors.New("markov: a ClassChain does not support WithWordFunc")
+	}
+	if cfg.banned != nil {
+		return errors.New("markov: a ClassChain does not support WithBannedWords")
+	}
+	if cfg.maxChars > 0 || cfg.completeSentences {
+		return errors.New("markov: a ClassChain does not support WithMaxChars or WithCompleteSentences")
+	}
+
+	// The class Chain reads the classes of words written as numbers.
+	prompt := cc.c.split(cfg.prompt)
+	sub := *cfg
+	sub.prompt = cc.classText(prompt)
+	sub.sentenceCase = false
+	sub.stops = nil
+	for _, seq := range cfg.stops {
+		sub.stops = append(sub.stops, cc.classText(cc.c.split(seq)))
+	}
+
+	bw := bufio.NewWriter(w)
+	var caser *sentenceCaser[T]
+	if cfg.sentenceCase {
+		caser = cc.c.newSentenceCaser(prompt, cfg.tidy)
+	}
+	space := cc.c.spacer(cfg.tidy)
+	var writeErr error
+	err = cc.classes.walk(context.Background(), n, &sub, func(class int) bool {
+		m := cc.members[class]
+		word := cc.words[m.pick(cfg.intn(m.total))]
+		if caser != nil {
+			word = caser.capitalize(word)
+		}
+		if _, writeErr = bw.WriteString(space(word)); writeErr != nil {
+			return false
+		}
+		writeErr = cc.c.writeToken(bw, word)
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// GenerateString returns a string of at most n words generated from the
+// ClassChain, as Generate would write it.
+func (cc *ClassChain[T]) GenerateString(n int, opts ...GenerateOption) (string, error) {
+	var b strings.Builder
+	if err := cc.Generate(&b, n, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// classText returns the classes of words as the class Chain reads them,
+// with -1 for each word never observed, which no class is.
+func (cc *ClassChain[T]) classText(words []T) string {
+	fields := make([]string, len(words))
+	for i, word := range words {
+		class, ok := cc.classOf[word]
+		if !ok {
+			class = -1
+		}
+		fields[i] = strconv.Itoa(class)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,80 @@
+package markov
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChainFrequencyClasses(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a a a a b b c d")
+	for _, tt := range []struct {
+		k    int
+		want map[string]int
+	}{
+		{1, map[string]int{"a": 0, "b": 0, "c": 0, "d": 0}},
+		{2, map[string]int{"a": 0, "b": 1, "c": 1, "d": 1}},
+		{8, map[string]int{"a": 0, "b": 4, "c": 6, "d": 7}},
+	} {
+		got, err := c.FrequencyClasses(tt.k)
+		if err != nil {
+			t.Fatalf("FrequencyClasses(%d) error: %v", tt.k, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FrequencyClasses(%d) = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+	if _, err := c.FrequencyClasses(0); err == nil {
+		t.Error("FrequencyClasses(0) succeeded, want error")
+	}
+}
+
+func TestChainCluster(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "the cat sat")
+	mustBuild(t, c, "the dog ran")
+	cc, err := c.Cluster(map[string]int{"cat": 0, "dog": 0, "sat": 1, "ran": 1})
+	if err != nil {
+		t.Fatalf("Cluster error: %v", err)
+	}
+	if class, ok := cc.Class("dog"); !ok || class != 0 {
+		t.Errorf("Class(dog) = %d, %t, want 0, true", class, ok)
+	}
+	if class, ok := cc.Class("the"); !ok || class != 2 {
+		t.Errorf("Class(the) = %d, %t, want 2, true", class, ok)
+	}
+	if _, ok := cc.Class("bird"); ok {
+		t.Error("Class(bird) reported true, want false")
+	}
+
+	// The ClassChain generates sentences the Chain never observed.
+	rng := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for range 50 {
+		got, err := cc.GenerateString(10, WithRand(rng))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		words := strings.Fields(got)
+		if len(words) != 3 || words[0] != "the" || !slices.Contains([]string{"cat", "dog"}, words[1]) || !slices.Contains([]string{"sat", "ran"}, words[2]) {
+			t.Fatalf("GenerateString = %q, want the, cat or dog, and sat or ran", got)
+		}
+		seen[got] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("GenerateString generated %v, want all 4 sentences", seen)
+	}
+
+	if got, err := cc.GenerateString(10, WithPrompt("the cat"), WithRand(rng)); err != nil || (got != "sat" && got != "ran") {
+		t.Errorf("GenerateString(WithPrompt(the cat)) = %q, %v, want sat or ran", got, err)
+	}
+	if got, err := cc.GenerateString(10, WithPrompt("the bird")); err != nil || got != "" {
+		t.Errorf("GenerateString(WithPrompt(the bird)) = %q, %v, want nothing", got, err)
+	}
+	if got, err := cc.GenerateString(10, WithStop("dog"), WithPrompt("the"), WithRand(rand.New(rand.NewSource(2)))); err != nil || got != "cat" && got != "dog" {
+		t.Errorf("GenerateString(WithStop(dog)) = %q, %v, want cat or dog", got, err)
+	}
+}
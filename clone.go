@@ -0,0 +1,54 @@
+package markov
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Clone returns a deep copy of the Chain, with its options, words and counts
+// and none of its maps and slices, so that a snapshot can be served while
+// the Chain goes on training in the background. The copy is kept in memory
+// whatever Store holds the Chain.
+func (c *Chain[T]) Clone() (*Chain[T], error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clone, err := c.emptyWithWords(c.prefixLen)
+	if err != nil {
+		return nil, err
+	}
+	clone.ngrams = maps.Clone(c.ngrams)
+	var putErr error
+	err = c.store.each(func(k string, s *suffixes) bool {
+		putErr = clone.store.put(k, s.clone())
+		return putErr == nil
+	})
+	if err == nil {
+		err = putErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("markov: cloning chain: %w", err)
+	}
+	return clone, nil
+}
+
+// emptyWithWords returns a new Chain in memory with prefixes of prefixLen
+// words, the options of c and all of its words, with the same IDs, and none
+// of its counts, for deriving a Chain from the counts of c. c.mu must be
+// held for writing.
+func (c *Chain[T]) emptyWithWords(prefixLen int) (*Chain[T], error) {
+	// Learn the words other Chains sharing the store have added, which its
+	// prefixes may refer to.
+	if err := c.learn(); err != nil {
+		return nil, err
+	}
+
+	e := New[T](prefixLen)
+	e.options = c.options
+	for _, word := range c.vocab.words[firstID:] {
+		if _, err := e.intern(word); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
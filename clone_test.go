@@ -0,0 +1,43 @@
+package markov
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestChainClone(t *testing.T) {
+	db := openTestDB(t)
+	sqlChain, err := OpenSQLChain(db, 2, WithMarkers())
+	if err != nil {
+		t.Fatalf("OpenSQLChain error: %v", err)
+	}
+	for name, c := range map[string]*Chain[string]{"memory": NewChain(2, WithMarkers()), "sql": sqlChain} {
+		mustBuild(t, c, "The cat sat on the mat. The dog sat on the cat.")
+		want := chainCounts(c)
+
+		clone, err := c.Clone()
+		if err != nil {
+			t.Fatalf("%s: Clone error: %v", name, err)
+		}
+		if got := chainCounts(clone); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: clone = %v, want %v", name, got, want)
+		}
+		rng := func() GenerateOption { return WithRand(rand.New(rand.NewSource(1))) }
+		got, _ := clone.GenerateString(20, rng())
+		if want, _ := c.GenerateString(20, rng()); got != want {
+			t.Errorf("%s: clone GenerateString = %q, want %q", name, got, want)
+		}
+
+		// Training either leaves the other as it was.
+		mustBuild(t, c, "The cat ran off. A bird sang.")
+		if got := chainCounts(clone); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: clone after training the chain = %v, want %v", name, got, want)
+		}
+		trained := chainCounts(c)
+		mustBuild(t, clone, "The dog ran away.")
+		if got := chainCounts(c); !reflect.DeepEqual(got, trained) {
+			t.Errorf("%s: chain after training the clone = %v, want %v", name, got, trained)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/saclark/markov"
+	"golang.org/x/text/unicode/norm"
+)
+
+// chainFlags are the flags describing how to build a chain.
+type chainFlags struct {
+	prefixLen   *int
+	mode        markov.Mode
+	lines       *bool
+	paragraphs  *bool
+	punctuation *bool
+	cjk         *bool
+	normalize   *string
+	lowercase   *bool
+	stopWords   *string
+	markers     *bool
+	sentences   *bool
+	backoff     *bool
+	overlap     *int
+	tokens      *tokenFlags
+}
+
+// addChainFlags defines the flags describing how to build a chain in fs.
+func addChainFlags(fs *flag.FlagSet) *chainFlags {
+	f := &chainFlags{}
+	f.prefixLen = fs.Int("prefix", 2, "prefix length in words (or characters)")
+	fs.TextVar(&f.mode, "mode", markov.Words, "split the input into words (\"word\"), characters (\"char\"), bytes (\"byte\"), lines of words (\"line\") or paragraphs of words (\"paragraph\")")
+	f.lines = fs.Bool("lines", false, "treat each input line as a separate text and print line breaks; short for -mode line")
+	f.paragraphs = fs.Bool("paragraphs", false, "treat each blank-line separated paragraph as a separate text and print blank lines between paragraphs; short for -mode paragraph")
+	f.tokens = addTokenFlags(fs)
+	f.punctuation = fs.Bool("punctuation", false, "split punctuation from words and reattach it in the generated text")
+	f.cjk = fs.Bool("cjk", false, "split Chinese, Japanese and Korean text into pairs of characters and print it without spaces")
+	f.normalize = fs.String("normalize", "", "normalize the input to the Unicode form \"nfc\", \"nfd\", \"nfkc\" or \"nfkd\"")
+	f.lowercase = fs.Bool("lowercase", false, "fold the input to lower case and capitalize the generated sentences")
+	f.stopWords = fs.String("stopwords", "", "file of whitespace-separated words to leave out of the chain")
+	f.markers = fs.Bool("markers", false, "start and end the generated text at sentence boundaries")
+	f.sentences = fs.Bool("sentences", false, "keep prefixes from spanning sentences, starting each sentence afresh")
+	f.backoff = fs.Bool("backoff", false, "back off to shorter prefixes instead of stopping at a prefix never seen")
+	f.overlap = fs.Int("overlap", 0, "never print more than this many consecutive words of the input verbatim (0 allows any; not with -load)")
+	return f
+}
+
+// newChain returns an empty chain as the flags describe it.
+func (f *chainFlags) newChain() *markov.Chain[string] {
+	mode := f.mode
+	if *f.lines {
+		mode = markov.Lines
+	}
+	if *f.paragraphs {
+		mode = markov.Paragraphs
+	}
+	opts := append([]markov.Option{markov.WithMode(mode)}, f.tokens.options()...)
+	if *f.cjk {
+		opts = append(opts, markov.WithSegmenter(nil))
+	}
+	if *f.normalize != "" {
+		forms := map[string]norm.Form{"nfc": norm.NFC, "nfd": norm.NFD, "nfkc": norm.NFKC, "nfkd": norm.NFKD}
+		form, ok := forms[strings.ToLower(*f.normalize)]
+		if !ok {
+			log.Fatalf("unknown normalization form %q", *f.normalize)
+		}
+		opts = append(opts, markov.WithNormalization(form))
+	}
+	if *f.lowercase {
+		opts = append(opts, markov.WithLowercase())
+	}
+	if *f.stopWords != "" {
+		b, err := os.ReadFile(*f.stopWords)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, markov.WithStopWords(strings.Fields(string(b))...))
+	}
+	if *f.punctuation {
+		opts = append(opts, markov.WithPunctuation())
+	}
+	if *f.markers {
+		opts = append(opts, markov.WithMarkers())
+	}
+	if *f.sentences {
+		opts = append(opts, markov.WithSentenceReset())
+	}
+	if *f.backoff {
+		opts = append(opts, markov.WithBackoff())
+	}
+	if *f.overlap > 0 {
+		opts = append(opts, markov.WithNgramIndex(*f.overlap+1))
+	}
+	return markov.NewChain(*f.prefixLen, opts...)
+}
+
+// tokenFlags are the flags describing how to split text into words that
+// are not saved with a chain, and so must be given again to generate from
+// a chain loaded.
+type tokenFlags struct {
+	pattern *string
+	re      *regexp.Regexp
+}
+
+// addTokenFlags defines the flags describing how to split text into words
+// in fs.
+func addTokenFlags(fs *flag.FlagSet) *tokenFlags {
+	return &tokenFlags{
+		pattern: fs.String("regexp", "", "split the input into the matches of this regular expression instead of by -mode"),
+	}
+}
+
+// options returns the options of a chain splitting text as the flags
+// describe, which are not saved with it.
+func (f *tokenFlags) options() []markov.Option {
+	if *f.pattern == "" {
+		return nil
+	}
+	if f.re == nil {
+		var err error
+		if f.re, err = regexp.Compile(*f.pattern); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return []markov.Option{markov.WithTokenizer(markov.RegexpTokenizer(f.re))}
+}
+
+// words returns text split into words as a chain in the given mode splits
+// its input, joined by spaces as the chain writes them, so that prompts are
+// printed as the words generated are.
+func (f *tokenFlags) words(text string, mode markov.Mode) string {
+	f.options()
+	if f.re != nil {
+		return strings.Join(f.re.FindAllString(text, -1), " ")
+	} else if mode == markov.Chars || mode == markov.Bytes {
+		return text
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// generateFlags are the flags describing what text to generate from a
+// chain and how to print it.
+type generateFlags struct {
+	numWords    *int
+	order       *int
+	novelty     *bool
+	wrap        *int
+	tidy        *bool
+	maxChars    *int
+	whole       *bool
+	ban         *string
+	temperature *float64
+	topK        *int
+	topP        *float64
+	greedy      *bool
+	beam        *int
+	start       *string
+	around      *string
+	end         *string
+	count       *int
+	delimiter   *string
+	format      *string
+	seed        *int64
+}
+
+// addGenerateFlags defines the flags describing what text to generate and
+// how to print it in fs.
+func addGenerateFlags(fs *flag.FlagSet) *generateFlags {
+	f := &generateFlags{}
+	f.numWords = fs.Int("words", 100, "maximum number of words (or characters) to print")
+	f.order = fs.Int("order", 0, "generate from prefixes of only the last n words of the chain's, such as one loaded with -load (0 uses them all)")
+	f.novelty = fs.Bool("novelty", false, "report how much of each text printed copies the input to the standard error")
+	f.wrap = fs.Int("wrap", 0, "wrap the output at this many columns (0 for no wrapping; not with -format json)")
+	f.tidy = fs.Bool("tidy", false, "capitalize sentences and \"i\" and attach punctuation to words in the output")
+	f.maxChars = fs.Int("chars", 0, "maximum number of characters of each text (0 for no limit)")
+	f.whole = fs.Bool("whole", false, "end each text cut short at the end of its last whole sentence")
+	f.ban = fs.String("ban", "", "never print any of these words")
+	f.temperature = fs.Float64("temperature", 1, "sampling temperature; lower is more conservative, higher is wilder")
+	f.topK = fs.Int("topk", 0, "sample only from the k most frequent suffixes (0 considers all)")
+	f.topP = fs.Float64("topp", 1, "sample only from the most frequent suffixes whose combined probability reaches p (1 considers all)")
+	f.greedy = fs.Bool("greedy", false, "always pick the most frequent suffix instead of sampling")
+	f.beam = fs.Int("beam", 0, "search for the most probable text with a beam of this width (0 samples instead)")
+	f.start = fs.String("start", "", "words to continue the generated text from")
+	f.around = fs.String("around", "", "words to put in the middle of the generated text, generating both backwards and forwards from them (needs -markers, at least as many words as -prefix, and neither -chars nor -whole)")
+	f.end = fs.String("end", "", "words to end the generated text with, generating backwards from them (needs -markers)")
+	f.count = fs.Int("count", 1, "number of independent texts to print")
+	f.delimiter = fs.String("delimiter", "\n", "text printed between each of the -count texts")
+	f.format = fs.String("format", "text", "print each text as plain \"text\" or as a \"json\" object with its metadata, one per line")
+	f.seed = fs.Int64("seed", 0, "random seed for reproducible output (0 uses the current time)")
+	return f
+}
+
+// chainOptions returns the options every subcommand generates from chain
+// with, whatever its flags: a chain built with -lowercase is generated with
+// its sentences capitalized.
+func chainOptions(chain *markov.Chain[string]) []markov.GenerateOption {
+	if chain.Lowercase() {
+		return []markov.GenerateOption{markov.WithSentenceCase()}
+	}
+	return nil
+}
+
+// print prints the texts the flags describe generated from chain, which
+// splits text as tokens describe, to out. extra are further options to
+// generate with.
+func (f *generateFlags) print(out io.Writer, chain *markov.Chain[string], tokens *tokenFlags, extra ...markov.GenerateOption) {
+	if *f.order > 0 {
+		var err error
+		if chain, err = chain.Shorten(*f.order); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Seed the random number generator
+	if *f.seed == 0 {
+		*f.seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*f.seed))
+	opts := []markov.GenerateOption{
+		markov.WithRand(rng),
+		markov.WithTemperature(*f.temperature),
+		markov.WithTopK(*f.topK),
+		markov.WithTopP(*f.topP),
+		markov.WithBeam(*f.beam),
+		markov.WithMaxChars(*f.maxChars),
+	}
+	opts = append(opts, chainOptions(chain)...)
+	opts = append(opts, extra...)
+	if *f.whole {
+		opts = append(opts, markov.WithCompleteSentences())
+	}
+	if *f.ban != "" {
+		opts = append(opts, markov.WithBannedWords(*f.ban))
+	}
+	if *f.greedy {
+		opts = append(opts, markov.WithGreedy())
+	}
+	if *f.tidy {
+		opts = append(opts, markov.WithTidy())
+	}
+	prompts := 0
+	for _, p := range []string{*f.start, *f.end, *f.around} {
+		if p != "" {
+			prompts++
+		}
+	}
+	if prompts > 1 {
+		log.Fatal("only one of -start, -end and -around can be given")
+	}
+	if *f.start != "" {
+		opts = append(opts, markov.WithPrompt(*f.start))
+	}
+	var reverse *markov.Chain[string]
+	if *f.end != "" || *f.around != "" {
+		var err error
+		if reverse, err = chain.Reverse(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *f.end != "" {
+		opts = append(opts, markov.WithPrompt(*f.end))
+	}
+
+	// Collect the words of each text generated, before they are cased, for
+	// -format json.
+	var generated []string
+	if *f.format == "json" {
+		opts = append(opts, markov.WithWordFunc(func(word string, _ markov.Prefix[string]) error {
+			generated = append(generated, word)
+			return nil
+		}))
+	}
+
+	// Prompts are printed split into words as the chain splits its input.
+	mode := chain.Mode()
+	space := " "
+	if mode == markov.Chars || mode == markov.Bytes {
+		space = ""
+	}
+
+	// generate writes one text to w.
+	generate := func(w io.Writer) error {
+		if *f.start != "" {
+			fmt.Fprint(w, tokens.words(*f.start, mode), space)
+		}
+		switch {
+		case *f.around != "":
+			return chain.GenerateAround(w, reverse, *f.numWords, *f.around, opts...)
+		case *f.end != "":
+			text, err := reverse.GenerateString(*f.numWords, opts...)
+			if err != nil {
+				return err
+			}
+			if text != "" {
+				text += space
+			}
+			_, err = fmt.Fprint(w, text, tokens.words(*f.end, mode))
+			return err
+		case *f.novelty:
+			text, nov, err := chain.GenerateWithNovelty(*f.numWords, opts...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "longest overlap %d words, %.0f%% of %d-grams novel\n", nov.LongestOverlap, 100*nov.NovelFraction, nov.N)
+			_, err = fmt.Fprint(w, text)
+			return err
+		}
+		return chain.Generate(w, *f.numWords, opts...)
+	}
+
+	switch *f.format {
+	case "text":
+	case "json":
+		if *f.wrap > 0 {
+			log.Fatal("-wrap cannot be given with -format json")
+		}
+		err := printJSON(out, chain, *f.count, *f.seed, func(w io.Writer, seed int64) (int, []string, error) {
+			rng.Seed(seed)
+			generated = generated[:0]
+			err := generate(w)
+			switch {
+			case *f.around != "":
+				// The words generated backwards and forwards are not told
+				// apart.
+				return len(generated), nil, err
+			case *f.end != "":
+				// The words are generated backwards from the end.
+				slices.Reverse(generated)
+			}
+			return len(generated), generated, err
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		log.Fatalf("unknown output format %q", *f.format)
+	}
+
+	// Our output is wrapped if asked.
+	if *f.wrap > 0 {
+		ww := newWrapper(out, *f.wrap)
+		defer ww.Flush()
+		out = ww
+	}
+	for i := 0; i < *f.count; i++ {
+		if i > 0 {
+			fmt.Fprint(out, *f.delimiter)
+		}
+		if err := generate(out); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if mode != markov.Bytes {
+		fmt.Fprintln(out)
+	}
+}
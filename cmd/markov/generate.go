@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runGenerate runs the generate subcommand, which prints text generated
+// from a saved chain.
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markov generate -model file [flags]")
+		fmt.Fprintln(fs.Output(), "Print text generated from the chain saved in the file by markov train.")
+		fs.PrintDefaults()
+	}
+	model := fs.String("model", "", "load the chain from this file")
+	tokens := addTokenFlags(fs)
+	genFlags := addGenerateFlags(fs)
+	fs.Parse(args)
+	if *model == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	genFlags.print(os.Stdout, loadChain(*model, tokens.options()), tokens)
+}
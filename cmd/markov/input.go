@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/saclark/markov"
+)
+
+// inputFlags are the flags describing which files of the directories named
+// as arguments to read, and how to fetch the URLs named as arguments.
+type inputFlags struct {
+	match      *string
+	exclude    []string
+	timeout    *time.Duration
+	maxBytes   *int64
+	s3Endpoint *string
+	mbox       *bool
+}
+
+// addInputFlags defines the flags describing which files of directories to
+// read in fs.
+func addInputFlags(fs *flag.FlagSet) *inputFlags {
+	f := &inputFlags{}
+	f.match = fs.String("match", "*", "read only the files of directories whose names match this glob pattern, such as \"*.txt\"")
+	fs.Func("exclude", "leave out the files and directories of directories whose names match this glob pattern, such as \".git\" (repeatable)", func(pattern string) error {
+		f.exclude = append(f.exclude, pattern)
+		return nil
+	})
+	f.timeout = fs.Duration("timeout", 5*time.Minute, "give up fetching a corpus URL after this long")
+	f.maxBytes = fs.Int64("maxbytes", 1<<30, "give up fetching a corpus URL whose response is larger than this many bytes")
+	f.s3Endpoint = fs.String("s3endpoint", "", "fetch s3://bucket/key URLs from this S3-compatible endpoint, such as http://localhost:9000, rather than Amazon S3")
+	f.mbox = fs.Bool("mbox", false, "read the input as mbox mailboxes, such as mailing list archives, training on the plain-text body of each message without its quoted replies")
+	return f
+}
+
+// buildOptions returns the options building chains from directories and
+// URLs as the flags describe.
+func (f *inputFlags) buildOptions() []markov.BuildOption {
+	opts := []markov.BuildOption{markov.WithExclude(f.exclude...)}
+	if *f.mbox {
+		opts = append(opts, markov.WithMbox())
+	}
+	return opts
+}
+
+// buildInputs builds chain from the files named by args, in which glob
+// patterns such as corpus/*.txt are expanded for shells that do not, each
+// file as a separate text, or from the standard input if there are none,
+// decompressing those compressed with gzip, bzip2 or Zstandard. Arguments
+// that are http, https, s3 or gs URLs are fetched as the flags describe. The
+// files of directories are read recursively, as in describes, leaving out
+// those that look binary. It logs the error of each file, directory or URL
+// it cannot read and goes on with the rest, reporting whether every one was
+// read.
+func buildInputs(chain *markov.Chain[string], args []string, in *inputFlags) bool {
+	if len(args) == 0 {
+		if err := in.build(chain, "standard input", os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+		return true
+	}
+
+	ok := true
+	for _, arg := range args {
+		if in.isURL(arg) {
+			if err := in.buildURL(chain, arg); err != nil {
+				log.Print(err)
+				ok = false
+			}
+			continue
+		}
+		names, err := expand(arg)
+		if err != nil {
+			log.Print(err)
+			ok = false
+			continue
+		}
+		for _, name := range names {
+			buildName := in.buildFile
+			if info, err := os.Stat(name); err == nil && info.IsDir() {
+				buildName = in.buildDir
+			}
+			if err := buildName(chain, name); err != nil {
+				log.Print(err)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// expand returns the names of the files matching the glob pattern, or
+// pattern itself if it holds no glob metacharacters.
+func expand(pattern string) ([]string, error) {
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pattern, err)
+	}
+	if len(names) == 0 {
+		if _, err := os.Lstat(pattern); err == nil || !hasMeta(pattern) {
+			// A name that is not a pattern is read as given, reporting
+			// why it cannot be.
+			return []string{pattern}, nil
+		}
+		return nil, fmt.Errorf("%s: no files match", pattern)
+	}
+	return names, nil
+}
+
+// hasMeta reports whether pattern holds any of the metacharacters of
+// filepath.Match.
+func hasMeta(pattern string) bool {
+	for _, r := range pattern {
+		switch r {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// buildDir builds chain from the files of the named directory as the flags
+// describe.
+func (f *inputFlags) buildDir(chain *markov.Chain[string], name string) error {
+	err := chain.BuildFS(context.Background(), os.DirFS(name), *f.match, f.buildOptions()...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// buildFile builds chain from the named file as a separate text, or as a
+// mailbox if the flags say so.
+func (f *inputFlags) buildFile(chain *markov.Chain[string], name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := f.build(chain, name, file); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
+
+// build builds chain from r, the named input, as a separate text, or as a
+// mailbox if the flags say so, logging how many of its messages could not
+// be parsed, decompressed if it is compressed.
+func (f *inputFlags) build(chain *markov.Chain[string], name string, r io.Reader) error {
+	dr, err := markov.Decompress(r)
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	if !*f.mbox {
+		return chain.Build(dr)
+	}
+	skipped, err := chain.BuildMbox(context.Background(), dr)
+	if skipped > 0 {
+		log.Printf("%s: messages skipped that could not be parsed: %d", name, skipped)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/saclark/markov"
+)
+
+// A result is a text printed by -format json, with its metadata.
+type result struct {
+	Text      string   `json:"text"`
+	Seed      int64    `json:"seed"`
+	PrefixLen int      `json:"prefixLength"`
+	Words     int      `json:"words"`
+	Checksum  string   `json:"checksum"`
+	Novelty   *novelty `json:"novelty"`
+}
+
+// novelty is the markov.Novelty of the words generated for a result's
+// text, or null if it is not measured. It is measured only against the
+// n-gram index of a chain built with -overlap, which a saved chain does not
+// keep, since without one every text would be found to copy the corpus.
+type novelty struct {
+	N              int     `json:"n"`
+	LongestOverlap int     `json:"longestOverlap"`
+	NovelFraction  float64 `json:"novelFraction"`
+}
+
+// printJSON prints count texts generated from chain as results to w, one
+// JSON object per line. generate writes each text generated from a random
+// source with the given seed and returns the number of words it generated
+// and those words in reading order, before they were cased, or nil if it
+// cannot tell their order. The text at index i is generated from seed plus
+// i, skipping 0, which its result reports, so that -seed with the seed of
+// any result and -count 1 generates its text again.
+func printJSON(w io.Writer, chain *markov.Chain[string], count int, seed int64, generate func(w io.Writer, seed int64) (int, []string, error)) error {
+	sum, err := checksum(chain)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for i := range count {
+		var b strings.Builder
+		textSeed := seed + int64(i)
+		if seed < 0 && textSeed >= 0 {
+			textSeed++ // -seed 0 stands for the current time
+		}
+		n, words, err := generate(&b, textSeed)
+		if err != nil {
+			return err
+		}
+		var nov *novelty
+		if words != nil && chain.NgramIndex() > 0 {
+			// The words are scored as generated, since the text may be
+			// cased and tidied unlike the corpus.
+			v, err := chain.Novelty(words)
+			if err != nil {
+				return err
+			}
+			nov = &novelty{v.N, v.LongestOverlap, v.NovelFraction}
+		}
+		err = enc.Encode(result{
+			Text:      b.String(),
+			Seed:      textSeed,
+			PrefixLen: chain.PrefixLen(),
+			Words:     n,
+			Checksum:  sum,
+			Novelty:   nov,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checksum returns the SHA-256 checksum of chain encoded as SaveBinary
+// writes it, which identifies the model a text was generated from.
+func checksum(chain *markov.Chain[string]) (string, error) {
+	h := sha256.New()
+	if err := chain.SaveBinary(h); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/saclark/markov"
+)
+
+// printed returns what print writes given the generate flags args.
+func printed(t *testing.T, chain *markov.Chain[string], args ...string) string {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	tokens := addTokenFlags(fs)
+	f := addGenerateFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%q) error: %v", args, err)
+	}
+	var b bytes.Buffer
+	f.print(&b, chain, tokens)
+	return b.String()
+}
+
+// results decodes the results printed one per line.
+func results(t *testing.T, out string) []result {
+	t.Helper()
+	var rs []result
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var r result
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unmarshal(%q) error: %v", line, err)
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+func newTestChain(t *testing.T, opts ...markov.Option) *markov.Chain[string] {
+	t.Helper()
+	chain := markov.NewChain(1, opts...)
+	const text = "the cat sat on the mat and the dog sat on the rug and a cat ran to the dog"
+	if err := chain.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	return chain
+}
+
+func TestPrintJSON(t *testing.T) {
+	chain := newTestChain(t)
+	sum, err := checksum(chain)
+	if err != nil {
+		t.Fatalf("checksum error: %v", err)
+	}
+
+	rs := results(t, printed(t, chain, "-format", "json", "-count", "3", "-seed", "42", "-words", "8"))
+	if len(rs) != 3 {
+		t.Fatalf("printed %d results, want 3", len(rs))
+	}
+	for i, r := range rs {
+		if want := int64(42 + i); r.Seed != want {
+			t.Errorf("result %d seed = %d, want %d", i, r.Seed, want)
+		}
+		if r.PrefixLen != 1 || r.Checksum != sum || r.Novelty != nil {
+			t.Errorf("result %d = %+v, want prefix length 1, checksum %s and no novelty", i, r, sum)
+		}
+		if got := len(strings.Fields(r.Text)); r.Words != got {
+			t.Errorf("result %d words = %d, want %d in %q", i, r.Words, got, r.Text)
+		}
+
+		// Each text is generated again from its own seed alone.
+		seed := []string{"-seed", strconv.FormatInt(r.Seed, 10)}
+		again := results(t, printed(t, chain, append(seed, "-format", "json", "-words", "8")...))
+		if len(again) != 1 || again[0] != r {
+			t.Errorf("result %d generated again = %+v, want %+v", i, again, r)
+		}
+		if text := printed(t, chain, append(seed, "-words", "8")...); text != r.Text+"\n" {
+			t.Errorf("text of result %d generated again = %q, want %q", i, text, r.Text+"\n")
+		}
+	}
+}
+
+func TestPrintJSONNovelty(t *testing.T) {
+	rs := results(t, printed(t, newTestChain(t, markov.WithNgramIndex(2)), "-format", "json", "-seed", "1", "-words", "8"))
+	if len(rs) != 1 || rs[0].Novelty == nil || rs[0].Novelty.N != 2 {
+		t.Fatalf("results = %+v, want one with the novelty of 2-grams", rs)
+	}
+	// Every pair of words generated from prefixes of one word was observed.
+	if nov := rs[0].Novelty; nov.LongestOverlap < 2 || nov.NovelFraction != 0 {
+		t.Errorf("novelty = %+v, want no novel 2-grams", nov)
+	}
+}
+
+func TestPrintJSONSeeds(t *testing.T) {
+	var seeds []int64
+	var b bytes.Buffer
+	err := printJSON(&b, newTestChain(t), 3, -1, func(w io.Writer, seed int64) (int, []string, error) {
+		seeds = append(seeds, seed)
+		return 0, nil, nil
+	})
+	if err != nil {
+		t.Fatalf("printJSON error: %v", err)
+	}
+	// 0 stands for the current time, so no text is generated from it.
+	if want := []int64{-1, 1, 2}; !slices.Equal(seeds, want) {
+		t.Errorf("seeds = %v, want %v", seeds, want)
+	}
+	for i, r := range results(t, b.String()) {
+		if r.Seed != seeds[i] {
+			t.Errorf("result %d seed = %d, want %d", i, r.Seed, seeds[i])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Command markov generates random text from a Markov chain built from
+// corpus files or the standard input.
+//
+// Its subcommands split that work into steps:
+//
+//	markov train -o model.mkv [flags] [corpus...]
+//	markov generate -model model.mkv [flags]
+//	markov serve -model model.mkv [-addr :8080]
+//	markov stats model.mkv...
+//	markov merge -o merged.mkv model.mkv...
+//
+// Run without a subcommand, it builds a chain and generates from it in one
+// pass.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/saclark/markov"
+)
+
+// commands are the subcommands, by name.
+var commands = map[string]func(args []string){
+	"train":    runTrain,
+	"generate": runGenerate,
+	"serve":    runServe,
+	"stats":    runStats,
+	"merge":    runMerge,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: markov [flags] [corpus...]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       markov train|generate|serve|stats|merge [flags] [args]")
+		fmt.Fprintln(flag.CommandLine.Output(), "Generate text from a chain built from the corpus files, glob patterns or directories, or the standard input, or run a subcommand; see markov train -h and so on.")
+		flag.PrintDefaults()
+	}
+	chainFlags := addChainFlags(flag.CommandLine)
+	inputFlags := addInputFlags(flag.CommandLine)
+	genFlags := addGenerateFlags(flag.CommandLine)
+	load := flag.String("load", "", "load the chain from this file, saved by -save, instead of building it from a corpus")
+	save := flag.String("save", "", "save the chain to this file so that it can be loaded with -load, compressed if the name ends in .gz or .zst")
+	flag.Parse()
+
+	// Build up a Markov Chain from the corpus
+	var chain *markov.Chain[string]
+	if *load != "" {
+		if flag.NArg() > 0 {
+			log.Fatal("-load cannot be given with corpus files")
+		}
+		chain = loadChain(*load, chainFlags.tokens.options())
+	} else {
+		chain = chainFlags.newChain()
+		if !buildInputs(chain, flag.Args(), inputFlags) {
+			os.Exit(1)
+		}
+	}
+	if *save != "" {
+		saveChain(*save, chain)
+	}
+
+	// Write our generated text to the standard output
+	genFlags.print(os.Stdout, chain, chainFlags.tokens, markov.WithMaxOverlap(*chainFlags.overlap))
+}
+
+// loadChain loads the chain saved in the named file.
+func loadChain(name string, opts []markov.Option) *markov.Chain[string] {
+	chain, err := markov.LoadChainFile(name, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return chain
+}
+
+// saveChain saves chain to the named file.
+func saveChain(name string, chain *markov.Chain[string]) {
+	if err := chain.SaveFile(name); err != nil {
+		log.Fatal(err)
+	}
+}
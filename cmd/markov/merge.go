@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runMerge runs the merge subcommand, which merges saved chains into one.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markov merge -o file model...")
+		fmt.Fprintln(fs.Output(), "Merge the chains saved in the models, which must have the same prefix length and options, and save the result to the file.")
+		fs.PrintDefaults()
+	}
+	out := fs.String("o", "", "save the merged chain to this file")
+	fs.Parse(args)
+	if *out == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	chain := loadChain(fs.Arg(0), nil)
+	for _, name := range fs.Args()[1:] {
+		if err := chain.Merge(loadChain(name, nil)); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+	saveChain(*out, chain)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saclark/markov"
+)
+
+// runServe runs the serve subcommand, which serves text generated from a
+// saved chain over HTTP.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markov serve -model file [flags]")
+		fmt.Fprintln(fs.Output(), "Serve text generated from the chain saved in the file by markov train at /generate, which takes the query parameters words, start, seed (0 or none for a random one) and temperature.")
+		fs.PrintDefaults()
+	}
+	model := fs.String("model", "", "load the chain from this file")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	maxWords := fs.Int("maxwords", 1000, "maximum number of words a request may ask for")
+	tokens := addTokenFlags(fs)
+	fs.Parse(args)
+	if *model == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	chain := loadChain(*model, tokens.options())
+	mux := http.NewServeMux()
+	mux.Handle("GET /generate", &generateHandler{chain: chain, maxWords: *maxWords})
+	log.Printf("serving %s on %s", *model, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// A generateHandler writes text generated from a chain as a plain text
+// response.
+type generateHandler struct {
+	chain    *markov.Chain[string]
+	maxWords int
+}
+
+func (h *generateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n, err := intParam(q.Get("words"), 100)
+	if err != nil || n < 0 || n > h.maxWords {
+		http.Error(w, fmt.Sprintf("words must be an integer in [0, %d]", h.maxWords), http.StatusBadRequest)
+		return
+	}
+	var seed int64
+	if s := q.Get("seed"); s != "" {
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			http.Error(w, "seed must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	opts := []markov.GenerateOption{markov.WithRand(rand.New(rand.NewSource(seed)))}
+	opts = append(opts, chainOptions(h.chain)...)
+	if start := q.Get("start"); start != "" {
+		opts = append(opts, markov.WithPrompt(start))
+	}
+	if t := q.Get("temperature"); t != "" {
+		temperature, err := strconv.ParseFloat(t, 64)
+		if err != nil || !(temperature > 0) || math.IsInf(temperature, 1) {
+			http.Error(w, "temperature must be a positive number", http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, markov.WithTemperature(temperature))
+	}
+
+	// The text is generated before any of it is written, so that a failure
+	// is reported with the status it deserves.
+	var text strings.Builder
+	if err := h.chain.GenerateContext(r.Context(), &text, n, opts...); err != nil {
+		if r.Context().Err() != nil {
+			return // the client is gone
+		}
+		log.Printf("generating: %v", err)
+		http.Error(w, "generating failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, text.String())
+}
+
+// intParam returns the integer value of a query parameter, or def if it is
+// empty.
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// runStats runs the stats subcommand, which prints statistics of the chains
+// saved in the files named by args.
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markov stats file...")
+		fmt.Fprintln(fs.Output(), "Print statistics of the chains saved in the files by markov train or -save.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	for i, name := range fs.Args() {
+		st, err := loadChain(name, nil).Stats()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		if fs.NArg() > 1 {
+			fmt.Printf("%s:\n", name)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "words:\t%d\n", st.Words)
+		fmt.Fprintf(w, "prefixes:\t%d\n", st.Prefixes)
+		fmt.Fprintf(w, "suffixes:\t%d\n", st.Suffixes)
+		fmt.Fprintf(w, "transitions:\t%d\n", st.Transitions)
+		fmt.Fprintf(w, "branching:\t%.3f\n", st.Branching)
+		fmt.Fprintf(w, "entropy:\t%.3f bits\n", st.Entropy)
+		w.Flush()
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runTrain runs the train subcommand, which builds a chain from corpus
+// files or the standard input and saves it.
+func runTrain(args []string) {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markov train -o file [flags] [corpus...]")
+		fmt.Fprintln(fs.Output(), "Build a chain from the corpus files, glob patterns or directories, or the standard input, and save it to the file, compressed if its name ends in .gz or .zst.")
+		fs.PrintDefaults()
+	}
+	chainFlags := addChainFlags(fs)
+	inputFlags := addInputFlags(fs)
+	out := fs.String("o", "", "save the chain to this file")
+	fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	chain := chainFlags.newChain()
+	ok := buildInputs(chain, fs.Args(), inputFlags)
+	saveChain(*out, chain)
+	if !ok {
+		// The chain saved leaves out the files that could not be read.
+		os.Exit(1)
+	}
+}
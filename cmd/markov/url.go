@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/saclark/markov"
+)
+
+// sources returns the sources of the corpora named by URLs as arguments,
+// fetching them as the flags describe.
+func (f *inputFlags) sources() markov.Sources {
+	src := markov.HTTPSource{Client: &http.Client{Timeout: *f.timeout}, MaxBytes: *f.maxBytes}
+	return markov.Sources{
+		"http":  src,
+		"https": src,
+		"s3":    markov.S3Source{HTTPSource: src, Endpoint: *f.s3Endpoint},
+		"gs":    markov.GCSSource{HTTPSource: src},
+	}
+}
+
+// isURL reports whether the argument names a corpus to fetch from one of
+// the sources rather than a file.
+func (f *inputFlags) isURL(arg string) bool {
+	u, err := url.Parse(arg)
+	if err != nil {
+		return false
+	}
+	_, ok := f.sources()[u.Scheme]
+	return ok
+}
+
+// buildURL builds chain from the corpus named by the URL as a separate
+// text, or as a mailbox if the flags say so, streaming it into the chain as
+// it arrives. It gives up once the fetch takes longer than the -timeout flag
+// or the corpus is larger than the -maxbytes flag.
+func (f *inputFlags) buildURL(chain *markov.Chain[string], url string) error {
+	return chain.BuildURLs(context.Background(), f.sources(), []string{url}, f.buildOptions()...)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A wrapper writes text to an underlying writer wrapped at a number of
+// columns, breaking lines at the spaces between words. Runs of spaces and
+// tabs become single spaces or line breaks, and a word wider than a line
+// gets a line of its own.
+type wrapper struct {
+	w     *bufio.Writer
+	width int    // the number of columns to wrap at
+	col   int    // the column the next rune is written at
+	word  []byte // the word being written, not yet placed on a line
+	buf   []byte // the bytes of a rune split across calls to Write
+}
+
+// newWrapper returns a wrapper writing to w wrapped at width columns.
+func newWrapper(w io.Writer, width int) *wrapper {
+	return &wrapper{w: bufio.NewWriter(w), width: width}
+}
+
+// Write writes p, holding back the word it ends with until the space or
+// line break after it, or Flush.
+func (ww *wrapper) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(ww.buf) > 0 {
+		p = append(ww.buf, p...)
+		ww.buf = nil
+	}
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && !utf8.FullRune(p) {
+			ww.buf = append([]byte(nil), p...)
+			break
+		}
+		switch {
+		case r == '\n':
+			if err := ww.place(); err != nil {
+				return 0, err
+			}
+			if err := ww.w.WriteByte('\n'); err != nil {
+				return 0, err
+			}
+			ww.col = 0
+		case unicode.IsSpace(r):
+			if err := ww.place(); err != nil {
+				return 0, err
+			}
+		default:
+			ww.word = append(ww.word, p[:size]...)
+		}
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// place writes the word held back, on the current line if it fits and on
+// a new line otherwise.
+func (ww *wrapper) place() error {
+	if len(ww.word) == 0 {
+		return nil
+	}
+	width := utf8.RuneCount(ww.word)
+	if ww.col > 0 {
+		sep := byte(' ')
+		if ww.col+1+width > ww.width {
+			sep = '\n'
+			ww.col = 0
+		} else {
+			ww.col++
+		}
+		if err := ww.w.WriteByte(sep); err != nil {
+			return err
+		}
+	}
+	if _, err := ww.w.Write(ww.word); err != nil {
+		return err
+	}
+	ww.col += width
+	ww.word = ww.word[:0]
+	return nil
+}
+
+// Flush writes the word held back and any buffered text.
+func (ww *wrapper) Flush() error {
+	if len(ww.buf) > 0 {
+		ww.word = append(ww.word, ww.buf...)
+		ww.buf = nil
+	}
+	if err := ww.place(); err != nil {
+		return err
+	}
+	return ww.w.Flush()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapper(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		writes []string
+		want   string
+	}{
+		{"fits exactly", 9, []string{"abc defgh"}, "abc defgh"},
+		{"one column over", 8, []string{"abc defgh"}, "abc\ndefgh"},
+		{"several lines", 7, []string{"a bb ccc dddd e"}, "a bb\nccc\ndddd e"},
+		{"spaces collapsed", 10, []string{"a   b\t\tc "}, "a b c"},
+		{"long word", 4, []string{"a abcdefgh b"}, "a\nabcdefgh\nb"},
+		{"long first word", 3, []string{"abcdef gh"}, "abcdef\ngh"},
+		{"newline", 9, []string{"abcdefgh\nab cd"}, "abcdefgh\nab cd"},
+		{"newline ends a word", 6, []string{"ab cd\nef gh ij"}, "ab cd\nef gh\nij"},
+		{"blank line", 10, []string{"a\n\nb"}, "a\n\nb"},
+		{"word across writes", 5, []string{"hel", "lo wor", "ld"}, "hello\nworld"},
+		{"runes counted as columns", 10, []string{"café naïve"}, "café naïve"},
+		{"rune across writes", 9, []string{"caf\xc3", "\xa9 na\xc3", "\xafve"}, "café\nnaïve"},
+		{"rune cut short", 10, []string{"ab\xc3"}, "ab\xc3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			ww := newWrapper(&b, tt.width)
+			for _, s := range tt.writes {
+				if n, err := ww.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+				}
+			}
+			if err := ww.Flush(); err != nil {
+				t.Fatalf("Flush error: %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("wrapped %q = %q, want %q", tt.writes, got, tt.want)
+			}
+		})
+	}
+}
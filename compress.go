@@ -0,0 +1,166 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// A Compression is a way of compressing saved Chains.
+type Compression int
+
+const (
+	// NoCompression saves Chains as they are.
+	NoCompression Compression = iota
+
+	// Gzip compresses saved Chains with gzip.
+	Gzip
+
+	// Zstd compresses saved Chains with Zstandard, which is faster than gzip
+	// and compresses better.
+	Zstd
+)
+
+// Magic numbers starting compressed streams. A bzip2 stream starts with
+// "BZh", a digit giving its block size, and the magic number of its first
+// block, or of its end if it is empty.
+var (
+	gzipMagic      = []byte{0x1f, 0x8b}
+	zstdMagic      = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic     = []byte("BZh")
+	bzip2Block     = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndOfFile = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// SaveCompressed is like SaveBinary but compresses the written Chain with
+// comp. LoadChain and Load decompress it again without being told how.
+func (c *Chain[T]) SaveCompressed(w io.Writer, comp Compression) error {
+	var zw io.WriteCloser
+	switch comp {
+	case NoCompression:
+		return c.SaveBinary(w)
+	case Gzip:
+		zw = gzip.NewWriter(w)
+	case Zstd:
+		var err error
+		if zw, err = zstd.NewWriter(w); err != nil {
+			return fmt.Errorf("markov: saving chain: %w", err)
+		}
+	default:
+		return fmt.Errorf("markov: unknown compression %d", comp)
+	}
+	if err := c.SaveBinary(zw); err != nil {
+		zw.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+	return nil
+}
+
+// SaveFile saves the Chain to the named file as SaveCompressed does,
+// compressed with gzip if the name ends in ".gz", with Zstandard if it ends
+// in ".zst", and uncompressed otherwise.
+func (c *Chain[T]) SaveFile(name string) error {
+	comp := NoCompression
+	switch filepath.Ext(name) {
+	case ".gz":
+		comp = Gzip
+	case ".zst":
+		comp = Zstd
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := c.SaveCompressed(f, comp); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadChainFile loads a Chain of strings from the named file, saved in any
+// of the ways LoadChain reads.
+func LoadChainFile(name string, opts ...Option) (*Chain[string], error) {
+	return LoadFile[string](name, opts...)
+}
+
+// LoadFile is like LoadChainFile but loads a Chain of words of type T.
+func LoadFile[T comparable](name string, opts ...Option) (*Chain[T], error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load[T](f, opts...)
+}
+
+// Decompress returns a reader of the text read from r, decompressed if it
+// is compressed with gzip, bzip2 or Zstandard, which it detects from the
+// magic number the compressed stream starts with, so that compressed
+// corpora can be built from as they are. Closing the reader releases the
+// decompressor but does not close r. BuildFiles and BuildFS decompress the
+// files they read this way.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	br, release, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("markov: decompressing: %w", err)
+	}
+	return decompressor{br, release}, nil
+}
+
+// A decompressor is a reader returned by Decompress.
+type decompressor struct {
+	*bufio.Reader
+	release func()
+}
+
+func (d decompressor) Close() error {
+	d.release()
+	return nil
+}
+
+// decompress returns r, decompressed if it starts with the magic number of
+// a Compression or of bzip2. The returned function releases the
+// decompressor.
+func decompress(r *bufio.Reader) (*bufio.Reader, func(), error) {
+	magic, _ := r.Peek(len(bzip2Magic) + 1 + len(bzip2Block))
+	switch {
+	case isBzip2(magic):
+		return bufio.NewReader(bzip2.NewReader(r)), func() {}, nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return bufio.NewReader(zr), func() { zr.Close() }, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return bufio.NewReader(zr), zr.Close, nil
+	}
+	return r, func() {}, nil
+}
+
+// isBzip2 reports whether a stream starting with magic is compressed with
+// bzip2.
+func isBzip2(magic []byte) bool {
+	n := len(bzip2Magic)
+	if len(magic) < n+1+len(bzip2Block) || !bytes.HasPrefix(magic, bzip2Magic) || magic[n] < '1' || magic[n] > '9' {
+		return false
+	}
+	block := magic[n+1:]
+	return bytes.Equal(block, bzip2Block) || bytes.Equal(block, bzip2EndOfFile)
+}
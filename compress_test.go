@@ -0,0 +1,167 @@
+package markov
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestSaveCompressed(t *testing.T) {
+	var corpus strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&corpus, "word%d ", i*i%499)
+	}
+	c := NewChain(2)
+	mustBuild(t, c, corpus.String())
+
+	var plain bytes.Buffer
+	if err := c.SaveBinary(&plain); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	for _, comp := range []Compression{NoCompression, Gzip, Zstd} {
+		var buf bytes.Buffer
+		if err := c.SaveCompressed(&buf, comp); err != nil {
+			t.Fatalf("SaveCompressed(%d) error: %v", comp, err)
+		}
+		if comp != NoCompression && buf.Len() >= plain.Len() {
+			t.Errorf("SaveCompressed(%d) wrote %d bytes, want fewer than %d", comp, buf.Len(), plain.Len())
+		}
+		loaded, err := LoadChain(&buf)
+		if err != nil {
+			t.Fatalf("LoadChain of compression %d error: %v", comp, err)
+		}
+		if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("compression %d: loaded chain = %v, want %v", comp, got, want)
+		}
+	}
+
+	if err := c.SaveCompressed(&plain, Compression(-1)); err == nil {
+		t.Error("SaveCompressed with unknown compression succeeded, want error")
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c")
+
+	dir := t.TempDir()
+	for _, name := range []string{"model.mkv", "model.mkv.gz", "model.mkv.zst"} {
+		path := filepath.Join(dir, name)
+		if err := c.SaveFile(path); err != nil {
+			t.Fatalf("SaveFile(%q) error: %v", name, err)
+		}
+		loaded, err := LoadChainFile(path)
+		if err != nil {
+			t.Fatalf("LoadChainFile(%q) error: %v", name, err)
+		}
+		if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: loaded chain = %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := LoadChainFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadChainFile of a missing file succeeded, want error")
+	}
+}
+
+// bzip2Text is "the cat sat on the mat" compressed with bzip2.
+const bzip2Text = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x16\x38\x50\x17\x00\x00\x09\x91\x80\x40\x00\x2a\x43\x8c\x00\x20\x00\x31\x00\x30\x12\xa6\xd2\x3d\x4d\xa5\x90\xfc\x78\xf0\x62\x94\x42\xd2\xd1\x77\x24\x53\x85\x09\x01\x63\x85\x01\x70"
+
+// compressText returns text compressed with gzip and Zstandard, by name.
+func compressText(t *testing.T, text string) map[string][]byte {
+	t.Helper()
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	io.WriteString(gw, text)
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip error: %v", err)
+	}
+	var zst bytes.Buffer
+	zw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatalf("zstd.NewWriter error: %v", err)
+	}
+	io.WriteString(zw, text)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zstd error: %v", err)
+	}
+	return map[string][]byte{"gzip": gz.Bytes(), "zstd": zst.Bytes()}
+}
+
+func TestDecompress(t *testing.T) {
+	const text = "the cat sat on the mat"
+	inputs := compressText(t, text)
+	inputs["bzip2"] = []byte(bzip2Text)
+	inputs["plain"] = []byte(text)
+	// Text that merely starts like a bzip2 stream is read as it is.
+	inputs["BZh"] = []byte("BZh9 is not bzip2")
+
+	for name, input := range inputs {
+		r, err := Decompress(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: Decompress error: %v", name, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: reading error: %v", name, err)
+		}
+		r.Close()
+		want := text
+		if name == "BZh" {
+			want = string(input)
+		}
+		if string(got) != want {
+			t.Errorf("%s: Decompress read %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0})); err == nil {
+		t.Error("Decompress of a corrupt gzip header succeeded, want error")
+	}
+}
+
+func TestBuildFilesCompressed(t *testing.T) {
+	const text = "the cat sat on the mat"
+	want := NewChain(1)
+	mustBuild(t, want, text)
+
+	dir := t.TempDir()
+	files := compressText(t, text)
+	files["bzip2"] = []byte(bzip2Text)
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		c := NewChain(1)
+		if err := c.BuildFiles(context.Background(), []string{path}); err != nil {
+			t.Fatalf("%s: BuildFiles error: %v", name, err)
+		}
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: chain = %v, want %v", name, got, want)
+		}
+	}
+
+	// BuildFS looks for binary files once they are decompressed, so that
+	// compressed text is not left out.
+	want = NewChain(1)
+	for range files {
+		mustBuild(t, want, text)
+	}
+	c := NewChain(1)
+	if err := c.BuildFS(context.Background(), os.DirFS(dir), "*"); err != nil {
+		t.Fatalf("BuildFS error: %v", err)
+	}
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildFS chain = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package markov
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// A Diff is the difference between the prefixes and counts of two Chains,
+// as reported by Chain.Diff. Each prefix leaves out the padding of a prefix
+// at the start of a text, as those of TopPrefixes do.
+type Diff[T comparable] struct {
+	OnlyA   []Prefix[T] // prefixes only the Chain observed
+	OnlyB   []Prefix[T] // prefixes only the other Chain observed
+	Changed []Prefix[T] // prefixes both observed, followed by different counts
+}
+
+// Empty reports whether the Diff has no differences.
+func (d Diff[T]) Empty() bool {
+	return len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the Chain, A, with other, B, a Chain with prefixes of as
+// many words, prefix by prefix, for tests and for auditing what changed
+// between two versions of a model. Words are compared by value, whatever IDs
+// each Chain assigned them, and their options are not compared.
+func (c *Chain[T]) Diff(other *Chain[T]) (Diff[T], error) {
+	if other.prefixLen != c.prefixLen {
+		return Diff[T]{}, fmt.Errorf("markov: cannot compare a chain with prefixes of %d words to one with prefixes of %d", other.prefixLen, c.prefixLen)
+	}
+	a, err := c.model()
+	if err != nil {
+		return Diff[T]{}, fmt.Errorf("markov: comparing chains: %w", err)
+	}
+	b, err := other.model()
+	if err != nil {
+		return Diff[T]{}, fmt.Errorf("markov: comparing chains: %w", err)
+	}
+
+	// Give the words of B the IDs of A, or new ones past them if A does not
+	// know them.
+	idsA := make(map[T]uint32, len(a.Words))
+	for id := firstID; int(id) < len(a.Words); id++ {
+		idsA[a.Words[id]] = id
+	}
+	words := slices.Clone(a.Words)
+	ids := make([]uint32, len(b.Words))
+	ids[beginID], ids[endID] = beginID, endID
+	for id := firstID; int(id) < len(ids); id++ {
+		word := b.Words[id]
+		if _, ok := idsA[word]; !ok {
+			idsA[word] = uint32(len(words))
+			words = append(words, word)
+		}
+		ids[id] = idsA[word]
+	}
+
+	counts := func(p savedPrefix, ids []uint32) map[uint32]int {
+		m := make(map[uint32]int, len(p.Next))
+		for i, id := range p.Next {
+			m[ids[id]] = p.Counts[i]
+		}
+		return m
+	}
+	identity := make([]uint32, len(a.Words))
+	for id := range identity {
+		identity[id] = uint32(id)
+	}
+	a.Prefixes, b.Prefixes = c.fullPrefixes(a.Prefixes), other.fullPrefixes(b.Prefixes)
+	inA := make(map[string]savedPrefix, len(a.Prefixes))
+	for _, p := range a.Prefixes {
+		inA[key(p.Words)] = p
+	}
+
+	var onlyA, onlyB, changed []string
+	inB := make(map[string]bool, len(b.Prefixes))
+	for _, p := range b.Prefixes {
+		translated := make([]uint32, len(p.Words))
+		for i, id := range p.Words {
+			translated[i] = ids[id]
+		}
+		k := key(translated)
+		inB[k] = true
+		pa, ok := inA[k]
+		switch {
+		case !ok:
+			onlyB = append(onlyB, k)
+		case !maps.Equal(counts(pa, identity), counts(p, ids)):
+			changed = append(changed, k)
+		}
+	}
+	for _, p := range a.Prefixes {
+		if k := key(p.Words); !inB[k] {
+			onlyA = append(onlyA, k)
+		}
+	}
+
+	// List the prefixes in the order of their keys in A, as model lists
+	// them.
+	prefixes := func(keys []string) []Prefix[T] {
+		slices.Sort(keys)
+		var ps []Prefix[T]
+		for _, k := range keys {
+			p := make(Prefix[T], 0, c.prefixLen)
+			for _, id := range unkey(k) {
+				if id != beginID {
+					p = append(p, words[id])
+				}
+			}
+			ps = append(ps, p)
+		}
+		return ps
+	}
+	return Diff[T]{
+		OnlyA:   prefixes(onlyA),
+		OnlyB:   prefixes(onlyB),
+		Changed: prefixes(changed),
+	}, nil
+}
+
+// Equal reports whether the Chain and other have prefixes of as many words
+// and observed the same words following the same prefixes as often, as
+// Diff compares them.
+func (c *Chain[T]) Equal(other *Chain[T]) bool {
+	d, err := c.Diff(other)
+	return err == nil && d.Empty()
+}
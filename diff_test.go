@@ -0,0 +1,50 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainDiff(t *testing.T) {
+	a := NewChain(1)
+	mustBuild(t, a, "a b a c")
+	b := NewChain(1)
+	mustBuild(t, b, "b a d a c")
+
+	d, err := a.Diff(b)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	want := Diff[string]{
+		OnlyB:   []Prefix[string]{{"d"}},
+		Changed: []Prefix[string]{{}, {"a"}}, // words start the texts, and follow a
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("Diff = %+v, want %+v", d, want)
+	}
+	if d, _ := b.Diff(a); !reflect.DeepEqual(d.OnlyA, want.OnlyB) || d.OnlyB != nil {
+		t.Errorf("reversed Diff = %+v, want d only in A", d)
+	}
+	if a.Equal(b) {
+		t.Error("Equal of different chains = true, want false")
+	}
+
+	// Words are compared by value, whatever their IDs.
+	c := NewChain(1)
+	mustBuild(t, c, "b a c")
+	mustBuild(t, c, "a b a")
+	reordered := NewChain(1)
+	mustBuild(t, reordered, "a b a")
+	mustBuild(t, reordered, "b a c")
+	if !c.Equal(reordered) {
+		d, _ := c.Diff(reordered)
+		t.Errorf("Equal of chains with words in another order = false, want true; Diff = %+v", d)
+	}
+	if !a.Equal(a) {
+		t.Error("Equal of a chain and itself = false, want true")
+	}
+	if _, err := a.Diff(NewChain(2)); err == nil || !strings.Contains(err.Error(), "prefixes") {
+		t.Errorf("Diff of another order error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,214 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+)
+
+// A BuildOption configures how BuildFiles, BuildFS, BuildURLs or
+// BuildExternal trains a Chain.
+type BuildOption func(*buildConfig)
+
+// buildConfig holds the settings of BuildOptions.
+type buildConfig struct {
+	workers     int
+	memoryLimit int64
+	tempDir     string
+	exclude     []string
+	mbox        bool
+}
+
+// WithWorkers makes BuildFiles and BuildFS read at most n files at once,
+// holding the Chains of at most n files read but not yet merged. By
+// default, they read runtime.GOMAXPROCS(0) files at once.
+func WithWorkers(n int) BuildOption {
+	return func(cfg *buildConfig) {
+		cfg.workers = n
+	}
+}
+
+// BuildFiles records the words of each of the named files as a separate
+// text, as Build would for each in turn, decompressing those compressed as
+// Decompress does. It reads and splits several files at once into Chains of
+// their own in a bounded pool of goroutines and merges those into the Chain
+// in the order the files are named. The result is the Chain building each
+// file in order would have built.
+//
+// BuildFiles returns the first error opening or reading a file, the files
+// before which remain in the Chain, or ctx.Err() if ctx is done first.
+func (c *Chain[T]) BuildFiles(ctx context.Context, paths []string, opts ...BuildOption) error {
+	cfg, err := newFilesConfig(opts)
+	if err != nil {
+		return err
+	}
+	return c.buildFiles(ctx, paths, func(name string) (io.ReadCloser, error) {
+		return os.Open(name)
+	}, false, cfg)
+}
+
+// newFilesConfig returns the buildConfig of BuildFiles or BuildFS described
+// by opts, or an error if they are invalid.
+func newFilesConfig(opts []BuildOption) (*buildConfig, error) {
+	cfg := &buildConfig{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.workers < 1 {
+		return nil, fmt.Errorf("markov: invalid number of workers %d", cfg.workers)
+	}
+	for _, pattern := range cfg.exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("markov: invalid exclusion pattern %q: %w", pattern, err)
+		}
+	}
+	return cfg, nil
+}
+
+// buildFiles builds the Chain from the named files, opened by open, as
+// BuildFiles does, leaving out those that look binary if skipBinary is
+// true.
+func (c *Chain[T]) buildFiles(ctx context.Context, names []string, open func(name string) (io.ReadCloser, error), skipBinary bool, cfg *buildConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		partial *Chain[T]
+		err     error
+	}
+	results := make([]chan result, len(names))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	// A file holds its slot until its Chain is merged, so that the files
+	// read ahead of one slow to read are not all held in memory at once.
+	sem := make(chan struct{}, cfg.workers)
+	go func() {
+		for i, name := range names {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i] <- result{err: ctx.Err()}
+				continue
+			}
+			go func() {
+				partial, err := c.buildFile(ctx, name, open, skipBinary, cfg.mbox)
+				results[i] <- result{partial, err}
+			}()
+		}
+	}()
+
+	for i := range names {
+		res := <-results[i]
+		if res.err != nil {
+			// Returning cancels ctx, so no more slots are needed.
+			return res.err
+		}
+		if res.partial != nil {
+			if err := c.merge(res.partial); err != nil {
+				return err
+			}
+		}
+		<-sem
+	}
+	return nil
+}
+
+// binaryPeek is the number of bytes at the start of a file checked for a
+// NUL byte, which marks it as binary.
+const binaryPeek = 8000
+
+// buildFile returns a new Chain configured as c built from the named file,
+// opened by open and decompressed as Decompress does, or nil if skipBinary
+// is true and the file looks binary. If mbox is true, the file is read as
+// BuildMbox reads it.
+func (c *Chain[T]) buildFile(ctx context.Context, name string, open func(name string) (io.ReadCloser, error), skipBinary, mbox bool) (*Chain[T], error) {
+	f, err := open(name)
+	if err != nil {
+		return nil, fmt.Errorf("markov: %w", err)
+	}
+	defer f.Close()
+
+	r, release, err := decompress(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("markov: decompressing %s: %w", name, err)
+	}
+	defer release()
+	if skipBinary {
+		r = bufio.NewReaderSize(r, binaryPeek)
+		if head, err := r.Peek(binaryPeek); bytes.IndexByte(head, 0) >= 0 {
+			return nil, nil
+		} else if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, fmt.Errorf("markov: reading %s: %w", name, err)
+		}
+	}
+
+	partial := c.empty()
+	build := partial.BuildContext
+	if mbox {
+		build = func(ctx context.Context, r io.Reader) error {
+			_, err := partial.BuildMbox(ctx, r)
+			return err
+		}
+	}
+	if err := build(ctx, r); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("markov: reading %s: %w", name, err)
+	}
+	return partial, nil
+}
+
+// empty returns a new Chain in memory with the prefix length and options of
+// c and none of its words.
+func (c *Chain[T]) empty() *Chain[T] {
+	e := New[T](c.prefixLen)
+	c.mu.RLock()
+	e.options = c.options
+	c.mu.RUnlock()
+	return e
+}
+
+// merge adds the words and counts of other, which has the same prefix
+// length and is not used concurrently, to the Chain, assigning the words it
+// does not know IDs in the order other assigned them.
+func (c *Chain[T]) merge(other *Chain[T]) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() {
+		if flushErr := c.store.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	ids, err := c.internAll(other.vocab.words)
+	if err != nil {
+		return err
+	}
+	c.mergeNgrams(other.ngrams)
+
+	var addErr error
+	err = other.store.each(func(k string, s *suffixes) bool {
+		prefix := unkey(k)
+		for i, id := range prefix {
+			prefix[i] = ids[id]
+		}
+		k = key(prefix)
+		for i, id := range s.words {
+			if addErr = c.store.add(k, ids[id], s.counts[i]); addErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = addErr
+	}
+	return err
+}
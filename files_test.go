@@ -0,0 +1,115 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeFiles writes each of texts to a file in a temporary directory and
+// returns their paths.
+func writeFiles(t *testing.T, texts ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, text := range texts {
+		path := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestChainBuildFiles(t *testing.T) {
+	texts := []string{"The cat sat.", "The dog ran. The cat ran.", "A bird sang", "The cat sang."}
+	paths := writeFiles(t, texts...)
+
+	want := NewChain(1, WithMarkers())
+	mustBuild(t, want, "Hello there.")
+	for _, text := range texts {
+		mustBuild(t, want, text)
+	}
+
+	for _, workers := range []int{1, 2, 10} {
+		c := NewChain(1, WithMarkers())
+		mustBuild(t, c, "Hello there.")
+		if err := c.BuildFiles(context.Background(), paths, WithWorkers(workers)); err != nil {
+			t.Fatalf("BuildFiles error: %v", err)
+		}
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: chain = %v, want %v", workers, got, want)
+		}
+		if !reflect.DeepEqual(c.vocab.words, want.vocab.words) {
+			t.Errorf("%d workers: words = %q, want %q in the order Build assigns them", workers, c.vocab.words, want.vocab.words)
+		}
+	}
+}
+
+func TestChainBuildFilesErrors(t *testing.T) {
+	paths := writeFiles(t, "a b", "c d")
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.txt")
+
+	c := NewChain(1)
+	err := c.BuildFiles(context.Background(), []string{paths[0], missing, paths[1]})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("BuildFiles error = %v, want %v", err, fs.ErrNotExist)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 1 {
+		t.Errorf("Count({a}, b) = %d, want 1 for the file before the error", got)
+	}
+	if got := c.Count(Prefix[string]{"c"}, "d"); got != 0 {
+		t.Errorf("Count({c}, d) = %d, want 0 for the file after the error", got)
+	}
+
+	if err := c.BuildFiles(context.Background(), paths, WithWorkers(0)); err == nil {
+		t.Error("BuildFiles with 0 workers succeeded, want error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewChain(1).BuildFiles(ctx, paths); !errors.Is(err, context.Canceled) {
+		t.Errorf("BuildFiles error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestChainBuildFilesBoundsMemory(t *testing.T) {
+	// While the first file is slow to read, the files after it are read
+	// only as far as the workers allow, not held in memory all at once.
+	const workers = 2
+	names := []string{"0", "1", "2", "3", "4", "5", "6", "7"}
+	var opened atomic.Int32
+	var openedAhead int32
+	open := func(name string) (io.ReadCloser, error) {
+		opened.Add(1)
+		if name == "0" {
+			time.Sleep(50 * time.Millisecond)
+			openedAhead = opened.Load()
+		}
+		return io.NopCloser(strings.NewReader("file " + name)), nil
+	}
+
+	c := NewChain(1)
+	cfg, err := newFilesConfig([]BuildOption{WithWorkers(workers)})
+	if err != nil {
+		t.Fatalf("newFilesConfig error: %v", err)
+	}
+	if err := c.buildFiles(context.Background(), names, open, false, cfg); err != nil {
+		t.Fatalf("buildFiles error: %v", err)
+	}
+	if openedAhead > workers {
+		t.Errorf("%d files opened while the first was read, want at most %d", openedAhead, workers)
+	}
+	if got := opened.Load(); got != int32(len(names)) {
+		t.Errorf("%d files opened, want %d", got, len(names))
+	}
+}
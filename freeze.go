@@ -0,0 +1,146 @@
+package markov
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// A Frozen is an immutable snapshot of a Chain laid out for generating as
+// fast as possible: every suffix and its cumulative count are kept in flat
+// slices, so each word is picked by a single map lookup and a binary
+// search, and Append allocates nothing. A Frozen is safe for concurrent use
+// without locking.
+//
+// A Frozen samples every suffix by its count, as Generate does without
+// options; it does not support the options a GenerateOption sets.
+type Frozen[T comparable] struct {
+	// c holds the options and prefix length of the Chain frozen, and none
+	// of its words, so that its methods can format the generated text.
+	c *Chain[T]
+
+	words  []T              // each word, by ID
+	index  map[string]int32 // the index of each prefix, by key
+	starts []int32          // the suffixes of prefix i are next[starts[i]:starts[i+1]]
+	next   []uint32         // the IDs of the suffixes of every prefix
+	cum    []int            // the cumulative counts of the suffixes of each prefix
+}
+
+// Freeze returns a Frozen snapshot of the Chain. Training the Chain further
+// does not change the snapshot.
+func (c *Chain[T]) Freeze() (*Frozen[T], error) {
+	m, err := c.model()
+	if err != nil {
+		return nil, fmt.Errorf("markov: freezing chain: %w", err)
+	}
+	var last uint32
+	for _, p := range m.Prefixes {
+		last = max(last, slices.Max(p.Next))
+	}
+	if int(last) >= len(m.Words) {
+		// Other Chains sharing the store added words since m was read.
+		if _, err := c.lookup([]uint32{last}); err != nil {
+			return nil, fmt.Errorf("markov: freezing chain: %w", err)
+		}
+		c.mu.RLock()
+		m.Words = slices.Clone(c.vocab.words)
+		c.mu.RUnlock()
+	}
+
+	f := &Frozen[T]{
+		c:      c.empty(),
+		words:  m.Words,
+		index:  make(map[string]int32, len(m.Prefixes)),
+		starts: make([]int32, 0, len(m.Prefixes)+1),
+	}
+	for i, p := range m.Prefixes {
+		f.index[key(p.Words)] = int32(i)
+		f.starts = append(f.starts, int32(len(f.next)))
+		total := 0
+		for j, id := range p.Next {
+			total += p.Counts[j]
+			f.next = append(f.next, id)
+			f.cum = append(f.cum, total)
+		}
+	}
+	f.starts = append(f.starts, int32(len(f.next)))
+	return f, nil
+}
+
+// Append appends at most n words generated from the Frozen snapshot to dst
+// and returns the extended slice, drawing from rng, or from the default
+// source if rng is nil. It allocates only if dst lacks the capacity.
+func (f *Frozen[T]) Append(dst []T, n int, rng *rand.Rand) []T {
+	// The window holds the key of the current prefix, on the stack unless
+	// the prefixes are unusually long, and masked holds it with the words
+	// the Chain skips filled in.
+	var (
+		buf, maskBuf   [64]byte
+		window, masked []byte
+	)
+	if size := 4 * f.c.prefixLen; size <= len(buf) {
+		window, masked = buf[:size], maskBuf[:size]
+	} else {
+		window, masked = make([]byte, size), make([]byte, size)
+	}
+
+	for ; n > 0; n-- {
+		i, ok := f.index[string(f.c.maskedKey(masked, window))]
+		if !ok {
+			break
+		}
+		cum := f.cum[f.starts[i]:f.starts[i+1]]
+		var r int
+		if rng != nil {
+			r = rng.Intn(cum[len(cum)-1])
+		} else {
+			r = rand.Intn(cum[len(cum)-1])
+		}
+		j, _ := slices.BinarySearch(cum, r+1)
+		id := f.next[int(f.starts[i])+j]
+		if id == endID {
+			break
+		}
+
+		word := f.words[id]
+		dst = append(dst, word)
+		if f.c.isBreak(word) || f.c.sentenceReset && !f.c.markers && endsSentence(word) {
+			clear(window)
+			continue
+		}
+		copy(window, window[4:])
+		binary.LittleEndian.PutUint32(window[len(window)-4:], id)
+	}
+	return dst
+}
+
+// Generate writes at most n words generated from the Frozen snapshot to w,
+// as the Chain's Generate would write them, drawing from rng, or from the
+// default source if rng is nil.
+func (f *Frozen[T]) Generate(w io.Writer, n int, rng *rand.Rand) error {
+	bw := bufio.NewWriter(w)
+	space := f.c.spacer(false)
+	for _, word := range f.Append(make([]T, 0, min(n, 1024)), n, rng) {
+		if _, err := bw.WriteString(space(word)); err != nil {
+			return err
+		}
+		if err := f.c.writeToken(bw, word); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// GenerateString returns at most n words generated from the Frozen
+// snapshot, as Generate would write them.
+func (f *Frozen[T]) GenerateString(n int, rng *rand.Rand) (string, error) {
+	var b strings.Builder
+	if err := f.Generate(&b, n, rng); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,70 @@
+package markov
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestChainFreeze(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithPunctuation())
+	mustBuild(t, c, strings.Repeat("The cat sat on the mat. The dog sat on the cat, again. ", 3))
+	f, err := c.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze error: %v", err)
+	}
+
+	// A Frozen picks the same words as the Chain given the same source.
+	for seed := int64(1); seed <= 10; seed++ {
+		want, err := c.GenerateString(30, WithRand(rand.New(rand.NewSource(seed))))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		got, err := f.GenerateString(30, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("Frozen GenerateString error: %v", err)
+		}
+		if got != want {
+			t.Errorf("Frozen GenerateString with seed %d = %q, want %q", seed, got, want)
+		}
+	}
+
+	// Training the Chain further leaves the snapshot alone.
+	mustBuild(t, c, "Birds fly.")
+	for range 20 {
+		if got, _ := f.GenerateString(30, nil); strings.Contains(got, "Birds") {
+			t.Fatalf("Frozen GenerateString = %q, want no words trained after Freeze", got)
+		}
+	}
+}
+
+func TestFrozenAppendAllocations(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, strings.Repeat("a b c a b d a c b a ", 10))
+	f, err := c.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze error: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	dst := make([]string, 0, 100)
+	if allocs := testing.AllocsPerRun(100, func() { dst = f.Append(dst[:0], 100, rng) }); allocs != 0 {
+		t.Errorf("Append made %v allocations, want 0", allocs)
+	}
+	if len(dst) != 100 {
+		t.Errorf("Append generated %d words, want 100", len(dst))
+	}
+}
+
+func TestFrozenSentenceReset(t *testing.T) {
+	c := NewChain(2, WithSentenceReset())
+	mustBuild(t, c, "Dr. Who ran. Dr. Who hid.")
+	f, err := c.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze error: %v", err)
+	}
+	got := f.Append(nil, 9, rand.New(rand.NewSource(1)))
+	if len(got) != 9 || got[3] != "Dr." || got[6] != "Dr." {
+		t.Errorf("Append = %q, want three sentences of three words", got)
+	}
+}
@@ -0,0 +1,331 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// The frozen model format written by SaveFrozen is laid out so that it can
+// be mapped into memory and searched in place, with every integer a
+// little-endian uint64 unless noted otherwise:
+//
+//	magic          "MKVF"
+//	header size
+//	header         the binary model format, from its magic through its words
+//	prefix count
+//	suffix count
+//	prefixes       sorted by key, each prefix length uint32 word IDs, the
+//	               index of its first suffix and a uint32 suffix count
+//	suffixes       each a uint32 word ID and a count, those of a prefix in
+//	               the order they were first observed
+//
+// A model whose counts are quantized by WithQuantizedCounts starts with
+// frozenQuantizedMagic instead, and the count of each of its suffixes is the
+// uint8 bucket of quantizeCount.
+const (
+	frozenMagic          = "MKVF"
+	frozenQuantizedMagic = "MKVQ"
+)
+
+// Sizes of the records of the frozen model format.
+const (
+	frozenSuffixSize          = 4 + 8
+	frozenQuantizedSuffixSize = 4 + 1
+	frozenFixedSize           = len(frozenMagic) + 8 + 8 + 8
+)
+
+// errFrozen is returned when training a Chain opened by OpenFrozen.
+var errFrozen = errors.New("markov: chain is frozen")
+
+// SaveFrozen writes the Chain to w in a format that OpenFrozenChain maps into
+// memory and queries in place, without reading it, so that a serving process
+// starts at once however large the Chain, and processes serving the same
+// file share its pages. Words must be strings or integers.
+func (c *Chain[T]) SaveFrozen(w io.Writer, opts ...SaveOption) error {
+	var cfg saveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	kind, err := wordKind[T]()
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+
+	var header bytes.Buffer
+	e := &binaryEncoder{w: bufio.NewWriter(&header)}
+	encodeBinaryHeader(e, m, kind)
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	if e.err != nil {
+		return fmt.Errorf("markov: saving chain: %w", e.err)
+	}
+
+	bw := bufio.NewWriter(w)
+	if cfg.quantize {
+		bw.WriteString(frozenQuantizedMagic)
+	} else {
+		bw.WriteString(frozenMagic)
+	}
+	b := binary.LittleEndian.AppendUint64(nil, uint64(header.Len()))
+	bw.Write(b)
+	bw.Write(header.Bytes())
+
+	nsuffixes := 0
+	for _, p := range m.Prefixes {
+		nsuffixes += len(p.Next)
+	}
+	b = binary.LittleEndian.AppendUint64(b[:0], uint64(len(m.Prefixes)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(nsuffixes))
+	bw.Write(b)
+
+	first := 0
+	for _, p := range m.Prefixes {
+		b = b[:0]
+		for _, id := range p.Words {
+			b = binary.LittleEndian.AppendUint32(b, id)
+		}
+		b = binary.LittleEndian.AppendUint64(b, uint64(first))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(p.Next)))
+		bw.Write(b)
+		first += len(p.Next)
+	}
+	for _, p := range m.Prefixes {
+		for i, id := range p.Next {
+			b = binary.LittleEndian.AppendUint32(b[:0], id)
+			if cfg.quantize {
+				b = append(b, quantizeCount(p.Counts[i]))
+			} else {
+				b = binary.LittleEndian.AppendUint64(b, uint64(p.Counts[i]))
+			}
+			bw.Write(b)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+	return nil
+}
+
+// OpenFrozenChain returns a Chain of strings that generates from the file
+// written by SaveFrozen at path, which it maps into memory where the
+// platform allows. Only the vocabulary is read; each prefix is found by a
+// binary search of the file when it is generated from. The Chain is
+// configured by the file's saved options followed by opts.
+//
+// The Chain cannot be trained: Build and Add return an error. Close unmaps
+// the file once the Chain is no longer used.
+func OpenFrozenChain(path string, opts ...Option) (*Chain[string], error) {
+	return OpenFrozen[string](path, opts...)
+}
+
+// OpenFrozen is like OpenFrozenChain but returns a Chain of words of type T,
+// which must be strings or integers.
+func OpenFrozen[T comparable](path string, opts ...Option) (*Chain[T], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	defer f.Close()
+	data, unmap, err := mmapFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	c, err := openFrozen[T](data, unmap, opts)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	return c, nil
+}
+
+// openFrozen returns a Chain generating from the frozen model data, which
+// unmap releases.
+func openFrozen[T comparable](data []byte, unmap func() error, opts []Option) (*Chain[T], error) {
+	invalid := errors.New("markov: opening chain: not a frozen model")
+	if len(data) < frozenFixedSize {
+		return nil, invalid
+	}
+	var quantized bool
+	switch string(data[:len(frozenMagic)]) {
+	case frozenMagic:
+	case frozenQuantizedMagic:
+		quantized = true
+	default:
+		return nil, invalid
+	}
+	rest := data[len(frozenMagic):]
+	size := binary.LittleEndian.Uint64(rest)
+	rest = rest[8:]
+	if size > uint64(len(rest)-16) {
+		return nil, invalid
+	}
+	d := &binaryDecoder{r: bufio.NewReader(bytes.NewReader(rest[:size]))}
+	m, err := decodeBinaryHeader[T](d)
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	c, err := m.empty(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rest = rest[size:]
+	nprefixes := binary.LittleEndian.Uint64(rest)
+	nsuffixes := binary.LittleEndian.Uint64(rest[8:])
+	rest = rest[16:]
+	s := &frozenStore{
+		keyLen:     4 * m.PrefixLen,
+		nwords:     uint32(len(m.Words)),
+		unmap:      unmap,
+		nprefix:    int(nprefixes),
+		quantized:  quantized,
+		suffixSize: frozenSuffixSize,
+	}
+	if quantized {
+		s.suffixSize = frozenQuantizedSuffixSize
+	}
+	s.prefixSize = s.keyLen + 8 + 4
+	if nprefixes > uint64(len(rest)/s.prefixSize) || nsuffixes > uint64(len(rest)/s.suffixSize) ||
+		nprefixes*uint64(s.prefixSize)+nsuffixes*uint64(s.suffixSize) != uint64(len(rest)) {
+		return nil, fmt.Errorf("markov: opening chain: frozen model is %d bytes, not as long as it records", len(data))
+	}
+	s.prefixes = rest[:int(nprefixes)*s.prefixSize]
+	s.suffixes = rest[len(s.prefixes):]
+	c.store = s
+	return c, nil
+}
+
+// Close releases the resources held by a Chain opened by OpenFrozen, after
+// which it must not be used. It does nothing for any other Chain.
+func (c *Chain[T]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.store.(io.Closer); ok {
+		return s.Close()
+	}
+	return nil
+}
+
+// frozenStore is the read-only store of a Chain opened by OpenFrozen, which
+// searches the prefixes of the frozen model in place. Records are checked as
+// they are read rather than when the model is opened, so that opening it
+// does not read it all.
+type frozenStore struct {
+	prefixes   []byte // the prefix records
+	suffixes   []byte // the suffix records
+	nprefix    int
+	keyLen     int
+	prefixSize int
+	suffixSize int
+	quantized  bool // counts are buckets of quantizeCount
+	nwords     uint32
+	unmap      func() error
+}
+
+func (s *frozenStore) get(k string) (*suffixes, error) {
+	i := sort.Search(s.nprefix, func(i int) bool {
+		return string(s.key(i)) >= k
+	})
+	if i == s.nprefix || string(s.key(i)) != k {
+		return nil, nil
+	}
+	return s.suffixesAt(i)
+}
+
+// key returns the key of the ith prefix.
+func (s *frozenStore) key(i int) []byte {
+	off := i * s.prefixSize
+	return s.prefixes[off : off+s.keyLen]
+}
+
+// suffixesAt returns the suffixes of the ith prefix.
+func (s *frozenStore) suffixesAt(i int) (*suffixes, error) {
+	rec := s.prefixes[i*s.prefixSize+s.keyLen:]
+	first, n := binary.LittleEndian.Uint64(rec), uint64(binary.LittleEndian.Uint32(rec[8:]))
+	if total := uint64(len(s.suffixes) / s.suffixSize); n == 0 || first > total || n > total-first {
+		return nil, fmt.Errorf("markov: invalid suffixes of prefix %v", unkey(string(s.key(i))))
+	}
+	suf := newSuffixes()
+	for j := range int(n) {
+		rec := s.suffixes[(int(first)+j)*s.suffixSize:]
+		id := binary.LittleEndian.Uint32(rec)
+		var count uint64
+		if s.quantized {
+			count = uint64(dequantizeCount(rec[4]))
+		} else {
+			count = binary.LittleEndian.Uint64(rec[4:])
+		}
+		if id >= s.nwords || count == 0 || count > 1<<62 {
+			return nil, fmt.Errorf("markov: invalid suffixes of prefix %v", unkey(string(s.key(i))))
+		}
+		suf.addCount(id, int(count))
+	}
+	return suf, nil
+}
+
+func (s *frozenStore) add(string, uint32, int) error {
+	return errFrozen
+}
+
+func (s *frozenStore) intern([]byte, uint32) (uint32, error) {
+	return noID, errFrozen
+}
+
+func (s *frozenStore) wordsFrom(uint32) ([][]byte, error) {
+	return nil, nil
+}
+
+func (s *frozenStore) meta() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *frozenStore) setMeta([]byte) error {
+	return errFrozen
+}
+
+func (s *frozenStore) flush() error {
+	return nil
+}
+
+func (s *frozenStore) put(string, *suffixes) error {
+	return errFrozen
+}
+
+func (s *frozenStore) remove(string) error {
+	return errFrozen
+}
+
+func (s *frozenStore) each(fn func(k string, s *suffixes) bool) error {
+	for i := range s.nprefix {
+		suf, err := s.suffixesAt(i)
+		if err != nil {
+			return err
+		}
+		if !fn(string(s.key(i)), suf) {
+			break
+		}
+	}
+	return nil
+}
+
+// Close unmaps the frozen model.
+func (s *frozenStore) Close() error {
+	if s.unmap == nil {
+		return nil
+	}
+	err := s.unmap()
+	s.unmap, s.prefixes, s.suffixes, s.nprefix = nil, nil, nil, 0
+	return err
+}
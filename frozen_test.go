@@ -0,0 +1,160 @@
+package markov
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// saveFrozen writes c frozen to a temporary file and returns its path.
+func saveFrozen[T comparable](t *testing.T, c *Chain[T]) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chain.frozen")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	defer f.Close()
+	if err := c.SaveFrozen(f); err != nil {
+		t.Fatalf("SaveFrozen error: %v", err)
+	}
+	return path
+}
+
+func TestOpenFrozenChain(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithLowercase())
+	mustBuild(t, c, strings.Repeat("The quick brown fox jumps over a lazy dog. Who saw it? ", 5))
+
+	frozen, err := OpenFrozenChain(saveFrozen(t, c))
+	if err != nil {
+		t.Fatalf("OpenFrozenChain error: %v", err)
+	}
+	defer frozen.Close()
+
+	if got, want := chainCounts(frozen), chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("frozen chain = %v, want %v", got, want)
+	}
+	if got, want := frozen.options.saved(), c.options.saved(); !reflect.DeepEqual(got, want) {
+		t.Errorf("frozen options = %+v, want %+v", got, want)
+	}
+	if got := frozen.Count(Prefix[string]{"the", "quick"}, "brown"); got != 5 {
+		t.Errorf("Count({the quick}, brown) = %d, want 5", got)
+	}
+	got, err := frozen.GenerateString(20, WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want, _ := c.GenerateString(20, WithGreedy()); got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+
+	if err := frozen.Build(strings.NewReader("more words")); !errors.Is(err, errFrozen) {
+		t.Errorf("Build error = %v, want %v", err, errFrozen)
+	}
+	if err := frozen.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+}
+
+func TestOpenFrozenIntegers(t *testing.T) {
+	c := New[int](1)
+	c.Add(-1, 2, -1, 300)
+
+	frozen, err := OpenFrozen[int](saveFrozen(t, c))
+	if err != nil {
+		t.Fatalf("OpenFrozen error: %v", err)
+	}
+	defer frozen.Close()
+	if got := frozen.Count(Prefix[int]{-1}, 300); got != 1 {
+		t.Errorf("Count({-1}, 300) = %d, want 1", got)
+	}
+	if _, err := OpenFrozen[string](saveFrozen(t, c)); err == nil {
+		t.Error("OpenFrozen of ints as strings succeeded, want error")
+	}
+}
+
+func TestOpenFrozenInvalid(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	data, err := os.ReadFile(saveFrozen(t, c))
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+
+	for _, data := range [][]byte{nil, []byte("MKVF"), data[:len(data)-1], append(data, 0)} {
+		if _, err := openFrozen[string](data, nil, nil); err == nil {
+			t.Errorf("openFrozen of %d bytes succeeded, want error", len(data))
+		}
+	}
+
+	// A suffix with a word ID out of range is reported when it is read.
+	bad := append([]byte(nil), data...)
+	copy(bad[len(bad)-frozenSuffixSize:], []byte{0xff, 0xff, 0, 0})
+	frozen, err := openFrozen[string](bad, nil, nil)
+	if err != nil {
+		t.Fatalf("openFrozen error: %v", err)
+	}
+	if _, err := frozen.GenerateString(3); err == nil {
+		t.Error("GenerateString with an invalid word ID succeeded, want error")
+	}
+}
+
+func TestOpenFrozenQuantized(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, strings.Repeat("a b ", 1000)+strings.Repeat("a c ", 17)+"a d")
+
+	path := filepath.Join(t.TempDir(), "chain.frozen")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if err := c.SaveFrozen(f, WithQuantizedCounts()); err != nil {
+		t.Fatalf("SaveFrozen error: %v", err)
+	}
+	f.Close()
+	if info, _ := os.Stat(path); info.Size() >= int64(len(readFile(t, saveFrozen(t, c)))) {
+		t.Errorf("quantized frozen model is %d bytes, want fewer than unquantized", info.Size())
+	}
+
+	frozen, err := OpenFrozenChain(path)
+	if err != nil {
+		t.Fatalf("OpenFrozenChain error: %v", err)
+	}
+	defer frozen.Close()
+	for word, want := range map[string]int{"b": 1000, "c": 17, "d": 1} {
+		got := frozen.Count(Prefix[string]{"a"}, word)
+		if diff := got - want; diff*100 > want*6 || -diff*100 > want*6 {
+			t.Errorf("Count({a}, %s) = %d, want within 6%% of %d", word, got, want)
+		}
+	}
+	if got := frozen.Count(Prefix[string]{"a"}, "d"); got != 1 {
+		t.Errorf("Count({a}, d) = %d, want 1 exactly", got)
+	}
+}
+
+func TestQuantizeCount(t *testing.T) {
+	prev := 0
+	for b := 1; b <= 255; b++ {
+		n := dequantizeCount(uint8(b))
+		if n <= prev {
+			t.Fatalf("dequantizeCount(%d) = %d, want more than %d", b, n, prev)
+		}
+		if got := quantizeCount(n); got != uint8(b) {
+			t.Errorf("quantizeCount(%d) = %d, want %d", n, got, b)
+		}
+		prev = n
+	}
+}
+
+// readFile returns the contents of the named file.
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	return data
+}
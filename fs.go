@@ -0,0 +1,72 @@
+package markov
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// WithExclude makes BuildFS leave out the files and directories whose names
+// match any of patterns, as path.Match matches them, such as ".git" or
+// "*.min.js".
+func WithExclude(patterns ...string) BuildOption {
+	return func(cfg *buildConfig) {
+		cfg.exclude = append(cfg.exclude, patterns...)
+	}
+}
+
+// BuildFS is like BuildFiles but builds the Chain from every regular file of
+// fsys whose name matches pattern, as path.Match matches it, such as
+// "*.txt", or from every file if pattern is empty, walking its directories
+// recursively in lexical order. Files holding a NUL byte among their first
+// 8000 bytes, once decompressed, are taken to be binary and left out,
+// unless the Chain splits its input into Bytes, as are the files and
+// directories excluded WithExclude.
+func (c *Chain[T]) BuildFS(ctx context.Context, fsys fs.FS, pattern string, opts ...BuildOption) error {
+	cfg, err := newFilesConfig(opts)
+	if err != nil {
+		return err
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("markov: invalid pattern %q: %w", pattern, err)
+	}
+
+	var names []string
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && cfg.excludes(d.Name()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if ok, _ := path.Match(pattern, d.Name()); ok || pattern == "" {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("markov: %w", err)
+	}
+	return c.buildFiles(ctx, names, func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}, c.mode != Bytes, cfg)
+}
+
+// excludes reports whether the file or directory with the given name is
+// excluded WithExclude.
+func (cfg *buildConfig) excludes(name string) bool {
+	for _, pattern := range cfg.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
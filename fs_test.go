@@ -0,0 +1,64 @@
+package markov
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestChainBuildFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":             {Data: []byte("The cat sat.")},
+		"b/c.txt":           {Data: []byte("The dog ran.")},
+		"b/d/e.txt":         {Data: []byte("A bird sang.")},
+		"b/notes.md":        {Data: []byte("Not text.")},
+		"b/image.txt":       {Data: []byte("GIF89a\x00\x01")},
+		"vendor/f.txt":      {Data: []byte("Left out.")},
+		"b/skip.min.txt":    {Data: []byte("Left out too.")},
+		"b/d/empty/g.txt":   {Data: []byte("The end.")},
+		"b/d/empty/unused":  {Data: []byte("Unmatched.")},
+		"vendor/deep/h.txt": {Data: []byte("Left out.")},
+	}
+
+	want := NewChain(1, WithMarkers())
+	for _, text := range []string{"The cat sat.", "The dog ran.", "A bird sang.", "The end."} {
+		mustBuild(t, want, text)
+	}
+
+	for _, workers := range []int{1, 3} {
+		c := NewChain(1, WithMarkers())
+		err := c.BuildFS(context.Background(), fsys, "*.txt", WithWorkers(workers), WithExclude("vendor", "*.min.txt"))
+		if err != nil {
+			t.Fatalf("BuildFS error: %v", err)
+		}
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: chain = %v, want %v", workers, got, want)
+		}
+	}
+}
+
+func TestChainBuildFSBytes(t *testing.T) {
+	fsys := fstest.MapFS{"a.bin": {Data: []byte("a\x00b")}}
+	c := New[byte](1, WithMode(Bytes))
+	if err := c.BuildFS(context.Background(), fsys, ""); err != nil {
+		t.Fatalf("BuildFS error: %v", err)
+	}
+	if got := c.Count(Prefix[byte]{'a'}, 0); got != 1 {
+		t.Errorf("Count({a}, NUL) = %d, want 1 for binary files of a Chain of bytes", got)
+	}
+}
+
+func TestChainBuildFSErrors(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a b")}}
+	c := NewChain(1)
+	if err := c.BuildFS(context.Background(), fsys, "["); err == nil {
+		t.Error("BuildFS with an invalid pattern succeeded, want error")
+	}
+	if err := c.BuildFS(context.Background(), fsys, "*", WithExclude("[")); err == nil {
+		t.Error("BuildFS with an invalid exclusion pattern succeeded, want error")
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 0 {
+		t.Errorf("Count({a}, b) = %d, want 0 after invalid patterns", got)
+	}
+}
@@ -0,0 +1,568 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// A GenerateOption configures a call to Generate.
+type GenerateOption func(*generateConfig)
+
+// generateConfig holds the settings applied by GenerateOptions.
+type generateConfig struct {
+	rand        *rand.Rand
+	prompt      string
+	stops       []string
+	temperature float64
+	topK        int
+	topP        float64
+	greedy      bool
+	beamWidth   int
+	onWord      any // a WordFunc[T] for the Chain's T
+	maxOverlap  int
+	banned      []string
+	maxChars    int
+
+	completeSentences bool
+
+	// bannedIDs holds the IDs of the words banned, set by walk.
+	bannedIDs map[uint32]bool
+
+	sentenceCase bool
+	tidy         bool
+
+	repeatNgram   int
+	repeatWindow  int
+	repeatPenalty float64
+}
+
+// newGenerateConfig returns the generateConfig described by opts, or an error
+// if they are invalid.
+func newGenerateConfig(opts []GenerateOption) (*generateConfig, error) {
+	cfg := &generateConfig{temperature: 1, topP: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if !(cfg.temperature > 0) || math.IsInf(cfg.temperature, 1) {
+		return nil, errors.New("markov: temperature must be positive and finite")
+	}
+	if cfg.topK < 0 {
+		return nil, errors.New("markov: top-k must not be negative")
+	}
+	if !(cfg.topP > 0 && cfg.topP <= 1) {
+		return nil, errors.New("markov: top-p must be in (0, 1]")
+	}
+	if cfg.beamWidth < 0 {
+		return nil, errors.New("markov: beam width must not be negative")
+	}
+	if cfg.repeatNgram < 0 || cfg.repeatNgram > cfg.repeatWindow ||
+		!(cfg.repeatPenalty >= 0 && cfg.repeatPenalty <= 1) {
+		return nil, errors.New("markov: invalid repetition penalty")
+	}
+	if cfg.maxOverlap < 0 {
+		return nil, errors.New("markov: maximum overlap must not be negative")
+	}
+	if cfg.maxChars < 0 {
+		return nil, errors.New("markov: maximum number of characters must not be negative")
+	}
+	return cfg, nil
+}
+
+// WithRand makes Generate draw random numbers from r instead of the
+// top-level functions of math/rand, so that the same seed always yields the
+// same text from the same Chain. A *rand.Rand is not safe for concurrent use,
+// so r must not be shared between concurrent calls to Generate.
+func WithRand(r *rand.Rand) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.rand = r
+	}
+}
+
+// WithPrompt makes Generate continue the given text rather than start from
+// the beginning of a text. The prompt is split into words the same way Build
+// splits its input, and its last words prime the prefix generation starts
+// from; a prompt shorter than the Chain's prefixes is treated as the start of
+// a text. Only the continuation is written, not the prompt itself.
+func WithPrompt(text string) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.prompt = text
+	}
+}
+
+// WithStop makes Generate stop as soon as it has generated any of the given
+// sequences of words, even if fewer than n words have been generated. Each
+// sequence is split into words the same way Build splits its input, and the
+// stop sequence itself is included in the output.
+func WithStop(sequences ...string) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.stops = append(cfg.stops, sequences...)
+	}
+}
+
+// WithTemperature reshapes the distribution suffixes are sampled from by
+// raising each suffix's count to the power 1/t. A temperature of 1, the
+// default, samples suffixes in proportion to how often they were observed.
+// Lower temperatures favor the most common suffixes, producing conservative,
+// corpus-like text, while higher temperatures flatten the distribution
+// towards uniform, producing wilder text. t must be positive.
+func WithTemperature(t float64) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.temperature = t
+	}
+}
+
+// WithTopK restricts sampling to the k most frequent suffixes of each
+// prefix, which keeps rare, often spurious transitions out of the generated
+// text. Suffixes tied in frequency are ranked by when they were first
+// observed. A k of 0, the default, considers every suffix.
+func WithTopK(k int) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.topK = k
+	}
+}
+
+// WithTopP restricts sampling to the smallest set of each prefix's most
+// frequent suffixes whose combined probability reaches p, known as nucleus
+// sampling. Unlike WithTopK, the number of suffixes considered adapts to how
+// peaked each prefix's distribution is. Probabilities are taken after any
+// temperature and top-k restriction are applied. A p of 1, the default,
+// considers every suffix; p must be in (0, 1].
+func WithTopP(p float64) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.topP = p
+	}
+}
+
+// WithGreedy makes Generate walk the Chain deterministically, always
+// choosing the most frequent suffix of each prefix instead of sampling one.
+// Suffixes tied in frequency are resolved in favor of the one observed first.
+// Greedy generation ignores the random source and any temperature, top-k or
+// top-p restriction.
+func WithGreedy() GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.greedy = true
+	}
+}
+
+// SkipWord and StopGeneration are special values a WordFunc may return to
+// steer generation without failing it.
+var (
+	// SkipWord drops the word from the output. Generation continues from
+	// the word as though it had been emitted.
+	SkipWord = errors.New("skip this word")
+
+	// StopGeneration ends generation before the word is emitted.
+	StopGeneration = errors.New("stop generating")
+)
+
+// A WordFunc is called with each word Generate is about to emit and the
+// prefix the word was generated from. The prefix must not be modified or
+// retained. If the function returns SkipWord or StopGeneration, Generate
+// acts as described for those values; any other non-nil error ends
+// generation and is returned by Generate.
+type WordFunc[T comparable] func(word T, prefix Prefix[T]) error
+
+// WithWordFunc makes Generate call fn with each word it generates, which
+// allows live display, filtering and custom stopping logic. The prefix
+// passed to fn is shorter than the Chain's prefixes at the start of a text.
+// Generate fails if T is not the Chain's type of word.
+func WithWordFunc[T comparable](fn WordFunc[T]) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.onWord = fn
+	}
+}
+
+// weighted reports whether suffixes must be sampled from weights computed by
+// cfg rather than directly from their counts.
+func (cfg *generateConfig) weighted() bool {
+	return cfg.temperature != 1 || cfg.topK > 0 || cfg.topP < 1
+}
+
+// weights returns the sampling weight of each of s's words under cfg.
+func (cfg *generateConfig) weights(s *suffixes) []float64 {
+	max := 0
+	for _, n := range s.counts {
+		if n > max {
+			max = n
+		}
+	}
+
+	// Weights are taken relative to the most common suffix so that low
+	// temperatures cannot underflow every weight to zero.
+	w := make([]float64, len(s.counts))
+	for i, n := range s.counts {
+		w[i] = math.Pow(float64(n)/float64(max), 1/cfg.temperature)
+	}
+
+	if cfg.topK == 0 && cfg.topP == 1 {
+		return w
+	}
+
+	// Temperature preserves the order of the counts, so ranking by count
+	// also ranks by weight.
+	ranked := s.ranked()
+	if cfg.topK > 0 && cfg.topK < len(ranked) {
+		for _, i := range ranked[cfg.topK:] {
+			w[i] = 0
+		}
+		ranked = ranked[:cfg.topK]
+	}
+	if cfg.topP < 1 {
+		var total float64
+		for _, i := range ranked {
+			total += w[i]
+		}
+		var sum float64
+		for j, i := range ranked {
+			sum += w[i]
+			if sum >= cfg.topP*total {
+				for _, i := range ranked[j+1:] {
+					w[i] = 0
+				}
+				break
+			}
+		}
+	}
+	return w
+}
+
+// probabilities returns the probability of each of s's words under cfg.
+func (cfg *generateConfig) probabilities(s *suffixes) []float64 {
+	p := make([]float64, len(s.counts))
+	if !cfg.weighted() {
+		for i, n := range s.counts {
+			p[i] = float64(n) / float64(s.total)
+		}
+		return p
+	}
+
+	w := cfg.weights(s)
+	var total float64
+	for _, x := range w {
+		total += x
+	}
+	for i, x := range w {
+		p[i] = x / total
+	}
+	return p
+}
+
+// sample returns an index into weights picked with probability proportional
+// to its weight. At least one weight must be positive.
+func (cfg *generateConfig) sample(weights []float64) int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	r := cfg.float64() * total
+	last := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			return i
+		}
+		r -= w
+		last = i
+	}
+
+	// Rounding may leave r just past the final weight.
+	return last
+}
+
+// walk generates at most n words from c as configured by cfg, passing each
+// to yield until it returns false, in reading order once all are generated
+// if the Chain is reversed. It returns ctx.Err() if ctx is done first.
+func (c *Chain[T]) walk(ctx context.Context, n int, cfg *generateConfig, yield func(T) bool) error {
+	var onWord WordFunc[T]
+	if cfg.onWord != nil {
+		fn, ok := cfg.onWord.(WordFunc[T])
+		if !ok {
+			return fmt.Errorf("markov: %T does not take words of type %T", cfg.onWord, *new(T))
+		}
+		onWord = fn
+	}
+	if err := c.checkOverlap(cfg); err != nil {
+		return err
+	}
+	if err := c.checkBudget(cfg); err != nil {
+		return err
+	}
+
+	prompt := c.split(cfg.prompt)
+	var stopWords [][]T
+	for _, seq := range cfg.stops {
+		stopWords = append(stopWords, c.split(seq))
+	}
+	if c.reversed {
+		// The Chain reads texts backwards, and the words it generates are
+		// yielded in reading order once all are generated.
+		slices.Reverse(prompt)
+		for _, words := range stopWords {
+			slices.Reverse(words)
+		}
+		var backwards []T
+		emit := yield
+		yield = func(word T) bool {
+			backwards = append(backwards, word)
+			return true
+		}
+		defer func() {
+			var caser *sentenceCaser[T]
+			if cfg.sentenceCase {
+				caser = c.newSentenceCaser(nil, cfg.tidy)
+			}
+			for _, word := range slices.Backward(backwards) {
+				if caser != nil {
+					word = caser.capitalize(word)
+				}
+				if !emit(word) {
+					return
+				}
+			}
+		}()
+	}
+
+	c.mu.RLock()
+	recent := c.ids(prompt)
+	window := c.window(recent)
+	if len(prompt) > 0 && !c.reversed {
+		last := prompt[len(prompt)-1]
+		if c.isBreak(last) || c.resetsSentences() && endsSentence(last) {
+			window = c.window(nil)
+		}
+	}
+
+	// A stop sequence containing a word never observed cannot be generated.
+	var stops [][]uint32
+	stopLen := 0
+	for _, words := range stopWords {
+		if ids := c.ids(words); len(ids) > 0 && !slices.Contains(ids, noID) {
+			stops = append(stops, ids)
+			stopLen = max(stopLen, len(ids))
+		}
+	}
+	if banned := c.bannedIDs(cfg.banned); banned != nil {
+		sub := *cfg
+		sub.bannedIDs = banned
+		cfg = &sub
+	}
+	c.mu.RUnlock()
+
+	var lookupErr error
+	next := func(window Prefix[uint32]) (uint32, T, bool) {
+		id, word, ok, err := c.next(window, recent, cfg)
+		if err != nil {
+			lookupErr = err
+			return id, word, false
+		}
+		if ok && (cfg.penalizesRepeats() || cfg.maxOverlap > 0) {
+			recent = append(recent, id)
+			if len(recent) > max(cfg.repeatWindow, cfg.maxOverlap) {
+				recent = recent[1:]
+			}
+		}
+		return id, word, ok
+	}
+	if cfg.beamWidth > 0 {
+		ids, err := c.beamSearch(ctx, window, n, cfg)
+		if err != nil {
+			return err
+		}
+		words, err := c.lookup(ids)
+		if err != nil {
+			return err
+		}
+
+		next = func(Prefix[uint32]) (uint32, T, bool) {
+			if len(ids) == 0 {
+				var zero T
+				return 0, zero, false
+			}
+			id, word := ids[0], words[0]
+			ids, words = ids[1:], words[1:]
+			return id, word, true
+		}
+	}
+
+	var caser *sentenceCaser[T]
+	if cfg.sentenceCase && !c.reversed {
+		caser = c.newSentenceCaser(prompt, cfg.tidy)
+	}
+
+	cut := true // whether generation stops short of the end of a text
+	if cfg.maxChars > 0 || cfg.completeSentences {
+		b := c.newBudget(cfg, yield)
+		yield = b.add
+		defer func() { b.finish(cut) }()
+	}
+
+	var tail []uint32
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		id, word, ok := next(window)
+		if !ok || id == endID {
+			cut = false
+			break
+		}
+
+		var err error
+		if onWord != nil {
+			c.mu.RLock()
+			prefix := c.words(window)
+			c.mu.RUnlock()
+			err = onWord(word, prefix)
+		}
+		switch err {
+		case nil:
+			if caser != nil {
+				word = caser.capitalize(word)
+			}
+			if !yield(word) {
+				return nil
+			}
+		case SkipWord:
+		case StopGeneration:
+			cut = false
+			return nil
+		default:
+			return err
+		}
+
+		window.Shift(id)
+		if c.isBreak(word) || c.sentenceReset && !c.markers && endsSentence(word) {
+			window = c.window(nil)
+		}
+
+		if len(stops) > 0 {
+			tail = append(tail, id)
+			if len(tail) > stopLen {
+				tail = tail[1:]
+			}
+			if endsWithAny(tail, stops) {
+				cut = false
+				break
+			}
+		}
+	}
+
+	return lookupErr
+}
+
+// next picks a random suffix of window given the IDs of the recently
+// generated words, reporting false if it has none that may be picked.
+func (c *Chain[T]) next(window Prefix[uint32], recent []uint32, cfg *generateConfig) (uint32, T, bool, error) {
+	var zero T
+	c.mu.RLock()
+	s, err := c.get(window)
+	var (
+		id uint32
+		ok bool
+	)
+	if err == nil {
+		id, ok = c.pick(s, recent, cfg)
+	}
+	if c.backoff && !ok {
+		// Back off to the longest shorter context that has a word to pick.
+		shorter := make(Prefix[uint32], len(window))
+		for n := len(window) - 1; err == nil && !ok && n >= 0; n-- {
+			if s, err = c.get(shortened(shorter, window, n)); err == nil {
+				id, ok = c.pick(s, recent, cfg)
+			}
+		}
+	}
+	c.mu.RUnlock()
+	if err != nil || !ok {
+		return 0, zero, false, err
+	}
+
+	words, err := c.lookup([]uint32{id})
+	if err != nil {
+		return 0, zero, false, err
+	}
+	return id, words[0], true, nil
+}
+
+// pick picks a random word of s given the IDs of the recently generated
+// words, reporting false if s is nil or has no word that may be picked.
+func (c *Chain[T]) pick(s *suffixes, recent []uint32, cfg *generateConfig) (uint32, bool) {
+	if s == nil {
+		return 0, false
+	}
+	if cfg.penalizesRepeats() || cfg.maxOverlap > 0 || cfg.bannedIDs != nil {
+		w := cfg.weights(s)
+		if cfg.penalizesRepeats() {
+			cfg.penalizeRepeats(w, s, recent)
+		}
+		if cfg.maxOverlap > 0 {
+			c.forbidOverlaps(w, s, recent, cfg)
+		}
+		if cfg.bannedIDs != nil {
+			cfg.forbidBanned(w, s)
+		}
+		i := heaviest(w)
+		if w[i] == 0 {
+			return 0, false
+		}
+		if !cfg.greedy {
+			i = cfg.sample(w)
+		}
+		return s.words[i], true
+	}
+	if cfg.greedy {
+		return s.words[s.mostFrequent()], true
+	}
+	if cfg.weighted() {
+		return s.words[cfg.sample(cfg.weights(s))], true
+	}
+	if s.alias != nil {
+		return s.words[s.alias.pick(cfg.intn(len(s.words)), cfg.intn(s.total))], true
+	}
+	return s.pick(cfg.intn(s.total)), true
+}
+
+// endsWithAny reports whether the generated word IDs end with any of seqs.
+func endsWithAny(generated []uint32, seqs [][]uint32) bool {
+	for _, seq := range seqs {
+		if len(generated) >= len(seq) && slices.Equal(generated[len(generated)-len(seq):], seq) {
+			return true
+		}
+	}
+	return false
+}
+
+// heaviest returns the index of the first of the largest weights.
+func heaviest(weights []float64) int {
+	best := 0
+	for i, w := range weights {
+		if w > weights[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// intn returns a random int in [0, n) from the configured source.
+func (cfg *generateConfig) intn(n int) int {
+	if cfg.rand != nil {
+		return cfg.rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// float64 returns a random float64 in [0.0, 1.0) from the configured source.
+func (cfg *generateConfig) float64() float64 {
+	if cfg.rand != nil {
+		return cfg.rand.Float64()
+	}
+	return rand.Float64()
+}
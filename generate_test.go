@@ -0,0 +1,281 @@
+package markov
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWithRand(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c a d b c d a b d c a")
+
+	generate := func(seed int64) string {
+		var b strings.Builder
+		if err := c.Generate(&b, 50, WithRand(rand.New(rand.NewSource(seed)))); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		return b.String()
+	}
+
+	if a, b := generate(1), generate(1); a != b {
+		t.Errorf("same seed generated different text:\n%q\n%q", a, b)
+	}
+	if a, b := generate(1), generate(2); a == b {
+		t.Errorf("different seeds generated the same text: %q", a)
+	}
+}
+
+func TestGenerateConfigWeights(t *testing.T) {
+	s := newSuffixes()
+	for _, word := range []uint32{'a', 'b', 'b', 'c', 'c', 'c', 'c'} {
+		s.add(word)
+	}
+
+	tests := []struct {
+		temperature float64
+		want        []float64
+	}{
+		{1, []float64{0.25, 0.5, 1}},
+		{0.5, []float64{0.0625, 0.25, 1}},
+		{2, []float64{0.5, math.Sqrt(0.5), 1}},
+	}
+	for _, tt := range tests {
+		cfg, err := newGenerateConfig([]GenerateOption{WithTemperature(tt.temperature)})
+		if err != nil {
+			t.Fatalf("temperature %v: %v", tt.temperature, err)
+		}
+		got := cfg.weights(s)
+		for i := range tt.want {
+			if math.Abs(got[i]-tt.want[i]) > 1e-12 {
+				t.Errorf("temperature %v: weights = %v, want %v", tt.temperature, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGenerateInvalidTemperature(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	for _, temp := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		var b strings.Builder
+		if err := c.Generate(&b, 10, WithTemperature(temp)); err == nil {
+			t.Errorf("Generate with temperature %v succeeded, want error", temp)
+		}
+	}
+}
+
+func TestGenerateLowTemperature(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a b a b a c")
+
+	var b strings.Builder
+	err := c.Generate(&b, 6, WithTemperature(0.01), WithRand(rand.New(rand.NewSource(1))))
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got, want := b.String(), "a b a b a b"; got != want {
+		t.Errorf("Generate = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateConfigWeightsTopK(t *testing.T) {
+	s := newSuffixes()
+	for _, word := range []uint32{'a', 'b', 'b', 'c', 'd', 'd'} {
+		s.add(word)
+	}
+
+	tests := []struct {
+		k    int
+		want []float64
+	}{
+		{0, []float64{0.5, 1, 0.5, 1}},
+		{1, []float64{0, 1, 0, 0}},
+		{2, []float64{0, 1, 0, 1}},
+		{3, []float64{0.5, 1, 0, 1}},
+		{10, []float64{0.5, 1, 0.5, 1}},
+	}
+	for _, tt := range tests {
+		cfg, err := newGenerateConfig([]GenerateOption{WithTopK(tt.k)})
+		if err != nil {
+			t.Fatalf("k %d: %v", tt.k, err)
+		}
+		if got := cfg.weights(s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("k %d: weights = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateInvalidTopK(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	if err := c.Generate(io.Discard, 10, WithTopK(-1)); err == nil {
+		t.Error("Generate with top-k -1 succeeded, want error")
+	}
+}
+
+func TestGenerateConfigWeightsTopP(t *testing.T) {
+	s := newSuffixes()
+	for _, word := range []uint32{'a', 'b', 'b', 'b', 'c', 'c', 'd', 'd', 'd', 'd'} {
+		s.add(word)
+	}
+
+	tests := []struct {
+		opts []GenerateOption
+		want []float64
+	}{
+		{[]GenerateOption{WithTopP(1)}, []float64{0.25, 0.75, 0.5, 1}},
+		{[]GenerateOption{WithTopP(0.4)}, []float64{0, 0, 0, 1}},
+		{[]GenerateOption{WithTopP(0.5)}, []float64{0, 0.75, 0, 1}},
+		{[]GenerateOption{WithTopP(0.7)}, []float64{0, 0.75, 0, 1}},
+		{[]GenerateOption{WithTopP(0.71)}, []float64{0, 0.75, 0.5, 1}},
+		{[]GenerateOption{WithTopP(0.95)}, []float64{0.25, 0.75, 0.5, 1}},
+		{[]GenerateOption{WithTopK(2), WithTopP(0.9)}, []float64{0, 0.75, 0, 1}},
+	}
+	for _, tt := range tests {
+		cfg, err := newGenerateConfig(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.weights(s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("top-k %d, top-p %v: weights = %v, want %v", cfg.topK, cfg.topP, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateInvalidTopP(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	for _, p := range []float64{0, -0.5, 1.5, math.NaN()} {
+		if err := c.Generate(io.Discard, 10, WithTopP(p)); err == nil {
+			t.Errorf("Generate with top-p %v succeeded, want error", p)
+		}
+	}
+}
+
+func TestGenerateGreedy(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a c a b a b c d c a")
+
+	for seed := int64(1); seed <= 3; seed++ {
+		var b strings.Builder
+		err := c.Generate(&b, 7, WithGreedy(), WithRand(rand.New(rand.NewSource(seed))))
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+
+		// "a" is followed by "b" most often, and "b" is followed by "a" and
+		// "c" equally often but by "a" first.
+		if got, want := b.String(), "a b a b a b a"; got != want {
+			t.Errorf("seed %d: Generate = %q, want %q", seed, got, want)
+		}
+	}
+}
+
+func TestGenerateWithPrompt(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "once upon a time there was a fox once upon a hill")
+
+	tests := []struct {
+		prompt string
+		n      int
+		want   string
+	}{
+		{"", 4, "once upon a time"},
+		{"there was", 3, "a fox once"},
+		{"  and then there   was\n", 2, "a fox"},
+		{"once", 2, "upon a"},
+		{"a fox", 4, "once upon a time"},
+		{"no such words", 10, ""},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		err := c.Generate(&b, tt.n, WithPrompt(tt.prompt), WithTopK(1))
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("prompt %q: Generate = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateWithStop(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "so it began and so it ended THE END")
+
+	tests := []struct {
+		stops []string
+		want  string
+	}{
+		{nil, "so it began and so it began and so it"},
+		{[]string{"and"}, "so it began and"},
+		{[]string{"it began"}, "so it began"},
+		{[]string{"THE  END", "it"}, "so it"},
+		{[]string{"", " "}, "so it began and so it began and so it"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := c.Generate(&b, 10, WithGreedy(), WithStop(tt.stops...)); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("stops %q: Generate = %q, want %q", tt.stops, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateWithWordFunc(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps over the lazy dog")
+
+	var prefixes []string
+	fnErr := errors.New("no dogs")
+	tests := []struct {
+		fn      WordFunc[string]
+		want    string
+		wantErr error
+	}{
+		{func(word string, prefix Prefix[string]) error {
+			prefixes = append(prefixes, prefix.String())
+			return nil
+		}, "the quick brown", nil},
+		{func(word string, _ Prefix[string]) error {
+			if word == "quick" {
+				return SkipWord
+			}
+			return nil
+		}, "the brown", nil},
+		{func(word string, _ Prefix[string]) error {
+			if word == "brown" {
+				return StopGeneration
+			}
+			return nil
+		}, "the quick", nil},
+		{func(word string, _ Prefix[string]) error {
+			if word == "brown" {
+				return fnErr
+			}
+			return nil
+		}, "the quick", fnErr},
+	}
+	for i, tt := range tests {
+		var b strings.Builder
+		err := c.Generate(&b, 3, WithWordFunc(tt.fn))
+		if err != tt.wantErr {
+			t.Errorf("%d: Generate error = %v, want %v", i, err, tt.wantErr)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%d: Generate = %q, want %q", i, got, tt.want)
+		}
+	}
+
+	if want := []string{"", "the", "the quick"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("WordFunc prefixes = %q, want %q", prefixes, want)
+	}
+}
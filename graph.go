@@ -0,0 +1,270 @@
+package markov
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// An ExportOption configures how a Chain is exported as a graph.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	topN int
+}
+
+// WithTopN limits an exported graph to the n prefixes observed most often
+// and the transitions between them, so that even large Chains can be
+// visualized. Zero, the default, exports every prefix.
+func WithTopN(n int) ExportOption {
+	return func(cfg *exportConfig) {
+		cfg.topN = n
+	}
+}
+
+// A graph is the transition graph of a Chain: its nodes are prefixes, and
+// each edge leads from a prefix to the prefix that follows it once a suffix
+// is appended.
+type graph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+type graphNode struct {
+	label string
+	count int  // the number of times the prefix, or End, was observed
+	end   bool // whether the node stands for End rather than a prefix
+}
+
+type graphEdge struct {
+	from, to int // indexes of nodes
+	word     string
+	count    int
+	p        float64 // the probability of the edge among its node's edges
+}
+
+// graph returns the transition graph of the Chain, configured by opts. The
+// start of a text is labeled Begin, and a recorded end End.
+func (c *Chain[T]) graph(opts []ExportOption) (*graph, error) {
+	var cfg exportConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m, err := c.model()
+	if err != nil {
+		return nil, fmt.Errorf("markov: exporting chain: %w", err)
+	}
+	name := func(id uint32) string {
+		switch id {
+		case beginID:
+			return Begin
+		case endID:
+			return End
+		}
+		return fmt.Sprint(m.Words[id])
+	}
+	label := func(words Prefix[uint32]) string {
+		names := make(Prefix[string], 0, len(words))
+		for _, id := range arpaHistory(words) {
+			names = append(names, name(id))
+		}
+		return names.String()
+	}
+	total := func(p savedPrefix) int {
+		n := 0
+		for _, count := range p.Counts {
+			n += count
+		}
+		return n
+	}
+
+	prefixes := c.fullPrefixes(m.Prefixes)
+	limited := cfg.topN > 0 && cfg.topN < len(prefixes)
+	if limited {
+		prefixes = slices.Clone(prefixes)
+		slices.SortStableFunc(prefixes, func(a, b savedPrefix) int {
+			return cmp.Compare(total(b), total(a))
+		})
+		prefixes = prefixes[:cfg.topN]
+		slices.SortFunc(prefixes, func(a, b savedPrefix) int {
+			return cmp.Compare(key(a.Words), key(b.Words))
+		})
+	}
+
+	g := new(graph)
+	index := make(map[string]int, len(prefixes))
+	for _, p := range prefixes {
+		index[key(p.Words)] = len(g.nodes)
+		g.nodes = append(g.nodes, graphNode{label: label(p.Words), count: total(p)})
+	}
+	end := -1
+	for i, p := range prefixes {
+		n := g.nodes[i].count
+		for j, id := range p.Next {
+			var to int
+			if id == endID {
+				if end < 0 {
+					end = len(g.nodes)
+					g.nodes = append(g.nodes, graphNode{label: End, end: true})
+				}
+				to = end
+				g.nodes[to].count += p.Counts[j]
+			} else {
+				next := Prefix[uint32](slices.Clone(p.Words))
+				next.Shift(id)
+				var ok bool
+				if to, ok = index[key(next)]; !ok {
+					if limited {
+						continue
+					}
+					// The prefix ends a text and has no suffixes.
+					to = len(g.nodes)
+					index[key(next)] = to
+					g.nodes = append(g.nodes, graphNode{label: label(next)})
+				}
+				if to >= len(prefixes) {
+					g.nodes[to].count += p.Counts[j]
+				}
+			}
+			g.edges = append(g.edges, graphEdge{
+				from:  i,
+				to:    to,
+				word:  name(id),
+				count: p.Counts[j],
+				p:     float64(p.Counts[j]) / float64(n),
+			})
+		}
+	}
+	return g, nil
+}
+
+// ExportDOT writes the transition graph of the Chain to w in the DOT
+// language of Graphviz, configured by opts. Each node is a prefix, labeled
+// with its words, and each edge the suffix that leads from one prefix to the
+// next, labeled with the suffix and its probability and weighted by its
+// count. The start of a text is labeled Begin, and a recorded end is a node
+// labeled End.
+func (c *Chain[T]) ExportDOT(w io.Writer, opts ...ExportOption) error {
+	g, err := c.graph(opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph markov {")
+	for i, n := range g.nodes {
+		shape := ""
+		if n.end {
+			shape = ", shape=doublecircle"
+		}
+		fmt.Fprintf(bw, "\tn%d [label=%s%s];\n", i, strconv.Quote(n.label), shape)
+	}
+	for _, e := range g.edges {
+		label := fmt.Sprintf("%s %.2f", e.word, e.p)
+		fmt.Fprintf(bw, "\tn%d -> n%d [label=%s, weight=%d];\n", e.from, e.to, strconv.Quote(label), e.count)
+	}
+	fmt.Fprintln(bw, "}")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("markov: exporting chain: %w", err)
+	}
+	return nil
+}
+
+// graphML is the GraphML document of a graph.
+type graphML struct {
+	XMLName xml.Name       `xml:"graphml"`
+	XMLNS   string         `xml:"xmlns,attr"`
+	Keys    []graphMLKey   `xml:"key"`
+	Graph   graphMLElement `xml:"graph"`
+}
+
+// graphMLKey declares an attribute of the nodes or edges of a graphML.
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+// graphMLElement is the graph, a node or an edge of a graphML.
+type graphMLElement struct {
+	ID          string           `xml:"id,attr"`
+	EdgeDefault string           `xml:"edgedefault,attr,omitempty"`
+	Source      string           `xml:"source,attr,omitempty"`
+	Target      string           `xml:"target,attr,omitempty"`
+	Data        []graphMLData    `xml:"data"`
+	Nodes       []graphMLElement `xml:"node"`
+	Edges       []graphMLElement `xml:"edge"`
+}
+
+// graphMLData is the value of an attribute of a graphMLElement.
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ExportGraphML writes the transition graph of the Chain to w as a GraphML
+// document, configured by opts, for analysis in tools such as Gephi and yEd.
+// The graph is the one ExportDOT writes. Each node has a "label" attribute
+// holding its words and a "frequency" attribute holding the number of times
+// it was observed; each edge has a "word" attribute holding its suffix, a
+// "count" attribute holding the number of times the suffix was observed
+// following the prefix, and a "probability" attribute.
+func (c *Chain[T]) ExportGraphML(w io.Writer, opts ...ExportOption) error {
+	g, err := c.graph(opts)
+	if err != nil {
+		return err
+	}
+
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "frequency", For: "node", Name: "frequency", Type: "int"},
+			{ID: "word", For: "edge", Name: "word", Type: "string"},
+			{ID: "count", For: "edge", Name: "count", Type: "int"},
+			{ID: "probability", For: "edge", Name: "probability", Type: "double"},
+		},
+		Graph: graphMLElement{ID: "markov", EdgeDefault: "directed"},
+	}
+	for i, n := range g.nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLElement{
+			ID: fmt.Sprintf("n%d", i),
+			Data: []graphMLData{
+				{Key: "label", Value: n.label},
+				{Key: "frequency", Value: strconv.Itoa(n.count)},
+			},
+		})
+	}
+	for i, e := range g.edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLElement{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: fmt.Sprintf("n%d", e.from),
+			Target: fmt.Sprintf("n%d", e.to),
+			Data: []graphMLData{
+				{Key: "word", Value: e.word},
+				{Key: "count", Value: strconv.Itoa(e.count)},
+				{Key: "probability", Value: strconv.FormatFloat(e.p, 'g', -1, 64)},
+			},
+		})
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err == nil {
+		bw.WriteString("\n")
+		err = bw.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("markov: exporting chain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportDOT(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a b. a c.")
+
+	tests := []struct {
+		opts []ExportOption
+		want string
+	}{
+		{nil, `digraph markov {
+	n0 [label="<s>"];
+	n1 [label="a"];
+	n2 [label="b."];
+	n3 [label="c."];
+	n4 [label="</s>", shape=doublecircle];
+	n0 -> n1 [label="a 1.00", weight=2];
+	n1 -> n2 [label="b. 0.50", weight=1];
+	n1 -> n3 [label="c. 0.50", weight=1];
+	n2 -> n4 [label="</s> 1.00", weight=1];
+	n3 -> n4 [label="</s> 1.00", weight=1];
+}
+`},
+		{[]ExportOption{WithTopN(2)}, `digraph markov {
+	n0 [label="<s>"];
+	n1 [label="a"];
+	n0 -> n1 [label="a 1.00", weight=2];
+}
+`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := c.ExportDOT(&b, tt.opts...); err != nil {
+			t.Fatalf("ExportDOT error: %v", err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("ExportDOT wrote\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
+
+func TestExportDOTDeadEnd(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, `say "hi"`)
+
+	var b strings.Builder
+	if err := c.ExportDOT(&b); err != nil {
+		t.Fatalf("ExportDOT error: %v", err)
+	}
+	if want := `n2 [label="\"hi\""];`; !strings.Contains(b.String(), want) {
+		t.Errorf("ExportDOT wrote\n%s\nwant it to contain %s", b.String(), want)
+	}
+}
+
+func TestExportGraphML(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a <b>. a c.")
+
+	var b strings.Builder
+	if err := c.ExportGraphML(&b, WithTopN(2)); err != nil {
+		t.Fatalf("ExportGraphML error: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"></key>
+  <key id="frequency" for="node" attr.name="frequency" attr.type="int"></key>
+  <key id="word" for="edge" attr.name="word" attr.type="string"></key>
+  <key id="count" for="edge" attr.name="count" attr.type="int"></key>
+  <key id="probability" for="edge" attr.name="probability" attr.type="double"></key>
+  <graph id="markov" edgedefault="directed">
+    <node id="n0">
+      <data key="label">&lt;s&gt;</data>
+      <data key="frequency">2</data>
+    </node>
+    <node id="n1">
+      <data key="label">a</data>
+      <data key="frequency">2</data>
+    </node>
+    <edge id="e0" source="n0" target="n1">
+      <data key="word">a</data>
+      <data key="count">2</data>
+      <data key="probability">1</data>
+    </edge>
+  </graph>
+</graphml>
+`
+	if got := b.String(); got != want {
+		t.Errorf("ExportGraphML wrote\n%s\nwant\n%s", got, want)
+	}
+
+	b.Reset()
+	if err := c.ExportGraphML(&b); err != nil {
+		t.Fatalf("ExportGraphML error: %v", err)
+	}
+	for _, want := range []string{
+		`<data key="label">&lt;b&gt;.</data>`,
+		`<data key="probability">0.5</data>`,
+		`<data key="label">&lt;/s&gt;</data>` + "\n      " + `<data key="frequency">2</data>`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("ExportGraphML wrote\n%s\nwant it to contain %s", b.String(), want)
+		}
+	}
+}
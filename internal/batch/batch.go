@@ -0,0 +1,87 @@
+// Package batch buffers the observations and new words of a Chain kept in a
+// database, for the stores of package markov and its subpackages.
+package batch
+
+import "sync"
+
+// Size is the number of distinct observations a Batch holds before it is
+// written.
+const Size = 10000
+
+// A Batch buffers the observations and new words of a store kept in a
+// database, so that they are written together in a single transaction.
+// Reads flush it, and may do so while the Chain's lock is held only for
+// reading, so it has its own.
+type Batch struct {
+	mu           sync.Mutex
+	Observations []Observation // in the order first observed
+	Counts       []int         // the number of times each was observed
+	Words        []Word
+	index        map[Observation]int
+}
+
+// An Observation is a word ID observed following a prefix, by key.
+type Observation struct {
+	Prefix string
+	ID     uint32
+}
+
+// A Word is a word, as encoded by the Chain, and its ID.
+type Word struct {
+	ID   uint32
+	Word []byte
+}
+
+// Add records n more observations of id following the prefix with key k,
+// writing the batch with write once it is full.
+func (b *Batch) Add(k string, id uint32, n int, write func(*Batch) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.index == nil {
+		b.index = make(map[Observation]int)
+	}
+	o := Observation{k, id}
+	i, ok := b.index[o]
+	if !ok {
+		i = len(b.Observations)
+		b.index[o] = i
+		b.Observations = append(b.Observations, o)
+		b.Counts = append(b.Counts, 0)
+	}
+	b.Counts[i] += n
+	if len(b.Observations) >= Size {
+		return b.write(write)
+	}
+	return nil
+}
+
+// AddWord records the word assigned the given ID.
+func (b *Batch) AddWord(id uint32, word []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Words = append(b.Words, Word{id, word})
+}
+
+// Flush writes the batch with write, unless it is empty.
+func (b *Batch) Flush(write func(*Batch) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.write(write)
+}
+
+// write writes the batch with write, unless it is empty, and empties it if
+// that succeeds. b.mu must be held.
+func (b *Batch) write(write func(*Batch) error) error {
+	if len(b.Observations) == 0 && len(b.Words) == 0 {
+		return nil
+	}
+	if err := write(b); err != nil {
+		return err
+	}
+	b.Observations, b.Counts, b.Words = b.Observations[:0], b.Counts[:0], b.Words[:0]
+	clear(b.index)
+	return nil
+}
@@ -0,0 +1,149 @@
+package markov
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonChain is the JSON form of a Chain.
+type jsonChain[T comparable] struct {
+	PrefixLength int             `json:"prefixLength"`
+	Options      savedOptions    `json:"options"`
+	Prefixes     []jsonPrefix[T] `json:"prefixes"`
+}
+
+// jsonPrefix is the JSON form of a prefix and its suffixes.
+type jsonPrefix[T comparable] struct {
+	Prefix   []T             `json:"prefix"`
+	Suffixes []jsonSuffix[T] `json:"suffixes"`
+}
+
+// jsonSuffix is the JSON form of a suffix and its count.
+type jsonSuffix[T comparable] struct {
+	Word  *T   `json:"word,omitempty"`
+	End   bool `json:"end,omitempty"`
+	Count int  `json:"count"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the Chain as an object of
+// this form:
+//
+//	{
+//	  "prefixLength": 2,
+//	  "options": {"mode": "word", "markers": true},
+//	  "prefixes": [
+//	    {"prefix": [], "suffixes": [{"word": "The", "count": 2}]},
+//	    {"prefix": ["The"], "suffixes": [{"word": "end.", "count": 2}]},
+//	    {"prefix": ["The", "end."], "suffixes": [{"end": true, "count": 2}]}
+//	  ]
+//	}
+//
+// Each prefix lists its words, leaving out those before the start of the
+// text, so a prefix shorter than prefixLength stands for the words at the
+// start of a text, just as it does for Count. Each suffix is a word and the
+// number of times it was observed following the prefix, in the order the
+// suffixes were first observed, or the end of a text or sentence recorded by
+// a Chain created WithMarkers. The options are those Save saves, with
+// false, empty and, except for mode, zero options left out:
+// "punctuation", "segmented", "markers", "sentenceReset", "lowercase",
+// "backoff" and "reversed" are booleans, "normalization" is "NFC", "NFD", "NFKC" or "NFKD",
+// "stopWords" is a list of words, "collapseStopWords" and "placeholder"
+// configure WithCollapsedStopWords, and "skipGram" lists the positions
+// given WithSkipGram.
+func (c *Chain[T]) MarshalJSON() ([]byte, error) {
+	m, err := c.model()
+	if err != nil {
+		return nil, err
+	}
+	j := jsonChain[T]{
+		PrefixLength: m.PrefixLen,
+		Options:      m.Options,
+		Prefixes:     make([]jsonPrefix[T], len(m.Prefixes)),
+	}
+	for i, p := range m.Prefixes {
+		start := 0
+		for start < len(p.Words) && p.Words[start] == beginID {
+			start++
+		}
+		prefix := make([]T, 0, len(p.Words)-start)
+		for _, id := range p.Words[start:] {
+			prefix = append(prefix, m.Words[id])
+		}
+
+		suffixes := make([]jsonSuffix[T], len(p.Next))
+		for j, id := range p.Next {
+			suffixes[j].Count = p.Counts[j]
+			if id == endID {
+				suffixes[j].End = true
+			} else {
+				suffixes[j].Word = &m.Words[id]
+			}
+		}
+		j.Prefixes[i] = jsonPrefix[T]{Prefix: prefix, Suffixes: suffixes}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the content of the
+// Chain with the Chain encoded by MarshalJSON, along with its options.
+// Options holding functions, which are not encoded, are kept.
+func (c *Chain[T]) UnmarshalJSON(data []byte) error {
+	var j jsonChain[T]
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.PrefixLength < 1 {
+		return fmt.Errorf("markov: invalid prefix length %d", j.PrefixLength)
+	}
+
+	// The prefixes of a Chain skipping words or backing off hold End in
+	// place of the words they leave out, and may hold Begin after them.
+	fills := len(j.Options.SkipGram) > 0 || j.Options.Backoff
+	v := newVocab[T]()
+	m := model[T]{
+		PrefixLen: j.PrefixLength,
+		Options:   j.Options,
+		Prefixes:  make([]savedPrefix, len(j.Prefixes)),
+	}
+	for i, p := range j.Prefixes {
+		if len(p.Prefix) > j.PrefixLength {
+			return fmt.Errorf("markov: prefix %v longer than %d words", p.Prefix, j.PrefixLength)
+		}
+		words := make([]uint32, j.PrefixLength-len(p.Prefix), j.PrefixLength)
+		for _, word := range p.Prefix {
+			switch {
+			case fills && any(word) == any(Begin):
+				words = append(words, beginID)
+			case fills && any(word) == any(End):
+				words = append(words, endID)
+			default:
+				words = append(words, v.intern(word))
+			}
+		}
+
+		next := make([]uint32, len(p.Suffixes))
+		counts := make([]int, len(p.Suffixes))
+		for k, s := range p.Suffixes {
+			switch {
+			case s.End && s.Word == nil:
+				next[k] = endID
+			case !s.End && s.Word != nil:
+				next[k] = v.intern(*s.Word)
+			default:
+				return fmt.Errorf("markov: suffix of prefix %v must have either a word or end", p.Prefix)
+			}
+			counts[k] = s.Count
+		}
+		m.Prefixes[i] = savedPrefix{Words: words, Next: next, Counts: counts}
+	}
+	m.Words = v.words
+
+	loaded, err := m.chain(nil)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replace(loaded)
+	return nil
+}
@@ -0,0 +1,92 @@
+package markov
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainMarshalJSON(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "The end. The end.")
+
+	got, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	want := `{"prefixLength":2,"options":{"mode":"word","markers":true},"prefixes":[` +
+		`{"prefix":[],"suffixes":[{"word":"The","count":2}]},` +
+		`{"prefix":["The"],"suffixes":[{"word":"end.","count":2}]},` +
+		`{"prefix":["The","end."],"suffixes":[{"end":true,"count":2}]}]}`
+	if string(got) != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+}
+
+func TestChainUnmarshalJSON(t *testing.T) {
+	c := NewChain(2, WithLowercase(), WithStopWords("a"))
+	mustBuild(t, c, "the quick brown fox. a quick brown dog! the dog")
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var loaded Chain[string]
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if got, want := chainCounts(&loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("unmarshaled chain = %v, want %v", got, want)
+	}
+	if got, want := loaded.options.saved(), c.options.saved(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unmarshaled options = %+v, want %+v", got, want)
+	}
+
+	ints := New[int](1)
+	ints.Add(1, 2, 3)
+	data, err = json.Marshal(ints)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var loadedInts Chain[int]
+	if err := json.Unmarshal(data, &loadedInts); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if got := loadedInts.Count(Prefix[int]{2}, 3); got != 1 {
+		t.Errorf("Count({2}, 3) = %d, want 1", got)
+	}
+}
+
+func TestChainUnmarshalJSONKeepsFunctions(t *testing.T) {
+	c := NewChain(1, WithTokenTransform(strings.ToUpper))
+	err := json.Unmarshal([]byte(`{"prefixLength":1,"options":{"mode":"word"},"prefixes":[]}`), c)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	mustBuild(t, c, "a b")
+	if got := c.Count(Prefix[string]{"A"}, "B"); got != 1 {
+		t.Errorf("Count(A, B) = %d, want 1", got)
+	}
+}
+
+func TestChainUnmarshalJSONInvalid(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{"prefixLength":0}`,
+		`{"prefixLength":-1}`,
+		`{"prefixLength":1,"options":{"mode":"sentence"}}`,
+		`{"prefixLength":1,"options":{"normalization":"NFX"}}`,
+		`{"prefixLength":1,"prefixes":[{"prefix":["a","b"],"suffixes":[{"word":"c","count":1}]}]}`,
+		`{"prefixLength":1,"prefixes":[{"prefix":["a"],"suffixes":[{"count":1}]}]}`,
+		`{"prefixLength":1,"prefixes":[{"prefix":["a"],"suffixes":[{"word":"b","end":true,"count":1}]}]}`,
+		`{"prefixLength":1,"prefixes":[{"prefix":["a"],"suffixes":[{"word":"b","count":0}]}]}`,
+		`{"prefixLength":1,"prefixes":[{"prefix":["a"],"suffixes":[]}]}`,
+	}
+	for _, data := range tests {
+		var c Chain[string]
+		if err := json.Unmarshal([]byte(data), &c); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package markov
+
+import "errors"
+
+// katzMax is the largest count Katz backoff discounts. Counts above it are
+// taken to be reliable as they are.
+const katzMax = 5
+
+// KatzBackoff returns a Smoother estimating probabilities by Katz backoff
+// over the shorter contexts counted by a Chain created WithBackoff. The
+// count r of each word observed following a context is discounted by the
+// Good-Turing estimate of how often words observed r times recur, and the
+// probability taken from the words observed is shared among those that were
+// not in proportion to their probabilities following the context one word
+// shorter, and so on down to the frequencies of the words alone. Only words
+// never observed at all are left to WithUnseenProbability.
+//
+// Counts above 5 are not discounted. Those whose Good-Turing estimate would
+// not discount them, as in corpora too small to estimate it, are instead
+// reduced by n1/(n1+2*n2), where n1 and n2 are the numbers of words observed
+// once and twice following contexts as long, or by a half if none was
+// observed twice. Scoring with KatzBackoff first
+// reads every prefix of the Chain to count its counts.
+func KatzBackoff() Smoother {
+	return katzBackoff{}
+}
+
+// katzBackoff is the Smoother of KatzBackoff.
+type katzBackoff struct{}
+
+func (katzBackoff) estimator(src source) (estimator, error) {
+	if !src.backoff {
+		return nil, errors.New("markov: Katz backoff needs a chain created WithBackoff")
+	}
+
+	// counts[n][r] is the number of words observed r times following a
+	// context of n words, for r up to katzMax+1.
+	counts := make([][katzMax + 2]int, src.prefixLen+1)
+	err := src.each(func(k string, s *suffixes) bool {
+		n := contextLen(unkey(k))
+		for _, r := range s.counts {
+			if r <= katzMax+1 {
+				counts[n][r]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	k := &katz{
+		src:       src,
+		discounts: make([][katzMax + 1]float64, src.prefixLen+1),
+		alphas:    make(map[string]float64),
+	}
+	for n, nr := range counts {
+		// Good-Turing renormalized so that counts above katzMax keep their
+		// probability mass, or else an absolute discount.
+		a := float64((katzMax+1)*nr[katzMax+1]) / float64(max(nr[1], 1))
+		abs := 0.5
+		if nr[2] > 0 {
+			abs = float64(nr[1]) / float64(nr[1]+2*nr[2])
+		}
+		for r := 1; r <= katzMax; r++ {
+			k.discounts[n][r] = 1
+			if nr[r] > 0 && nr[1] > 0 && a < 1 {
+				star := float64((r+1)*nr[r+1]) / float64(nr[r])
+				if d := (star/float64(r) - a) / (1 - a); d > 0 && d < 1 {
+					k.discounts[n][r] = d
+					continue
+				}
+			}
+			if d := 1 - abs/float64(r); d > 0 && d < 1 {
+				k.discounts[n][r] = d
+			}
+		}
+	}
+	return k, nil
+}
+
+// katz is the estimator of KatzBackoff.
+type katz struct {
+	src source
+
+	// discounts holds the discount of each count up to katzMax of the words
+	// following contexts of each number of words.
+	discounts [][katzMax + 1]float64
+
+	// alphas holds the backoff weight of each context by key, once known.
+	alphas map[string]float64
+}
+
+// contextLen returns the number of words of the context of the key window,
+// that is the number of them not filled with endID.
+func contextLen(window Prefix[uint32]) int {
+	n := len(window)
+	for _, id := range window {
+		if id != endID {
+			break
+		}
+		n--
+	}
+	return n
+}
+
+// discount returns the discounted count r of a word following a context of
+// n words.
+func (k *katz) discount(n, r int) float64 {
+	if r > katzMax {
+		return float64(r)
+	}
+	return k.discounts[n][r] * float64(r)
+}
+
+func (k *katz) prob(window Prefix[uint32], id uint32) (float64, error) {
+	return k.probAt(window, len(window), id)
+}
+
+// probAt returns the probability of the word with the given ID, or noID,
+// following the context of the last n words of window.
+func (k *katz) probAt(window Prefix[uint32], n int, id uint32) (float64, error) {
+	if n > 0 && k.src.skips(n) {
+		return k.probAt(window, n-1, id)
+	}
+	context := shortened(make(Prefix[uint32], len(window)), window, n)
+	s, err := k.src.get(context)
+	if err != nil {
+		return 0, err
+	}
+	if s == nil {
+		if n == 0 {
+			return 0, nil
+		}
+		return k.probAt(window, n-1, id)
+	}
+	if r := s.count(id); id != noID && r > 0 {
+		if n == 0 {
+			return float64(r) / float64(s.total), nil
+		}
+		return k.discount(n, r) / float64(s.total), nil
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	alpha, err := k.alpha(context, n, s)
+	if err != nil || alpha == 0 {
+		return 0, err
+	}
+	p, err := k.probAt(window, n-1, id)
+	return alpha * p, err
+}
+
+// alpha returns the backoff weight of context, a context of n words with
+// the suffixes s: the probability the discounts take from the words
+// observed following it, divided by the probability of the words not
+// observed following the context one word shorter.
+func (k *katz) alpha(context Prefix[uint32], n int, s *suffixes) (float64, error) {
+	ck := key(context)
+	if alpha, ok := k.alphas[ck]; ok {
+		return alpha, nil
+	}
+	left, lower := 1.0, 1.0
+	for i, id := range s.words {
+		left -= k.discount(n, s.counts[i]) / float64(s.total)
+		p, err := k.probAt(context, n-1, id)
+		if err != nil {
+			return 0, err
+		}
+		lower -= p
+	}
+	alpha := 0.0
+	if left > 1e-12 && lower > 1e-12 {
+		alpha = left / lower
+	}
+	k.alphas[ck] = alpha
+	return alpha, nil
+}
@@ -0,0 +1,63 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChainKatzBackoff(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithBackoff())
+	mustBuild(t, c, strings.Repeat("the cat sat on the mat. the dog sat on the log. a cat ate. ", 3)+"the dog ate the cat. a bird sang on the log.")
+	k, err := KatzBackoff().estimator(c.source())
+	if err != nil {
+		t.Fatalf("estimator error: %v", err)
+	}
+
+	// The probabilities of every word following a context, seen or not, sum
+	// to 1.
+	ids := func(words ...string) Prefix[uint32] {
+		window := make(Prefix[uint32], len(words))
+		for i, w := range words {
+			window[i] = c.vocab.id(w)
+		}
+		return window
+	}
+	for _, window := range []Prefix[uint32]{ids("the", "cat"), ids("sat", "on"), ids("bird", "sat"), ids("bird", "the"), ids("z", "dog"), {beginID, beginID}} {
+		sum := 0.0
+		for id := endID; int(id) < len(c.vocab.words); id++ {
+			p, err := k.prob(window, id)
+			if err != nil {
+				t.Fatalf("prob error: %v", err)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("probabilities following %v sum to %v, want 1", c.words(window), sum)
+		}
+	}
+
+	// A text of seen words in an order never seen is possible, but less
+	// likely than one seen.
+	unseen := strings.Fields("the bird ate on the mat.")
+	if got := c.Score(unseen); !math.IsInf(got, -1) {
+		t.Errorf("Score(%q) = %v, want -Inf without Katz backoff", unseen, got)
+	}
+	katz := c.Score(unseen, WithSmoother(KatzBackoff()))
+	if math.IsInf(katz, -1) || math.IsNaN(katz) {
+		t.Errorf("Score(%q) with KatzBackoff = %v, want finite", unseen, katz)
+	}
+	if seen := c.Score(strings.Fields("the cat sat on the mat."), WithSmoother(KatzBackoff())); !(seen > katz) {
+		t.Errorf("Score of a seen text with Katz backoff = %v, want above %v", seen, katz)
+	}
+	if got := c.Score([]string{"zebra"}, WithSmoother(KatzBackoff())); !math.IsInf(got, -1) {
+		t.Errorf("Score of a word never observed with Katz backoff = %v, want -Inf", got)
+	}
+	if got := c.Score([]string{"zebra"}, WithSmoother(KatzBackoff()), WithUnseenProbability(0.01)); math.IsInf(got, -1) {
+		t.Error("Score of a word never observed with Katz backoff and an unseen probability = -Inf, want finite")
+	}
+
+	if _, err := NewChain(2).Perplexity(strings.NewReader("a b"), WithSmoother(KatzBackoff())); err == nil {
+		t.Error("Perplexity with KatzBackoff of a chain without backoff succeeded, want error")
+	}
+}
@@ -0,0 +1,158 @@
+package markov
+
+// KneserNey returns a Smoother estimating probabilities by interpolated,
+// modified Kneser-Ney smoothing, which models small corpora markedly better
+// than their counts alone. Three discounts, for words observed once, twice
+// and more often following a prefix, are estimated from how many words were
+// observed so, and are taken from each word observed following the prefix.
+// The probability they take is shared among all words in proportion to
+// their probabilities following the prefix one word shorter, and so on down
+// to every word alike, where the probability of a word following a shorter
+// prefix is not its count but the number of different words it was observed
+// following, so that a word common only after one other, such as
+// "Francisco" after "San", is not taken to be likely after any other.
+//
+// The Chain need not be created WithBackoff; the shorter prefixes are
+// derived from the full ones. Only words never observed at all are left to
+// WithUnseenProbability. Scoring with KneserNey first reads every prefix of
+// the Chain to count the words observed following the shorter prefixes.
+func KneserNey() Smoother {
+	return kneserNeySmoother{}
+}
+
+// kneserNeySmoother is the Smoother of KneserNey.
+type kneserNeySmoother struct{}
+
+func (kneserNeySmoother) estimator(src source) (estimator, error) {
+	// Every word observed following a context of i words and the word
+	// before it is a new word observed following the context alone.
+	seen := make(map[string]bool)
+	lower, err := shorterCounts(src, func(s *suffixes, gram Prefix[uint32], id uint32, _ int) bool {
+		gk := key(gram)
+		if seen[gk] {
+			return false
+		}
+		seen[gk] = true
+		s.addCount(id, 1)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// counts[i][r] is the number of words observed r times following a
+	// context of i words, for r up to 4.
+	n := src.prefixLen
+	counts := make([][5]int, n+1)
+	countOf := func(i int, s *suffixes) {
+		for _, r := range s.counts {
+			if r <= 4 {
+				counts[i][r]++
+			}
+		}
+	}
+	err = src.each(func(k string, s *suffixes) bool {
+		if contextLen(unkey(k)) == n {
+			countOf(n, s)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range lower {
+		for _, s := range l {
+			countOf(i, s)
+		}
+	}
+
+	kn := &kneserNey{
+		src:       src,
+		lower:     lower,
+		discounts: make([][3]float64, n+1),
+		gammas:    make([]map[string]float64, n+1),
+	}
+	for i, nr := range counts {
+		kn.discounts[i] = kneserNeyDiscounts(nr)
+		kn.gammas[i] = make(map[string]float64)
+	}
+	return kn, nil
+}
+
+// kneserNey is the estimator of KneserNey.
+type kneserNey struct {
+	src source
+
+	// lower holds the suffixes of each context shorter than the Chain's
+	// prefixes by its number of words and key, whose counts are the numbers
+	// of different words they were observed following.
+	lower []map[string]*suffixes
+
+	// discounts holds the discounts of words observed once, twice and more
+	// often following contexts of each number of words.
+	discounts [][3]float64
+
+	// gammas holds the weight of the shorter context of each context by
+	// number of words and key, once known.
+	gammas []map[string]float64
+}
+
+// kneserNeyDiscounts returns the discounts of words observed once, twice and
+// more often, given the number of words nr[r] observed r times for r up to
+// 4. A discount that cannot be estimated, or would exceed the count it is
+// taken from, is that of words observed once in the unmodified Kneser-Ney
+// smoothing.
+func kneserNeyDiscounts(nr [5]int) [3]float64 {
+	y := float64(nr[1]) / float64(max(nr[1]+2*nr[2], 1))
+	var d [3]float64
+	for r := 1; r <= 3; r++ {
+		d[r-1] = float64(r) - float64(r+1)*y*float64(nr[r+1])/float64(nr[r])
+		if nr[r] == 0 || nr[r+1] == 0 || d[r-1] < 0 || d[r-1] > float64(r) {
+			d[r-1] = y
+		}
+	}
+	return d
+}
+
+// discount returns the discount of a word observed r times following a
+// context of i words.
+func (kn *kneserNey) discount(i, r int) float64 {
+	if r == 0 {
+		return 0
+	}
+	return kn.discounts[i][min(r, 3)-1]
+}
+
+func (kn *kneserNey) prob(window Prefix[uint32], id uint32) (float64, error) {
+	if id == noID {
+		return 0, nil
+	}
+	p := 1 / float64(kn.src.words)
+	for i := 0; i <= len(window); i++ {
+		var s *suffixes
+		ck := key(window[len(window)-i:])
+		if i == len(window) {
+			var err error
+			if s, err = kn.src.get(window); err != nil {
+				return 0, err
+			}
+		} else {
+			s = kn.lower[i][ck]
+		}
+		if s == nil {
+			continue
+		}
+
+		gamma, ok := kn.gammas[i][ck]
+		if !ok {
+			for _, r := range s.counts {
+				gamma += kn.discount(i, r)
+			}
+			gamma /= float64(s.total)
+			kn.gammas[i][ck] = gamma
+		}
+		r := s.count(id)
+		p = (float64(r)-kn.discount(i, r))/float64(s.total) + gamma*p
+	}
+	return p, nil
+}
@@ -0,0 +1,62 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChainKneserNey(t *testing.T) {
+	text := "I flew to San Francisco. San Francisco is foggy. San Francisco is far. I like the fog. I like the sea. The sea is far. A sea is deep."
+	for _, opts := range [][]Option{{WithMarkers()}, {WithMarkers(), WithBackoff()}} {
+		c := NewChain(2, opts...)
+		mustBuild(t, c, text)
+		kn, err := KneserNey().estimator(c.source())
+		if err != nil {
+			t.Fatalf("estimator error: %v", err)
+		}
+
+		// The probabilities of every word following a context, seen or not,
+		// sum to 1.
+		ids := func(words ...string) Prefix[uint32] {
+			window := make(Prefix[uint32], len(words))
+			for i, w := range words {
+				window[i] = c.vocab.id(w)
+			}
+			return window
+		}
+		for _, window := range []Prefix[uint32]{ids("San", "Francisco"), ids("I", "like"), ids("the", "San"), ids("z", "is"), {beginID, beginID}} {
+			sum := 0.0
+			for id := endID; int(id) < len(c.vocab.words); id++ {
+				p, err := kn.prob(window, id)
+				if err != nil {
+					t.Fatalf("prob error: %v", err)
+				}
+				sum += p
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("probabilities following %v sum to %v, want 1", c.words(window), sum)
+			}
+		}
+
+		// Francisco is frequent but only ever follows San, so it is less
+		// likely than the rarer sea, which follows several, after a word never seen before it.
+		francisco, _ := kn.prob(ids("z", "zz"), c.vocab.id("Francisco"))
+		sea, _ := kn.prob(ids("z", "zz"), c.vocab.id("sea"))
+		if !(francisco < sea) {
+			t.Errorf("P(Francisco) = %v, want below P(sea) = %v", francisco, sea)
+		}
+
+		unseen := strings.Fields("I like San Francisco.")
+		if got := c.Score(unseen); !math.IsInf(got, -1) {
+			t.Errorf("Score(%q) = %v, want -Inf without smoothing", unseen, got)
+		}
+		smoothed := c.Score(unseen, WithSmoother(KneserNey()))
+		if math.IsInf(smoothed, -1) || math.IsNaN(smoothed) {
+			t.Errorf("Score(%q) with Kneser-Ney = %v, want finite", unseen, smoothed)
+		}
+		if seen := c.Score(strings.Fields("I like the sea."), WithSmoother(KneserNey())); !(seen > smoothed) {
+			t.Errorf("Score of a seen text with Kneser-Ney = %v, want above %v", seen, smoothed)
+		}
+	}
+}
@@ -1,112 +1,535 @@
 /*
+Package markov implements Markov chain text generation.
+
+A Chain may be built over words of any comparable type. NewChain returns a
+Chain of strings, which splits text into words and joins generated words back
+into text; New returns a Chain over any other type of word, such as integers
+or custom symbols.
+
 See: https://golang.org/doc/codewalk/markov/
 */
-
-package main
+package markov
 
 import (
 	"bufio"
-	"flag"
+	"context"
 	"fmt"
 	"io"
-	"log"
-	"math/rand"
-	"os"
+	"iter"
+	"maps"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 )
 
 // Prefix is a Markov chain prefix of one or more words.
-type Prefix []string
+type Prefix[T comparable] []T
 
-// String returns the Prefix as a string (for use as a map key).
-func (p Prefix) String() string {
-	return strings.Join(p, " ")
+// String returns the Prefix as a string of words joined with spaces.
+func (p Prefix[T]) String() string {
+	words := make([]string, len(p))
+	for i, word := range p {
+		words[i] = fmt.Sprint(word)
+	}
+	return strings.Join(words, " ")
 }
 
 // Shift removes the first word from the Prefix and appends the given word.
-func (p Prefix) Shift(word string) {
+func (p Prefix[T]) Shift(word T) {
 	copy(p, p[1:])
 	p[len(p)-1] = word
 }
 
-// Chain contains a map ("chain") of prefixes to a list of suffixes.
-// A prefix is a string of prefixLen words joined with spaces.
-// A suffix is a single word. A prefix can have multiple suffixes.
-type Chain struct {
-	chain     map[string][]string
+// Chain contains a map ("chain") of prefixes to their suffixes.
+// A prefix is a sequence of prefixLen words and a suffix is a single word.
+// A prefix can have multiple suffixes, each counted by the number of times
+// it was observed following the prefix.
+//
+// A Chain is safe for concurrent use by multiple goroutines, so it may be
+// built from and generated from at the same time.
+type Chain[T comparable] struct {
+	mu        sync.RWMutex
+	store     store
+	vocab     vocab[T]
 	prefixLen int
+	options
+
+	// ngrams holds the hashes of the runs of words indexed WithNgramIndex.
+	ngrams map[uint64]struct{}
+}
+
+// New returns a new Chain of words of type T with prefixes of prefixLen
+// words, configured by opts.
+func New[T comparable](prefixLen int, opts ...Option) *Chain[T] {
+	c := &Chain[T]{
+		store:     newMemoryStore(prefixLen),
+		vocab:     newVocab[T](),
+		prefixLen: prefixLen,
+	}
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+	return c
+}
+
+// NewChain returns a new Chain of strings with prefixes of prefixLen words,
+// configured by opts.
+func NewChain(prefixLength int, opts ...Option) *Chain[string] {
+	return New[string](prefixLength, opts...)
 }
 
-// NewChain returns a new Chain with prefixes of prefixLen words.
-func NewChain(prefixLength int) *Chain {
-	return &Chain{
-		chain:     make(map[string][]string),
-		prefixLen: prefixLength,
+// Count returns the number of times word was observed following prefix.
+// A prefix shorter than the Chain's prefixes stands for the words at the
+// start of a text.
+func (c *Chain[T]) Count(prefix Prefix[T], word T) int {
+	prefix = c.normalized(prefix)
+	word = c.normalized([]T{word})[0]
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s, err := c.get(c.window(c.ids(prefix)))
+	if err != nil || s == nil {
+		return 0
 	}
+	return s.count(c.vocab.id(word))
+}
+
+// Suffixes returns the number of times each word was observed following
+// prefix, which stands for the words at the start of a text if it is shorter
+// than the Chain's prefixes, or nil if it was never observed or the Chain's
+// store fails. In a Chain of strings, End counts the texts or sentences that
+// end after prefix.
+func (c *Chain[T]) Suffixes(prefix []T) map[T]int {
+	prefix = c.normalized(prefix)
+
+	c.mu.RLock()
+	s, err := c.get(c.window(c.ids(prefix)))
+	if err != nil || s == nil {
+		c.mu.RUnlock()
+		return nil
+	}
+	ids, counts := slices.Clone(s.words), slices.Clone(s.counts)
+	c.mu.RUnlock()
+
+	words, err := c.lookup(ids)
+	if err != nil {
+		return nil
+	}
+	_, hasEnd := any(End).(T)
+	m := make(map[T]int, len(words))
+	for i, word := range words {
+		if ids[i] != endID || hasEnd {
+			m[word] = counts[i]
+		}
+	}
+	return m
+}
+
+// Compile precomputes a sampling table for every prefix so that Generate
+// picks each suffix in constant time, however many suffixes a prefix has.
+// The sampled distribution is unchanged. Building the Chain further discards
+// the tables of the prefixes it touches, so Compile should be called again
+// once training is finished.
+func (c *Chain[T]) Compile() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var all iter.Seq[*suffixes]
+	switch s := c.store.(type) {
+	case arrayStore:
+		all = maps.Values(s.prefixes)
+	case mapStore:
+		all = maps.Values(s)
+	case *memoryStore:
+		all = maps.Values(s.prefixes)
+	default:
+		return
+	}
+	for s := range all {
+		if s.alias == nil {
+			s.compile()
+		}
+	}
+}
+
+// PrefixLen returns the number of words in each of the Chain's prefixes.
+func (c *Chain[T]) PrefixLen() int {
+	return c.prefixLen
+}
+
+// Mode returns the Mode the Chain splits its input by.
+func (c *Chain[T]) Mode() Mode {
+	return c.mode
 }
 
 // Build reads text from the provided Reader and
 // parses it into prefixes and suffixes that are stored in Chain.
-func (c *Chain) Build(r io.Reader) {
-	bufReader := bufio.NewReader(r)
-	prefix := make(Prefix, c.prefixLen)
+// It returns nil once the Reader is exhausted, or the first read error
+// encountered otherwise.
+//
+// The input is split into words by the Chain's Mode or Tokenizer. Words of
+// Chains of types other than string are scanned as by fmt.Fscan.
+func (c *Chain[T]) Build(r io.Reader) error {
+	return c.BuildContext(context.Background(), r)
+}
+
+// BuildContext is like Build but stops reading and returns ctx.Err() if ctx
+// is done before the Reader is exhausted. Words read up to that point remain
+// in the Chain.
+func (c *Chain[T]) BuildContext(ctx context.Context, r io.Reader) error {
+	return c.train(ctx, c.tokenize(r))
+}
+
+// Add records words as a single text, just as Build records the words of
+// its input.
+func (c *Chain[T]) Add(words ...T) {
+	words = c.normalized(words)
+	c.train(context.Background(), c.filter(func() (T, error) {
+		if len(words) == 0 {
+			var zero T
+			return zero, io.EOF
+		}
+		word := words[0]
+		words = words[1:]
+		return word, nil
+	}))
+}
+
+// train records the words returned by next, up to the io.EOF that ends
+// them, as a text. It returns any other error next or the Chain's store
+// returns, or ctx.Err() if ctx is done first.
+func (c *Chain[T]) train(ctx context.Context, next func() (T, error)) (err error) {
+	if c.reversed {
+		return errReversed
+	}
+	defer func() {
+		if flushErr := c.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	observe, add := c.backingOff(c.indexing(c.observe), c.add)
+	return c.scan(ctx, next, observe, add)
+}
+
+// scan passes each word returned by next, up to the io.EOF that ends them,
+// to observe with the IDs of the words of the text preceding it, which
+// returns the word's ID, and passes to add the endID ending each text or
+// sentence when the Chain has markers. It returns any other error next,
+// observe or add return, or ctx.Err() if ctx is done first.
+func (c *Chain[T]) scan(ctx context.Context, next func() (T, error), observe func(Prefix[uint32], T) (uint32, error), add func(Prefix[uint32], uint32) error) error {
+	window := make(Prefix[uint32], c.prefixLen)
+	atStart := true
 
 	for {
-		var word string
-		_, err := fmt.Fscan(bufReader, &word)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		word, err := next()
+		if err == io.EOF {
+			if c.markers && !atStart {
+				return add(window, endID)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if atStart && c.mode == Paragraphs && c.isBreak(word) {
+			// Blank lines before the first paragraph separate nothing.
+			continue
+		}
+
+		id, err := observe(window, word)
 		if err != nil {
-			break
+			return err
 		}
+		window.Shift(id)
+		atStart = false
 
-		key := prefix.String()
-		c.chain[key] = append(c.chain[key], word)
-		prefix.Shift(word)
+		if c.isBreak(word) {
+			window = make(Prefix[uint32], c.prefixLen)
+			atStart = true
+		} else if c.resetsSentences() && endsSentence(word) {
+			if c.markers {
+				if err := add(window, endID); err != nil {
+					return err
+				}
+			}
+			window = make(Prefix[uint32], c.prefixLen)
+			atStart = true
+		}
 	}
 }
 
-// Generate writes a string of at most n words, generated from Chain,
-// to the standard output.
-func (c *Chain) Generate(w io.Writer, n int) error {
+// Generate writes a string of at most n words, generated from Chain and
+// separated by spaces unless the Chain's Mode or WithPunctuation says
+// otherwise, to the provided Writer. No space follows the last word, as one
+// did before GenerateN was added.
+func (c *Chain[T]) Generate(w io.Writer, n int, opts ...GenerateOption) error {
+	return c.GenerateContext(context.Background(), w, n, opts...)
+}
+
+// GenerateContext is like Generate but stops generating and returns
+// ctx.Err() if ctx is done before n words have been written. Words generated
+// up to that point are still flushed to the Writer.
+func (c *Chain[T]) GenerateContext(ctx context.Context, w io.Writer, n int, opts ...GenerateOption) error {
+	cfg, err := newGenerateConfig(opts)
+	if err != nil {
+		return err
+	}
+
 	bufWriter := bufio.NewWriter(w)
 	defer bufWriter.Flush()
-	prefix := make(Prefix, c.prefixLen)
 
-	for i := 0; i < n; i++ {
-		key := prefix.String()
-		words := c.chain[key]
-		if len(words) == 0 {
-			break
+	var writeErr error
+	space := c.spacer(cfg.tidy)
+	err = c.walk(ctx, n, cfg, func(word T) bool {
+		if _, writeErr = bufWriter.WriteString(space(word)); writeErr != nil {
+			return false
 		}
+		writeErr = c.writeToken(bufWriter, word)
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
 
-		nextWord := words[rand.Intn(len(words))]
-		_, err := bufWriter.WriteString(fmt.Sprint(nextWord, " "))
+// GenerateN generates count independent texts of at most n words each, as
+// Generate would write them, reusing the Chain for every text. The same opts
+// apply to each text; a random source given WithRand is drawn from in turn,
+// so the texts differ from one another but are reproducible as a whole.
+func (c *Chain[T]) GenerateN(n, count int, opts ...GenerateOption) ([]string, error) {
+	texts := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		text, err := c.GenerateString(n, opts...)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
 
-		prefix.Shift(nextWord)
+// Tokens returns an iterator over at most n words generated from Chain, so
+// that callers can consume words one at a time, stop early, and format them
+// as they please. Each iteration generates a new text. If opts are invalid,
+// or generating fails, the iterator yields nothing more; TokensErr reports
+// why.
+func (c *Chain[T]) Tokens(n int, opts ...GenerateOption) iter.Seq[T] {
+	seq, _ := c.TokensErr(n, opts...)
+	return seq
+}
+
+// TokensErr is like Tokens but also returns a function reporting the error
+// that ended the last iteration early, if any: that opts are invalid, a
+// failure of the Chain's Store, or an error a WordFunc returned. It reports
+// nil once an iteration generates every word it may, or the caller stops
+// it, so that a text cut short is not taken for a whole one.
+func (c *Chain[T]) TokensErr(n int, opts ...GenerateOption) (iter.Seq[T], func() error) {
+	var err error
+	seq := func(yield func(T) bool) {
+		var cfg *generateConfig
+		if cfg, err = newGenerateConfig(opts); err != nil {
+			return
+		}
+		err = c.walk(context.Background(), n, cfg, yield)
 	}
+	return seq, func() error { return err }
+}
 
-	return nil
+// GenerateString returns a string of at most n words generated from Chain,
+// as Generate would write it.
+func (c *Chain[T]) GenerateString(n int, opts ...GenerateOption) (string, error) {
+	var b strings.Builder
+	if err := c.Generate(&b, n, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
 }
 
-func main() {
-	numWords := flag.Int("words", 100, "maximum number of words to print")
-	prefixLen := flag.Int("prefix", 2, "prefix length in words")
+// observe records word as a suffix of the prefix of word IDs window and
+// returns the word's ID.
+func (c *Chain[T]) observe(window Prefix[uint32], word T) (uint32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Parse flags and seed the random number generator
-	flag.Parse()
-	rand.Seed(time.Now().UnixNano())
+	id, err := c.intern(word)
+	if err != nil {
+		return noID, err
+	}
+	return id, c.record(window, id)
+}
 
-	// Build up a Markov Chain from the standard input
-	chain := NewChain(*prefixLen)
-	chain.Build(os.Stdin)
+// intern returns the ID of word, assigning it one, and recording it in the
+// Chain's store, if it has none. c.mu must be held for writing.
+func (c *Chain[T]) intern(word T) (uint32, error) {
+	id := c.vocab.id(word)
+	if id != noID {
+		return id, nil
+	}
+	switch c.store.(type) {
+	case mapStore, arrayStore:
+		// The store of a Chain made by New does not record its words.
+		return c.vocab.intern(word), nil
+	}
+	b, err := encodeWord(word)
+	if err != nil {
+		return noID, err
+	}
+	id, err = c.store.intern(b, uint32(len(c.vocab.words)))
+	if err != nil {
+		return noID, err
+	}
+	if err := c.sync(int(id)); err != nil {
+		return noID, err
+	}
+	if got := c.vocab.intern(word); got != id {
+		return noID, fmt.Errorf("markov: store assigned %v ID %d, which is that of %v", word, id, c.vocab.words[id])
+	}
+	return id, nil
+}
 
-	// Write our generated text to the standard output
-	err := chain.Generate(os.Stdout, *numWords)
+// sync interns the words that other Chains sharing the Chain's store have
+// added to it if the Chain knows fewer than n words, and then checks that it
+// knows at least n. c.mu must be held for writing.
+func (c *Chain[T]) sync(n int) error {
+	if n <= len(c.vocab.words) {
+		return nil
+	}
+	if err := c.learn(); err != nil {
+		return err
+	}
+	if n > len(c.vocab.words) {
+		return fmt.Errorf("markov: store has no word with ID %d", n-1)
+	}
+	return nil
+}
+
+// learn interns every word that other Chains sharing the Chain's store have
+// added to it. c.mu must be held for writing.
+func (c *Chain[T]) learn() error {
+	from := uint32(len(c.vocab.words))
+	words, err := c.store.wordsFrom(from)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	for i, b := range words {
+		if err := internStored(&c.vocab, from+uint32(i), b); err != nil {
+			return fmt.Errorf("markov: %w", err)
+		}
+	}
+	return nil
+}
+
+// lookup returns the words with the given IDs, interning any that other
+// Chains sharing the Chain's store have added to it.
+func (c *Chain[T]) lookup(ids []uint32) ([]T, error) {
+	n := 0
+	for _, id := range ids {
+		n = max(n, int(id)+1)
+	}
+
+	c.mu.RLock()
+	known := n <= len(c.vocab.words)
+	c.mu.RUnlock()
+	if !known {
+		c.mu.Lock()
+		err := c.sync(n)
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	words := make([]T, len(ids))
+	for i, id := range ids {
+		words[i] = c.vocab.words[id]
+	}
+	return words, nil
+}
+
+// add records the word with the given ID as a suffix of window.
+func (c *Chain[T]) add(window Prefix[uint32], id uint32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.record(window, id)
+}
+
+// get returns the suffixes of window, or nil if it has none, without
+// allocating if the Chain is kept in memory and skips no words. c.mu must
+// be held.
+func (c *Chain[T]) get(window Prefix[uint32]) (*suffixes, error) {
+	window = c.masked(window)
+	switch s := c.store.(type) {
+	case arrayStore:
+		return s.prefixes[windowKey(window)], nil
+	case mapStore:
+		var buf [64]byte
+		return s.lookup(appendKey(buf[:0], window)), nil
+	}
+	return c.store.get(key(window))
+}
+
+// record records one more occurrence of the word with the given ID
+// following window, allocating only for a new prefix if the Chain is kept in
+// memory and skips no words. c.mu must be held for writing.
+func (c *Chain[T]) record(window Prefix[uint32], id uint32) error {
+	window = c.masked(window)
+	switch s := c.store.(type) {
+	case arrayStore:
+		s.record(windowKey(window), id, 1)
+		return nil
+	case mapStore:
+		var buf [64]byte
+		s.record(appendKey(buf[:0], window), id, 1)
+		return nil
+	}
+	return c.store.add(key(window), id, 1)
+}
+
+// flush writes any changes the Chain's store has buffered.
+func (c *Chain[T]) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.store.flush()
+}
+
+// ids returns the IDs of words, using noID for any never observed.
+// c.mu must be held.
+func (c *Chain[T]) ids(words []T) []uint32 {
+	ids := make([]uint32, len(words))
+	for i, word := range words {
+		ids[i] = c.vocab.id(word)
+	}
+	return ids
+}
+
+// window returns the prefix of word IDs that follows the words with the
+// given IDs at the start of a text.
+func (c *Chain[T]) window(ids []uint32) Prefix[uint32] {
+	window := make(Prefix[uint32], c.prefixLen)
+	for _, id := range ids {
+		window.Shift(id)
+	}
+	return window
+}
+
+// words returns the words of window, leaving out the padding at the start
+// of a text. c.mu must be held.
+func (c *Chain[T]) words(window Prefix[uint32]) Prefix[T] {
+	words := make(Prefix[T], 0, len(window))
+	for _, id := range window {
+		if id != beginID && int(id) < len(c.vocab.words) {
+			words = append(words, c.vocab.words[id])
+		}
 	}
-	fmt.Println()
+	return words
 }
@@ -0,0 +1,512 @@
+package markov
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixString(t *testing.T) {
+	p := Prefix[string]{"a", "b", "c"}
+	if got, want := p.String(), "a b c"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestPrefixShift(t *testing.T) {
+	p := Prefix[string]{"a", "b", "c"}
+	p.Shift("d")
+	if want := (Prefix[string]{"b", "c", "d"}); !reflect.DeepEqual(p, want) {
+		t.Errorf("Shift(\"d\") = %q, want %q", p, want)
+	}
+}
+
+func TestChainBuild(t *testing.T) {
+	c := NewChain(2)
+	if err := c.Build(strings.NewReader("I am a c I am a d \n")); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	want := map[string]map[string]int{
+		"":     {"I": 1},
+		"I":    {"am": 1},
+		"I am": {"a": 2},
+		"am a": {"c": 1, "d": 1},
+		"a c":  {"I": 1},
+		"c I":  {"am": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
+
+func TestChainBuildWithMarkers(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, `Hi there. "Who's there?" Me`)
+
+	want := map[string]map[string]int{
+		"":               {"Hi": 1, `"Who's`: 1, "Me": 1},
+		"Hi":             {"there.": 1},
+		"Hi there.":      {End: 1},
+		`"Who's`:         {`there?"`: 1},
+		`"Who's there?"`: {End: 1},
+		"Me":             {End: 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
+
+func TestChainBuildWithSentenceReset(t *testing.T) {
+	c := NewChain(2, WithSentenceReset())
+	mustBuild(t, c, "Dr. Who ran. Dr. Who hid.")
+
+	want := map[string]map[string]int{
+		"":        {"Dr.": 2},
+		"Dr.":     {"Who": 2},
+		"Dr. Who": {"ran.": 1, "hid.": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(9, WithRand(rand.New(rand.NewSource(1))))
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if words := strings.Fields(got); len(words) != 9 || words[3] != "Dr." || words[6] != "Dr." {
+		t.Errorf("GenerateString = %q, want three sentences of three words", got)
+	}
+}
+
+func TestChainGenerateWithMarkers(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "The end. The end.")
+
+	var b strings.Builder
+	if err := c.Generate(&b, 10); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got, want := b.String(), "The end."; got != want {
+		t.Errorf("Generate = %q, want %q", got, want)
+	}
+}
+
+func TestChainCount(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "I am a c I am a d I am a c")
+
+	tests := []struct {
+		prefix Prefix[string]
+		word   string
+		want   int
+	}{
+		{Prefix[string]{"am", "a"}, "c", 2},
+		{Prefix[string]{"am", "a"}, "d", 1},
+		{Prefix[string]{"am", "a"}, "e", 0},
+		{Prefix[string]{"no", "such"}, "c", 0},
+	}
+	for _, tt := range tests {
+		if got := c.Count(tt.prefix, tt.word); got != tt.want {
+			t.Errorf("Count(%q, %q) = %d, want %d", tt.prefix, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestChainBuildReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a b c "), errReader{readErr})
+
+	c := NewChain(2)
+	if err := c.Build(r); !errors.Is(err, readErr) {
+		t.Errorf("Build error = %v, want %v", err, readErr)
+	}
+}
+
+func TestChainBuildContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := NewChain(2)
+	if err := c.BuildContext(ctx, strings.NewReader("a b c")); !errors.Is(err, context.Canceled) {
+		t.Errorf("BuildContext error = %v, want %v", err, context.Canceled)
+	}
+	if got := chainCounts(c); len(got) != 0 {
+		t.Errorf("chain = %v, want empty", got)
+	}
+}
+
+func TestChainGenerate(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		{2, "the quick"},
+		{5, "the quick brown fox jumps"},
+		{10, "the quick brown fox jumps"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := c.Generate(&b, tt.n); err != nil {
+			t.Fatalf("Generate(%d) error: %v", tt.n, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("Generate(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestChainGenerateContextCanceled(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var b strings.Builder
+	if err := c.GenerateContext(ctx, &b, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateContext error = %v, want %v", err, context.Canceled)
+	}
+	if b.Len() != 0 {
+		t.Errorf("GenerateContext wrote %q, want nothing", b.String())
+	}
+}
+
+func TestChainCompile(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+	c.Compile()
+
+	var b strings.Builder
+	if err := c.Generate(&b, 10); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got, want := b.String(), "the quick brown fox jumps"; got != want {
+		t.Errorf("Generate after Compile = %q, want %q", got, want)
+	}
+}
+
+func TestChainTokens(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+
+	var got []string
+	for word := range c.Tokens(10) {
+		got = append(got, word)
+	}
+	if want := []string{"the", "quick", "brown", "fox", "jumps"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens(10) = %q, want %q", got, want)
+	}
+
+	got = nil
+	for word := range c.Tokens(10) {
+		if word == "fox" {
+			break
+		}
+		got = append(got, word)
+	}
+	if want := []string{"the", "quick", "brown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens(10) until \"fox\" = %q, want %q", got, want)
+	}
+
+	for word := range c.Tokens(10, WithTopK(-1)) {
+		t.Errorf("Tokens with invalid options yielded %q", word)
+	}
+}
+
+func TestChainTokensErr(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+
+	seq, errFn := c.TokensErr(10)
+	if got := slices.Collect(seq); len(got) != 5 {
+		t.Errorf("TokensErr(10) = %q, want 5 words", got)
+	}
+	if err := errFn(); err != nil {
+		t.Errorf("TokensErr(10) error = %v, want nil", err)
+	}
+
+	seq, errFn = c.TokensErr(10, WithTopK(-1))
+	for word := range seq {
+		t.Errorf("TokensErr with invalid options yielded %q", word)
+	}
+	if errFn() == nil {
+		t.Error("TokensErr with invalid options reported no error")
+	}
+
+	failed := errors.New("failed")
+	seq, errFn = c.TokensErr(10, WithWordFunc(func(word string, _ Prefix[string]) error {
+		if word == "brown" {
+			return failed
+		}
+		return nil
+	}))
+	if got, want := slices.Collect(seq), []string{"the", "quick"}; !slices.Equal(got, want) {
+		t.Errorf("TokensErr with a failing WordFunc = %q, want %q", got, want)
+	}
+	if err := errFn(); !errors.Is(err, failed) {
+		t.Errorf("TokensErr with a failing WordFunc error = %v, want %v", err, failed)
+	}
+
+	// Stopping early is no error.
+	seq, errFn = c.TokensErr(10)
+	for range seq {
+		break
+	}
+	if err := errFn(); err != nil {
+		t.Errorf("TokensErr stopped early error = %v, want nil", err)
+	}
+}
+
+func TestChainGenerateString(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps")
+
+	got, err := c.GenerateString(3)
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "the quick brown"; got != want {
+		t.Errorf("GenerateString(3) = %q, want %q", got, want)
+	}
+}
+
+func TestChainGenerateN(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c a d b c d a b d c a")
+
+	rng := func() GenerateOption { return WithRand(rand.New(rand.NewSource(1))) }
+	texts, err := c.GenerateN(5, 3, rng())
+	if err != nil {
+		t.Fatalf("GenerateN error: %v", err)
+	}
+	if len(texts) != 3 {
+		t.Fatalf("GenerateN returned %d texts, want 3", len(texts))
+	}
+	if texts[0] == texts[1] && texts[1] == texts[2] {
+		t.Errorf("GenerateN texts are all %q, want independent texts", texts[0])
+	}
+
+	again, err := c.GenerateN(5, 3, rng())
+	if err != nil {
+		t.Fatalf("GenerateN error: %v", err)
+	}
+	if !reflect.DeepEqual(again, texts) {
+		t.Errorf("GenerateN with the same seed = %q, want %q", again, texts)
+	}
+}
+
+func TestNewGeneric(t *testing.T) {
+	c := New[int](1)
+	c.Add(1, 2, 3, 1, 2, 4)
+
+	if got := c.Count(Prefix[int]{2}, 3); got != 1 {
+		t.Errorf("Count({2}, 3) = %d, want 1", got)
+	}
+	var got []int
+	for word := range c.Tokens(3, WithGreedy()) {
+		got = append(got, word)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens(3) = %v, want %v", got, want)
+	}
+	if got, err := c.GenerateString(3, WithGreedy()); err != nil || got != "1 2 3" {
+		t.Errorf("GenerateString(3) = %q, %v, want %q", got, err, "1 2 3")
+	}
+	if err := c.Generate(io.Discard, 3, WithWordFunc(func(string, Prefix[string]) error { return nil })); err == nil {
+		t.Error("Generate with a WordFunc of strings succeeded, want error")
+	}
+}
+
+func TestNewGenericKinds(t *testing.T) {
+	// Words of any comparable type, not only those a store can record,
+	// build and generate.
+	type pt struct{ x, y int }
+	points := New[pt](1, WithNgramIndex(2))
+	points.Add(pt{0, 0}, pt{1, 2}, pt{3, 4})
+	var got []pt
+	for word := range points.Tokens(3, WithGreedy()) {
+		got = append(got, word)
+	}
+	if want := []pt{{0, 0}, {1, 2}, {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens(3) = %v, want %v", got, want)
+	}
+	if _, err := Open[pt](NewMemoryStore(), 1); err == nil {
+		t.Error("Open of structs succeeded, want error")
+	}
+
+	type sym string
+	syms := New[sym](1)
+	syms.Add("a", "b", "c")
+	if got := syms.Count(Prefix[sym]{"b"}, "c"); got != 1 {
+		t.Errorf("Count({b}, c) = %d, want 1", got)
+	}
+	if got, err := syms.GenerateString(3, WithGreedy()); err != nil || got != "a b c" {
+		t.Errorf("GenerateString(3) = %q, %v, want %q", got, err, "a b c")
+	}
+}
+
+func TestChainConcurrentUse(t *testing.T) {
+	c := NewChain(2)
+	mustBuild(t, c, "the quick brown fox jumps over the lazy dog")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := c.Build(strings.NewReader("the quick red fox naps")); err != nil {
+				t.Errorf("Build error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.Generate(io.Discard, 20); err != nil {
+				t.Errorf("Generate error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func mustBuild(t *testing.T, c *Chain[string], text string) {
+	t.Helper()
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build(%q) error: %v", text, err)
+	}
+}
+
+// chainCounts returns the suffix counts of every prefix in c, keyed by the
+// prefix's words as Generate would write them.
+func chainCounts(c *Chain[string]) map[string]map[string]int {
+	m := make(map[string]map[string]int)
+	c.store.each(func(k string, s *suffixes) bool {
+		prefix := strings.Join(c.words(unkey(k)), c.separator())
+		m[prefix] = make(map[string]int)
+		for i, id := range s.words {
+			m[prefix][c.vocab.words[id]] = s.counts[i]
+		}
+		return true
+	})
+	return m
+}
+
+func TestChainKeysDoNotAllocate(t *testing.T) {
+	// Prefixes of up to arrayLen words are kept in an arrayStore, and longer
+	// ones in a mapStore.
+	for _, prefixLen := range []int{2, arrayLen + 1} {
+		c := NewChain(prefixLen)
+		mustBuild(t, c, "the quick brown fox jumps over the lazy dog")
+		window := c.window(c.ids(strings.Fields("the quick brown fox jumps")[:prefixLen]))
+		id := c.vocab.id("over")
+
+		if allocs := testing.AllocsPerRun(100, func() { c.get(window) }); allocs != 0 {
+			t.Errorf("prefix length %d: get made %v allocations, want 0", prefixLen, allocs)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { c.record(window, id) }); allocs != 0 {
+			t.Errorf("prefix length %d: record of a known prefix made %v allocations, want 0", prefixLen, allocs)
+		}
+	}
+}
+
+func TestChainArrayStore(t *testing.T) {
+	for prefixLen := 1; prefixLen <= arrayLen+1; prefixLen++ {
+		c := NewChain(prefixLen)
+		if _, ok := c.store.(arrayStore); ok != (prefixLen <= arrayLen) {
+			t.Errorf("prefix length %d: store is %T", prefixLen, c.store)
+		}
+		words := strings.Fields("a b c d e f a b c d e g")
+		c.Add(words...)
+		want := 2
+		if prefixLen == 5 {
+			want = 1 // "a b c d e" is followed by f and then g
+		}
+		if got := c.Count(words[:prefixLen], words[prefixLen]); got != want {
+			t.Errorf("prefix length %d: Count(%q, %q) = %d, want %d", prefixLen, words[:prefixLen], words[prefixLen], got, want)
+		}
+
+		var b bytes.Buffer
+		if err := c.SaveBinary(&b); err != nil {
+			t.Fatalf("SaveBinary error: %v", err)
+		}
+		loaded, err := LoadChain(&b)
+		if err != nil {
+			t.Fatalf("LoadChain error: %v", err)
+		}
+		if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("prefix length %d: loaded chain = %v, want %v", prefixLen, got, want)
+		}
+	}
+}
+
+func BenchmarkChainBuild(b *testing.B) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog and runs away\n", 10000)
+	b.SetBytes(int64(len(text)))
+	for range b.N {
+		c := NewChain(2)
+		if err := c.Build(strings.NewReader(text)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestChainSuffixes(t *testing.T) {
+	c := NewChain(1, WithMarkers(), WithLowercase())
+	mustBuild(t, c, "A b. A c. A b.")
+
+	if got, want := c.Suffixes([]string{"a"}), map[string]int{"b.": 2, "c.": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes(a) = %v, want %v", got, want)
+	}
+	if got, want := c.Suffixes([]string{"b."}), map[string]int{End: 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes(b.) = %v, want %v", got, want)
+	}
+	if got, want := c.Suffixes(nil), map[string]int{"a": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes(nil) = %v, want %v", got, want)
+	}
+	if got := c.Suffixes([]string{"z"}); got != nil {
+		t.Errorf("Suffixes(z) = %v, want nil", got)
+	}
+
+	ints := New[int](1, WithMarkers())
+	ints.Add(0, 1, 0)
+	if got, want := ints.Suffixes([]int{0}), map[int]int{1: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes(0) = %v, want %v without the end", got, want)
+	}
+}
+
+func TestChainMarkerWords(t *testing.T) {
+	// Without markers, Begin and End in a text are ordinary words.
+	c := NewChain(1)
+	mustBuild(t, c, "see the </s> tag and the <s> tag here")
+
+	if got, want := c.Suffixes([]string{"the"}), map[string]int{End: 1, Begin: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes(the) = %v, want %v", got, want)
+	}
+	if got := c.Count(Prefix[string]{Begin}, "tag"); got != 1 {
+		t.Errorf("Count({<s>}, tag) = %d, want 1", got)
+	}
+	if got := c.Count(nil, "see"); got != 1 {
+		t.Errorf("Count({}, see) = %d, want 1", got)
+	}
+
+	c = NewChain(1)
+	mustBuild(t, c, "see the </s> tag here")
+	if got, err := c.GenerateString(10, WithGreedy()); err != nil || got != "see the </s> tag here" {
+		t.Errorf("GenerateString = %q, %v, want %q", got, err, "see the </s> tag here")
+	}
+}
@@ -0,0 +1,241 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// WithMbox makes BuildFiles, BuildFS and BuildURLs read each file as an
+// mbox mailbox, as BuildMbox does, rather than as a single text, skipping
+// the messages they cannot parse.
+func WithMbox() BuildOption {
+	return func(cfg *buildConfig) {
+		cfg.mbox = true
+	}
+}
+
+// BuildMbox records the plain-text body of each message of the mbox
+// mailbox read from r as a separate text, as Build would, so that a Chain
+// can be trained on mailing list archives. Headers, HTML alternatives and
+// attachments are left out, as are quoted replies with the lines
+// introducing them, forwarded originals, signatures and mailing list
+// footers. Quoted-printable and base64 bodies are decoded, as are
+// ISO-8859-1 ones; bodies in other character sets are read as they are.
+//
+// A message whose headers or MIME parts cannot be parsed, of which large
+// archives hold a few, is skipped, and BuildMbox returns the number of
+// messages it skipped. It returns an error only if r cannot be read, is not
+// a mailbox, or building the Chain fails.
+func (c *Chain[T]) BuildMbox(ctx context.Context, r io.Reader) (skipped int, err error) {
+	err = readMbox(r, func(msg []byte) error {
+		body, err := plainBody(msg)
+		if err != nil {
+			skipped++
+			return nil
+		}
+		if body == "" {
+			return nil
+		}
+		return c.BuildContext(ctx, strings.NewReader(body))
+	})
+	return skipped, err
+}
+
+// errNotMbox reports input that does not start with the "From " line of an
+// mbox message.
+var errNotMbox = errors.New("markov: not an mbox mailbox")
+
+// readMbox calls yield with each message of the mbox mailbox read from r,
+// without its "From " line and with the lines escaped as ">From " in the
+// mboxo and mboxrd formats unescaped.
+func readMbox(r io.Reader, yield func(msg []byte) error) error {
+	br := bufio.NewReader(r)
+	var msg bytes.Buffer
+	started, blank := false, true
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case blank && bytes.HasPrefix(line, []byte("From ")):
+				if started {
+					if err := yield(msg.Bytes()); err != nil {
+						return err
+					}
+				}
+				msg.Reset()
+				started = true
+			case !started:
+				if len(bytes.TrimSpace(line)) > 0 {
+					return errNotMbox
+				}
+			default:
+				if trimmed := bytes.TrimLeft(line, ">"); len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+					line = line[1:]
+				}
+				msg.Write(line)
+			}
+			blank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("markov: %w", err)
+		}
+	}
+	if !started {
+		return nil
+	}
+	return yield(msg.Bytes())
+}
+
+// plainBody returns the plain text of the body of msg, an email message,
+// with its quoted replies and signature left out.
+func plainBody(msg []byte) (string, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return "", err
+	}
+	text, err := plainText(textproto.MIMEHeader(m.Header), m.Body)
+	if err != nil {
+		return "", err
+	}
+	return stripReplies(text), nil
+}
+
+// plainText returns the plain text of a MIME entity with the given header
+// and body: the text of a text/plain entity, decoded, or of the text/plain
+// parts of a multipart one, or nothing for other entities and attachments.
+func plainText(header textproto.MIMEHeader, body io.Reader) (string, error) {
+	mediaType, params := "text/plain", map[string]string{}
+	if ct := header.Get("Content-Type"); ct != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(ct); err != nil {
+			// An entity of an unknown type is left out rather than mistaken
+			// for text.
+			return "", nil
+		}
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" {
+		return "", nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		var texts []string
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			text, err := plainText(part.Header, part)
+			if err != nil {
+				return "", err
+			}
+			if text == "" {
+				continue
+			}
+			if mediaType == "multipart/alternative" {
+				// The alternatives are the same text, of which only one is
+				// plain.
+				return text, nil
+			}
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, "\n\n"), nil
+	case mediaType == "text/plain":
+		switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+		case "quoted-printable":
+			body = quotedprintable.NewReader(body)
+		case "base64":
+			body = base64.NewDecoder(base64.StdEncoding, body)
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(params["charset"]) {
+		case "iso-8859-1", "latin1":
+			return latin1(b), nil
+		}
+		return string(b), nil
+	}
+	return "", nil
+}
+
+// latin1 returns the text encoded in ISO-8859-1 by b, whose bytes are the
+// code points of its characters.
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// stripReplies returns text, the body of a message, without its quoted
+// replies and the attribution lines introducing them, such as "On Monday,
+// Ann wrote:", or anything from its signature, forwarded original message
+// or mailing list footer on.
+func stripReplies(text string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "--" || isOriginalMessage(trimmed) || isFooterRule(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			kept = dropAttribution(kept)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// isOriginalMessage reports whether line starts a forwarded or replied-to
+// message quoted without ">", as some mail clients do.
+func isOriginalMessage(line string) bool {
+	return strings.HasPrefix(line, "-----Original Message-----") ||
+		strings.HasPrefix(line, "---------- Forwarded message")
+}
+
+// isFooterRule reports whether line is the rule of underscores starting the
+// footer that mailing list software such as Mailman appends.
+func isFooterRule(line string) bool {
+	return len(line) >= 10 && strings.Trim(line, "_") == ""
+}
+
+// dropAttribution returns the kept lines before a quoted reply without the
+// attribution introducing it, which ends in "wrote:" and may be wrapped
+// onto a second line.
+func dropAttribution(kept []string) []string {
+	end := len(kept)
+	for end > 0 && strings.TrimSpace(kept[end-1]) == "" {
+		end--
+	}
+	if end == 0 || !strings.HasSuffix(strings.TrimSpace(kept[end-1]), "wrote:") {
+		return kept
+	}
+	end--
+	if end > 0 && !strings.HasPrefix(strings.TrimSpace(kept[end]), "On ") && strings.HasPrefix(strings.TrimSpace(kept[end-1]), "On ") {
+		end--
+	}
+	return kept[:end]
+}
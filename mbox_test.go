@@ -0,0 +1,169 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMbox is a mailbox of a plain message replying to another, a
+// multipart one with an HTML alternative and an attachment, and a
+// quoted-printable one in ISO-8859-1.
+const testMbox = `From ann@example.com Mon Jan  1 00:00:00 2024
+From: Ann <ann@example.com>
+Subject: Re: cats
+
+The cat sat on the mat.
+>From the start it sat there.
+
+On Sun, Dec 31, 2023, Bob <bob@example.com>
+wrote:
+> Where did the cat sit?
+>> Who knows.
+
+Thanks.
+-- 
+Ann, signing off
+
+From bob@example.com Mon Jan  1 00:01:00 2024
+From: Bob <bob@example.com>
+Subject: dogs
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: base64
+
+VGhlIGRvZyByYW4u
+--inner
+Content-Type: text/html
+
+<p>The dog ran.</p>
+--inner--
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="notes.txt"
+
+secret notes
+--outer--
+
+From carol@example.com Mon Jan  1 00:02:00 2024
+From: Carol <carol@example.com>
+Subject: caf=E9
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+A caf=E9 bird sang.
+_______________________________________________
+Cats mailing list
+`
+
+func TestChainBuildMbox(t *testing.T) {
+	want := NewChain(1)
+	for _, text := range []string{
+		"The cat sat on the mat.\nFrom the start it sat there.\n\nThanks.",
+		"The dog ran.",
+		"A café bird sang.",
+	} {
+		mustBuild(t, want, text)
+	}
+
+	c := NewChain(1)
+	if skipped, err := c.BuildMbox(context.Background(), strings.NewReader(testMbox)); err != nil || skipped != 0 {
+		t.Fatalf("BuildMbox = %d skipped, %v, want none", skipped, err)
+	}
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	if _, err := c.BuildMbox(context.Background(), strings.NewReader("Dear Ann,\n")); !errors.Is(err, errNotMbox) {
+		t.Errorf("BuildMbox of a letter error = %v, want %v", err, errNotMbox)
+	}
+	if _, err := NewChain(1).BuildMbox(context.Background(), strings.NewReader("")); err != nil {
+		t.Errorf("BuildMbox of an empty mailbox error = %v", err)
+	}
+}
+
+func TestChainBuildMboxSkips(t *testing.T) {
+	// Messages that cannot be parsed are skipped, and the rest built.
+	const mbox = `From ann@example.com Mon Jan  1 00:00:00 2024
+this line is no header
+From: Ann <ann@example.com>
+
+Lost words.
+
+From bob@example.com Mon Jan  1 00:01:00 2024
+From: Bob <bob@example.com>
+Content-Type: multipart/mixed; boundary="parts"
+
+--parts
+Content-Type: text/plain
+
+Truncated words.
+
+From carol@example.com Mon Jan  1 00:02:00 2024
+From: Carol <carol@example.com>
+
+The cat sat.
+`
+	want := NewChain(1)
+	mustBuild(t, want, "The cat sat.")
+
+	c := NewChain(1)
+	skipped, err := c.BuildMbox(context.Background(), strings.NewReader(mbox))
+	if err != nil {
+		t.Fatalf("BuildMbox error: %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("BuildMbox skipped %d messages, want 2", skipped)
+	}
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
+
+func TestWithMbox(t *testing.T) {
+	want := NewChain(1)
+	if _, err := want.BuildMbox(context.Background(), strings.NewReader(testMbox)); err != nil {
+		t.Fatalf("BuildMbox error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "list.mbox")
+	if err := os.WriteFile(path, []byte(testMbox), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	c := NewChain(1)
+	if err := c.BuildFiles(context.Background(), []string{path}, WithMbox()); err != nil {
+		t.Fatalf("BuildFiles error: %v", err)
+	}
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
+
+func TestStripReplies(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Hello.\r\n\r\nBye.\r\n", "Hello.\n\nBye."},
+		{"On Monday, Ann wrote:\n> Hi\nHello.", "Hello."},
+		{"Yes.\n\nAnn wrote:\n\n> Hi\n", "Yes."},
+		{"I wrote: this\nand that.", "I wrote: this\nand that."},
+		{"Agreed.\n\n-----Original Message-----\nFrom: Ann\n\nHi", "Agreed."},
+		{"See below.\n---------- Forwarded message ---------\nHi", "See below."},
+		{"Hi.\n--\nAnn", "Hi."},
+	}
+	for _, tt := range tests {
+		if got := stripReplies(tt.text); got != tt.want {
+			t.Errorf("stripReplies(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package markov
+
+import (
+	"unique"
+	"unsafe"
+)
+
+// MemStats is an estimate of the memory a Chain takes, as reported by
+// Chain.MemStats.
+type MemStats struct {
+	Prefixes int // the number of prefixes held in memory
+	Suffixes int // the number of suffixes of those prefixes
+
+	KeyBytes      int64 // the keys of the prefixes
+	SuffixBytes   int64 // the word IDs and counts of their suffixes
+	VocabBytes    int64 // the words, by ID and by value
+	OverheadBytes int64 // maps, indexes, sampling tables and headers
+}
+
+// TotalBytes returns the estimated memory the Chain takes in all.
+func (m MemStats) TotalBytes() int64 {
+	return m.KeyBytes + m.SuffixBytes + m.VocabBytes + m.OverheadBytes
+}
+
+// Sizes, in bytes, that MemStats counts.
+const (
+	pointerSize     = int64(unsafe.Sizeof(uintptr(0)))
+	stringSize      = int64(unsafe.Sizeof(""))
+	sliceSize       = int64(unsafe.Sizeof([]byte(nil)))
+	suffixesSize    = int64(unsafe.Sizeof(suffixes{}))
+	aliasTableSize  = int64(unsafe.Sizeof(aliasTable{}))
+	topSuffixesSize = int64(unsafe.Sizeof(topSuffixes{}))
+)
+
+// mapEntrySize returns the memory a map entry whose key and value take size
+// bytes is estimated to take, with its control byte and the slack of a map
+// filled to seven eighths.
+func mapEntrySize(size int64) int64 {
+	return (size + 1) * 8 / 7
+}
+
+// MemStats returns an estimate of the memory the Chain takes, from the
+// sizes of its keys, suffixes and words and of the maps and slices holding
+// them, so that a service can plan the memory a model needs before it loads
+// it. The estimate leaves out memory the Go runtime keeps for itself.
+//
+// Strings shared with other Chains, as words are, are counted in full by
+// each. A Chain kept in a database or opened by OpenFrozen reports only what
+// it keeps in memory, chiefly its vocabulary.
+func (c *Chain[T]) MemStats() MemStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var m MemStats
+	switch s := c.store.(type) {
+	case arrayStore:
+		keySize := int64(unsafe.Sizeof(arrayKey{}))
+		for _, suf := range s.prefixes {
+			m.addPrefix(keySize, suf)
+			m.OverheadBytes += mapEntrySize(keySize+pointerSize) - keySize
+		}
+	case mapStore:
+		m.addPrefixes(s)
+	case *memoryStore:
+		m.addPrefixes(s.prefixes)
+		m.VocabBytes += storedWordsSize(s.words)
+	case *sketchStore:
+		for k, t := range s.prefixes {
+			m.addPrefix(int64(len(k)), t.suffixes)
+			m.OverheadBytes += mapEntrySize(stringSize+pointerSize) + topSuffixesSize +
+				8*int64(cap(t.heap)+cap(t.at))
+		}
+		m.SuffixBytes += 4 * int64(len(s.counters))
+		m.VocabBytes += storedWordsSize(s.words)
+	}
+
+	var word T
+	m.VocabBytes += int64(cap(c.vocab.words)) * int64(unsafe.Sizeof(word))
+	m.VocabBytes += int64(len(c.vocab.ids)) * mapEntrySize(int64(unsafe.Sizeof(word))+4)
+	m.VocabBytes += int64(cap(c.vocab.handles)) * int64(unsafe.Sizeof(unique.Handle[string]{}))
+	for _, w := range c.vocab.words {
+		if s, ok := any(w).(string); ok {
+			m.VocabBytes += int64(len(s))
+		}
+	}
+	return m
+}
+
+// addPrefixes adds the prefixes of a mapStore.
+func (m *MemStats) addPrefixes(s mapStore) {
+	for k, suf := range s {
+		m.addPrefix(int64(len(k)), suf)
+		m.OverheadBytes += mapEntrySize(stringSize + pointerSize)
+	}
+}
+
+// addPrefix adds a prefix whose key takes keySize bytes and its suffixes.
+func (m *MemStats) addPrefix(keySize int64, s *suffixes) {
+	m.Prefixes++
+	m.Suffixes += len(s.words)
+	m.KeyBytes += keySize
+	m.SuffixBytes += 4*int64(cap(s.words)) + 8*int64(cap(s.counts))
+	m.OverheadBytes += suffixesSize + int64(len(s.index))*mapEntrySize(4+8)
+	if s.alias != nil {
+		m.OverheadBytes += aliasTableSize + 8*int64(cap(s.alias.prob)+cap(s.alias.alias))
+	}
+}
+
+// storedWordsSize returns the memory taken by the words a store keeps.
+func storedWordsSize(words [][]byte) int64 {
+	n := int64(cap(words)) * sliceSize
+	for _, w := range words {
+		n += int64(cap(w))
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package markov
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestChainMemStats(t *testing.T) {
+	var b strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&b, "w%d w%d w%d ", i%3000, i%7, i%500)
+	}
+	text := b.String()
+
+	for _, prefixLen := range []int{2, 5} {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		c := NewChain(prefixLen)
+		mustBuild(t, c, text)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+
+		m := c.MemStats()
+		if m.Prefixes == 0 || m.Suffixes < m.Prefixes {
+			t.Errorf("prefix length %d: MemStats = %+v, want every prefix and suffix counted", prefixLen, m)
+		}
+		if m.KeyBytes == 0 || m.SuffixBytes == 0 || m.VocabBytes == 0 || m.OverheadBytes == 0 {
+			t.Errorf("prefix length %d: MemStats = %+v, want every kind of memory counted", prefixLen, m)
+		}
+		// The estimate should be near the memory the Chain holds, and no more
+		// than the memory allocated building it.
+		live := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		allocated := int64(after.TotalAlloc - before.TotalAlloc)
+		if total := m.TotalBytes(); total < live/3 || total > allocated {
+			t.Errorf("prefix length %d: TotalBytes = %d, want at least a third of the %d bytes held and at most the %d allocated", prefixLen, total, live, allocated)
+		}
+		runtime.KeepAlive(c)
+	}
+}
+
+func TestChainMemStatsVocabOnly(t *testing.T) {
+	c, err := OpenChain(newTestStore(), 1)
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, "a b c")
+	if m := c.MemStats(); m.Prefixes != 0 || m.KeyBytes != 0 || m.VocabBytes == 0 {
+		t.Errorf("MemStats = %+v, want only the vocabulary counted", m)
+	}
+}
@@ -0,0 +1,150 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// A MergeOption configures how Merge combines two Chains.
+type MergeOption func(*mergeConfig)
+
+// mergeConfig holds the settings of MergeOptions.
+type mergeConfig struct {
+	self, other float64
+}
+
+// WithWeights makes Merge weigh the counts of the Chain by self and those
+// of the other Chain by other, as in 0.8×A + 0.2×B, so that blending a
+// large general Chain with a small one of a particular domain can bias it
+// toward the domain. Only the ratio of the weights matters: Merge scales the
+// counts of the Chain weighing more by it, rounding, so that no count is
+// lost. Weighing each Chain by the inverse of its Stats().Transitions
+// blends their probabilities rather than their counts.
+func WithWeights(self, other float64) MergeOption {
+	return func(cfg *mergeConfig) {
+		cfg.self, cfg.other = self, other
+	}
+}
+
+// Merge adds the counts of other, a Chain with prefixes of as many words, to
+// those of the Chain, so that Chains trained on shards of a corpus, or on
+// corpora gathered at different times, combine into the Chain training on
+// them all would have built. The words of other the Chain does not know are
+// added to it in the order other observed them. other is unchanged, and may
+// be the Chain itself, whose counts Merge then doubles.
+func (c *Chain[T]) Merge(other *Chain[T], opts ...MergeOption) error {
+	cfg := mergeConfig{self: 1, other: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	valid := func(w float64) bool { return w > 0 && !math.IsInf(w, 1) }
+	if !valid(cfg.self) || !valid(cfg.other) {
+		return errors.New("markov: merge weights must be positive and finite")
+	}
+	if other.prefixLen != c.prefixLen {
+		return fmt.Errorf("markov: cannot merge a chain with prefixes of %d words into one with prefixes of %d", other.prefixLen, c.prefixLen)
+	}
+	if !slices.Equal(other.skipGram, c.skipGram) {
+		return errors.New("markov: cannot merge chains skipping different words of their prefixes")
+	}
+	if other.reversed != c.reversed {
+		return errors.New("markov: cannot merge a reversed chain and one that is not")
+	}
+	if other.backoff != c.backoff {
+		return errors.New("markov: cannot merge a chain backing off and one that is not")
+	}
+	m, err := other.model()
+	if err != nil {
+		return fmt.Errorf("markov: merging chain: %w", err)
+	}
+	other.mu.RLock()
+	ngrams := maps.Clone(other.ngrams)
+	other.mu.RUnlock()
+
+	scale := 1.0
+	if cfg.self > cfg.other {
+		c.mu.Lock()
+		err := c.rewrite(func(_ string, s *suffixes) *suffixes {
+			return s.scaled(cfg.self / cfg.other)
+		})
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	} else {
+		scale = cfg.other / cfg.self
+	}
+	c.mu.Lock()
+	c.mergeNgrams(ngrams)
+	c.mu.Unlock()
+	return c.mergeModel(m, scale)
+}
+
+// scaled returns the suffixes of s with their counts multiplied by f, which
+// is at least 1, and rounded, or s itself if f is 1.
+func (s *suffixes) scaled(f float64) *suffixes {
+	if f == 1 {
+		return s
+	}
+	scaled := newSuffixes()
+	for i, id := range s.words {
+		scaled.addCount(id, scaleCount(s.counts[i], f))
+	}
+	return scaled
+}
+
+// scaleCount returns n multiplied by f and rounded.
+func scaleCount(n int, f float64) int {
+	return int(math.Round(float64(n) * f))
+}
+
+// mergeModel adds the counts of the model m, of a Chain with the same prefix
+// length, multiplied by scale, which is at least 1, to the Chain.
+func (c *Chain[T]) mergeModel(m model[T], scale float64) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() {
+		if flushErr := c.store.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	ids, err := c.internAll(m.Words)
+	if err != nil {
+		return err
+	}
+	prefix := make(Prefix[uint32], c.prefixLen)
+	for _, p := range m.Prefixes {
+		if slices.Max(p.Words) >= uint32(len(ids)) || slices.Max(p.Next) >= uint32(len(ids)) {
+			// Chains sharing the store m was read from have added words since.
+			return errors.New("markov: merging chain: chain changed while it was read")
+		}
+		for i, id := range p.Words {
+			prefix[i] = ids[id]
+		}
+		k := key(prefix)
+		for i, id := range p.Next {
+			if err := c.store.add(k, ids[id], scaleCount(p.Counts[i], scale)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// internAll interns words, the words of another Chain by ID, in order and
+// returns the ID the Chain assigns each. c.mu must be held for writing.
+func (c *Chain[T]) internAll(words []T) ([]uint32, error) {
+	ids := make([]uint32, len(words))
+	ids[beginID], ids[endID] = beginID, endID
+	for id := firstID; int(id) < len(ids); id++ {
+		var err error
+		if ids[id], err = c.intern(words[id]); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
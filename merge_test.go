@@ -0,0 +1,90 @@
+package markov
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainMerge(t *testing.T) {
+	want := NewChain(2, WithMarkers())
+	mustBuild(t, want, "The cat sat. The dog ran.")
+	mustBuild(t, want, "The dog sat. A bird sang.")
+
+	a := NewChain(2, WithMarkers())
+	mustBuild(t, a, "The cat sat. The dog ran.")
+	b := NewChain(2, WithMarkers())
+	mustBuild(t, b, "The dog sat. A bird sang.")
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	if got, want := chainCounts(a), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged chain = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(a.vocab.words, want.vocab.words) {
+		t.Errorf("words = %q, want %q in the order Build assigns them", a.vocab.words, want.vocab.words)
+	}
+	if got := b.Count(Prefix[string]{"The", "cat"}, "sat."); got != 0 {
+		t.Errorf("other chain Count({The cat}, sat.) = %d, want 0, unchanged", got)
+	}
+
+	db := openTestDB(t)
+	sqlChain, err := OpenSQLChain(db, 2, WithMarkers())
+	if err != nil {
+		t.Fatalf("OpenSQLChain error: %v", err)
+	}
+	if err := sqlChain.Merge(a); err != nil {
+		t.Fatalf("Merge into SQL error: %v", err)
+	}
+	if got, want := chainCounts(sqlChain), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged SQL chain = %v, want %v", got, want)
+	}
+}
+
+func TestChainMergeSelf(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c")
+	if err := c.Merge(c); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 2 {
+		t.Errorf("Count({a}, b) = %d, want 2", got)
+	}
+	if err := c.Merge(NewChain(2)); err == nil || !strings.Contains(err.Error(), "prefixes") {
+		t.Errorf("Merge of another order error = %v, want an error", err)
+	}
+}
+
+func TestChainMergeWeighted(t *testing.T) {
+	build := func(text string) *Chain[string] {
+		c := NewChain(1)
+		mustBuild(t, c, text)
+		return c
+	}
+
+	a, b := build("a b a c"), build("a c")
+	if err := a.Merge(b, WithWeights(0.25, 0.75)); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	if got, want := a.Suffixes(Prefix[string]{"a"}), map[string]int{"b": 1, "c": 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes({a}) weighing the other chain 3 times = %v, want %v", got, want)
+	}
+
+	a, b = build("a b a c"), build("a c")
+	if err := a.Merge(b, WithWeights(2, 1)); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+	if got, want := a.Suffixes(Prefix[string]{"a"}), map[string]int{"b": 2, "c": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes({a}) weighing the chain twice = %v, want %v", got, want)
+	}
+	if got := b.Count(Prefix[string]{"a"}, "c"); got != 1 {
+		t.Errorf("other chain Count({a}, c) = %d, want 1, unchanged", got)
+	}
+
+	for _, weights := range [][2]float64{{0, 1}, {1, -1}, {math.Inf(1), 1}, {1, math.NaN()}} {
+		if err := a.Merge(b, WithWeights(weights[0], weights[1])); err == nil {
+			t.Errorf("Merge WithWeights(%v, %v) succeeded, want error", weights[0], weights[1])
+		}
+	}
+}
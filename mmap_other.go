@@ -0,0 +1,18 @@
+//go:build !unix
+
+package markov
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the content of f, since memory-mapping it is not supported
+// on this platform, returning it and a function that does nothing.
+func mmapFile(f *os.File) ([]byte, func() error, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,286 @@
+package markov
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// A Mode determines what a Chain treats as a single word of its input.
+type Mode int
+
+const (
+	// Words splits input into whitespace-separated words, which are
+	// written separated by spaces.
+	Words Mode = iota
+
+	// Chars splits input into individual characters (runes), whitespace
+	// included, which are written exactly as generated. Character chains
+	// suit generating names and text in languages written without spaces.
+	Chars
+
+	// Bytes splits input into individual bytes, whether or not they form
+	// valid text, which are written exactly as generated. Byte chains suit
+	// synthesizing binary data such as fuzzing payloads or fake file
+	// contents.
+	Bytes
+
+	// Lines splits input into whitespace-separated words and a Newline word
+	// ending each line, and treats every line as a separate text, so that
+	// each starts from the same prefix as the start of the input. Generate
+	// writes Newline as a line break and starts a new line afresh after it.
+	// Line chains suit lyrics, poetry and log lines.
+	Lines
+
+	// Paragraphs splits input into whitespace-separated words and a
+	// ParagraphBreak word wherever a blank line separates two paragraphs,
+	// and treats every paragraph as a separate text. Generate writes
+	// ParagraphBreak as a blank line, so that long texts are split into
+	// paragraphs rather than written as a single wall of words.
+	Paragraphs
+)
+
+// Newline is the word ending each line of a Chain in Lines mode, and
+// ParagraphBreak the word ending each paragraph of a Chain in Paragraphs
+// mode.
+const (
+	Newline        = "\n"
+	ParagraphBreak = "\n\n"
+)
+
+// modeNames holds the textual form of each Mode.
+var modeNames = []string{
+	Words:      "word",
+	Chars:      "char",
+	Bytes:      "byte",
+	Lines:      "line",
+	Paragraphs: "paragraph",
+}
+
+// String returns the name of the Mode, as accepted by UnmarshalText.
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Mode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(modeNames) {
+		return nil, fmt.Errorf("markov: invalid mode %d", int(m))
+	}
+	return []byte(modeNames[m]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting the name of
+// a Mode: "word", "char", "byte", "line" or "paragraph".
+func (m *Mode) UnmarshalText(text []byte) error {
+	for i, name := range modeNames {
+		if string(text) == name {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("markov: unknown mode %q", text)
+}
+
+// WithMode makes the Chain split its input as described by m rather than
+// into whitespace-separated words.
+//
+// Chars mode requires words of type string or rune, Bytes mode words of
+// type string or byte, and Lines and Paragraphs modes words of type string.
+func WithMode(m Mode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// readToken reads the next word of the Chain's mode from r.
+func (c *Chain[T]) readToken(r *bufio.Reader) (T, error) {
+	var word T
+	switch c.mode {
+	case Chars:
+		ch, _, err := r.ReadRune()
+		if err != nil {
+			return word, err
+		}
+		switch p := any(&word).(type) {
+		case *string:
+			*p = string(ch)
+		case *rune:
+			*p = ch
+		default:
+			return word, fmt.Errorf("markov: cannot read characters as %T", word)
+		}
+		return word, nil
+	case Bytes:
+		b, err := r.ReadByte()
+		if err != nil {
+			return word, err
+		}
+		switch p := any(&word).(type) {
+		case *string:
+			*p = string([]byte{b})
+		case *byte:
+			*p = b
+		default:
+			return word, fmt.Errorf("markov: cannot read bytes as %T", word)
+		}
+		return word, nil
+	case Lines, Paragraphs:
+		p, ok := any(&word).(*string)
+		if !ok {
+			return word, fmt.Errorf("markov: cannot read %ss as %T", c.mode, word)
+		}
+		var err error
+		if c.mode == Lines {
+			*p, err = readLineWord(r)
+		} else {
+			*p, err = readParagraphWord(r)
+		}
+		return word, err
+	default:
+		_, err := fmt.Fscan(r, &word)
+		return word, err
+	}
+}
+
+// readLineWord reads the next whitespace-separated word from r, or Newline
+// if the line ends first.
+func readLineWord(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		ch, _, err := r.ReadRune()
+		if err != nil {
+			if err == io.EOF && b.Len() > 0 {
+				return b.String(), nil
+			}
+			return "", err
+		}
+		switch {
+		case ch == '\n' && b.Len() == 0:
+			return Newline, nil
+		case unicode.IsSpace(ch) && b.Len() == 0:
+		case unicode.IsSpace(ch):
+			r.UnreadRune()
+			return b.String(), nil
+		default:
+			b.WriteRune(ch)
+		}
+	}
+}
+
+// writeToken writes word to w as text.
+func (c *Chain[T]) writeToken(w *bufio.Writer, word T) error {
+	var err error
+	switch v := any(word).(type) {
+	case string:
+		_, err = w.WriteString(v)
+	case rune:
+		if c.mode == Chars {
+			_, err = w.WriteRune(v)
+		} else {
+			_, err = fmt.Fprint(w, v)
+		}
+	case byte:
+		if c.mode == Bytes {
+			err = w.WriteByte(v)
+		} else {
+			_, err = fmt.Fprint(w, v)
+		}
+	default:
+		_, err = fmt.Fprint(w, v)
+	}
+	return err
+}
+
+// readParagraphWord reads the next whitespace-separated word from r, or
+// ParagraphBreak if a blank line precedes it.
+func readParagraphWord(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	newlines := 0
+	for {
+		ch, _, err := r.ReadRune()
+		if err != nil {
+			if err == io.EOF && b.Len() > 0 {
+				return b.String(), nil
+			}
+			return "", err
+		}
+		switch {
+		case unicode.IsSpace(ch) && b.Len() == 0:
+			if ch == '\n' {
+				newlines++
+			}
+		case unicode.IsSpace(ch):
+			r.UnreadRune()
+			return b.String(), nil
+		case newlines >= 2:
+			r.UnreadRune()
+			return ParagraphBreak, nil
+		default:
+			b.WriteRune(ch)
+		}
+	}
+}
+
+// separator returns the text Generate writes between words.
+func (o *options) separator() string {
+	switch o.mode {
+	case Chars, Bytes:
+		return ""
+	default:
+		return " "
+	}
+}
+
+// spacer returns a function returning the text Generate writes before each
+// successive word of a text, tidied WithTidy if tidy is true.
+func (c *Chain[T]) spacer(tidy bool) func(word T) string {
+	if _, ok := any(*new(T)).(string); c.punctuation || tidy && ok {
+		d := detokenizer{whole: !c.punctuation}
+		return func(word T) string {
+			s, _ := any(word).(string)
+			if tidy && c.isBreak(word) {
+				// Punctuation after a break has nothing to attach to.
+				d.prev = ""
+				return ""
+			}
+			return d.space(s)
+		}
+	}
+
+	sep := c.separator()
+	first := true
+	var prev T
+	return func(word T) string {
+		defer func() { first, prev = false, word }()
+		if first || c.isBreak(word) || c.isBreak(prev) {
+			return ""
+		}
+		if c.segmented {
+			p, _ := any(prev).(string)
+			s, _ := any(word).(string)
+			if joinsCJK(p, s) {
+				return ""
+			}
+		}
+		return sep
+	}
+}
+
+// isBreak reports whether word is the Newline ending a line of a Chain in
+// Lines mode or the ParagraphBreak ending a paragraph in Paragraphs mode.
+func (c *Chain[T]) isBreak(word T) bool {
+	s, ok := any(word).(string)
+	switch c.mode {
+	case Lines:
+		return ok && s == Newline
+	case Paragraphs:
+		return ok && s == ParagraphBreak
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModeText(t *testing.T) {
+	for _, m := range []Mode{Words, Chars, Bytes, Lines, Paragraphs} {
+		text, err := m.MarshalText()
+		if err != nil {
+			t.Fatalf("%v.MarshalText error: %v", m, err)
+		}
+		var got Mode
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) error: %v", text, err)
+		}
+		if got != m {
+			t.Errorf("UnmarshalText(%q) = %v, want %v", text, got, m)
+		}
+	}
+
+	var m Mode
+	if err := m.UnmarshalText([]byte("sentence")); err == nil {
+		t.Error(`UnmarshalText("sentence") succeeded, want error`)
+	}
+	if _, err := Mode(-1).MarshalText(); err == nil {
+		t.Error("Mode(-1).MarshalText succeeded, want error")
+	}
+}
+
+func TestCharsMode(t *testing.T) {
+	c := NewChain(2, WithMode(Chars))
+	mustBuild(t, c, "abc ab")
+
+	want := map[string]map[string]int{
+		"":   {"a": 1},
+		"a":  {"b": 1},
+		"ab": {"c": 1},
+		"bc": {" ": 1},
+		"c ": {"a": 1},
+		" a": {"b": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(10, WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "abc abc ab"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+
+	got, err = c.GenerateString(3, WithPrompt("c "))
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "abc"; got != want {
+		t.Errorf("GenerateString with prompt = %q, want %q", got, want)
+	}
+}
+
+func TestBytesMode(t *testing.T) {
+	data := "\x00\xff\x00\xfe"
+	c := NewChain(1, WithMode(Bytes))
+	mustBuild(t, c, data)
+
+	if got := c.Count(Prefix[string]{"\x00"}, "\xff"); got != 1 {
+		t.Errorf("Count(\\x00, \\xff) = %d, want 1", got)
+	}
+	if got := c.Count(Prefix[string]{"\x00"}, "\ufffd"); got != 0 {
+		t.Errorf("Count(\\x00, U+FFFD) = %d, want 0", got)
+	}
+
+	got, err := c.GenerateString(4, WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "\x00\xff\x00\xff"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
+
+func TestLinesMode(t *testing.T) {
+	c := NewChain(2, WithMode(Lines))
+	mustBuild(t, c, "roses are red\r\n  violets are blue\n\nsugar is sweet\n")
+
+	want := map[string]map[string]int{
+		"":            {"roses": 1, "violets": 1, Newline: 1, "sugar": 1},
+		"roses":       {"are": 1},
+		"roses are":   {"red": 1},
+		"are red":     {Newline: 1},
+		"violets":     {"are": 1},
+		"violets are": {"blue": 1},
+		"are blue":    {Newline: 1},
+		"sugar":       {"is": 1},
+		"sugar is":    {"sweet": 1},
+		"is sweet":    {Newline: 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(8, WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "roses are red\nroses are red\n"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
+
+func TestParagraphsMode(t *testing.T) {
+	c := NewChain(1, WithMode(Paragraphs))
+	mustBuild(t, c, "\n\nOne two.\nThree.\n\n \n\nFour five.\n")
+
+	want := map[string]map[string]int{
+		"":       {"One": 1, "Four": 1},
+		"One":    {"two.": 1},
+		"two.":   {"Three.": 1},
+		"Three.": {ParagraphBreak: 1},
+		"Four":   {"five.": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(9, WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "One two. Three.\n\nOne two. Three.\n\nOne"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
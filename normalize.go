@@ -0,0 +1,48 @@
+package markov
+
+import (
+	"io"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithNormalization makes the Chain normalize its input, and the words
+// given to Add and Count, to the Unicode normalization form f before
+// splitting it into words, so that words that look the same but are encoded
+// differently, such as "café" with a precomposed or a combining accent,
+// share the same entries. norm.NFC suits most text; norm.NFKC also folds
+// compatibility characters such as ligatures and full-width letters into
+// their plain equivalents.
+//
+// Normalization applies only to text, so it is ignored in Bytes mode.
+func WithNormalization(f norm.Form) Option {
+	return func(o *options) {
+		o.normalize = true
+		o.form = f
+	}
+}
+
+// normalizeReader returns r normalized as configured WithNormalization.
+func (o *options) normalizeReader(r io.Reader) io.Reader {
+	if !o.normalize || o.mode == Bytes {
+		return r
+	}
+	return o.form.Reader(r)
+}
+
+// normalized returns words, or a copy of them normalized as configured
+// WithNormalization, WithLowercase and WithTokenTransform.
+func (c *Chain[T]) normalized(words []T) []T {
+	normalize := c.normalize && c.mode != Bytes
+	if !normalize && !c.lowercase && len(c.transforms) == 0 {
+		return words
+	}
+	normalized := make([]T, len(words))
+	for i, word := range words {
+		if s, ok := any(word).(string); ok && normalize {
+			word = any(c.form.String(s)).(T)
+		}
+		normalized[i] = c.canonical(word)
+	}
+	return normalized
+}
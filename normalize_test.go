@@ -0,0 +1,45 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestWithNormalization(t *testing.T) {
+	const composed, decomposed = "caf\u00e9", "cafe\u0301"
+
+	c := NewChain(1)
+	mustBuild(t, c, "a "+composed+" a "+decomposed)
+	if got := len(chainCounts(c)["a"]); got != 2 {
+		t.Errorf("without normalization, a has %d suffixes, want 2", got)
+	}
+
+	c = NewChain(1, WithNormalization(norm.NFC))
+	mustBuild(t, c, "a "+composed+" a "+decomposed)
+	if want := map[string]int{composed: 2}; !reflect.DeepEqual(chainCounts(c)["a"], want) {
+		t.Errorf("with NFC, a has suffixes %q, want %q", chainCounts(c)["a"], want)
+	}
+	if got := c.Count(Prefix[string]{"a"}, decomposed); got != 2 {
+		t.Errorf("Count(a, %q) = %d, want 2", decomposed, got)
+	}
+
+	c = NewChain(1, WithNormalization(norm.NFKC))
+	words := []string{"\ufb01ne", "\uff41"}
+	c.Add(words...)
+	if got := c.Count(Prefix[string]{"fine"}, "a"); got != 1 {
+		t.Errorf("with NFKC, Count(fine, a) = %d, want 1", got)
+	}
+	if words[0] != "\ufb01ne" {
+		t.Errorf("Add modified its argument to %q", words)
+	}
+}
+
+func TestWithNormalizationChars(t *testing.T) {
+	c := NewChain(1, WithMode(Chars), WithNormalization(norm.NFC))
+	mustBuild(t, c, "e\u0301e")
+	if want := map[string]map[string]int{"": {"\u00e9": 1}, "\u00e9": {"e": 1}}; !reflect.DeepEqual(chainCounts(c), want) {
+		t.Errorf("chain = %q, want %q", chainCounts(c), want)
+	}
+}
@@ -0,0 +1,151 @@
+package markov
+
+import (
+	"bufio"
+	"context"
+	"slices"
+	"strings"
+)
+
+// A Novelty measures how much of a sequence of words the texts a Chain was
+// built from already hold, as returned by Novelty.
+type Novelty struct {
+	// N is the number of words in each n-gram compared: the length of the
+	// runs the Chain indexes WithNgramIndex, or else one more than its
+	// prefix length.
+	N int
+
+	// LongestOverlap is the number of words in the longest run of the
+	// sequence every N consecutive words of which appear in that order in
+	// the texts, or 0 if none do: the most the sequence may have copied of
+	// them verbatim.
+	LongestOverlap int
+
+	// NovelFraction is the fraction of the n-grams of the sequence the
+	// texts never held, 0 if it has fewer than N words.
+	NovelFraction float64
+}
+
+// Novelty returns how novel words are relative to the texts the Chain was
+// built from, comparing their n-grams to the runs of words it indexes
+// WithNgramIndex, if it has any, or else to the prefixes and suffixes it
+// counts, which for a Chain WithSkipGram ignore the words skipped. Every
+// n-gram of prefixLen+1 words a Chain generates is one it observed, so only
+// an index of longer runs tells how much generated text recombines them.
+func (c *Chain[T]) Novelty(words []T) (Novelty, error) {
+	words = c.normalized(words)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var seen func(gram []T) (bool, error)
+	nov := Novelty{N: c.prefixLen + 1}
+	if c.ngrams != nil {
+		nov.N = c.ngramLen
+		run := make([]uint64, nov.N)
+		seen = func(gram []T) (bool, error) {
+			for i, word := range gram {
+				run[i] = wordHash(word)
+			}
+			_, ok := c.ngrams[ngramHash(run)]
+			return ok, nil
+		}
+	} else {
+		if c.reversed {
+			// The counts of a reversed Chain follow its texts backwards.
+			words = slices.Clone(words)
+			slices.Reverse(words)
+		}
+		seen = func(gram []T) (bool, error) {
+			ids := c.ids(gram)
+			if slices.Contains(ids, noID) {
+				return false, nil
+			}
+			s, err := c.get(Prefix[uint32](ids[:c.prefixLen]))
+			if err != nil || s == nil {
+				return false, err
+			}
+			return s.count(ids[c.prefixLen]) > 0, nil
+		}
+	}
+
+	grams := len(words) - nov.N + 1
+	if grams < 1 {
+		return nov, nil
+	}
+	novel, run := 0, 0
+	for i := range grams {
+		ok, err := seen(words[i : i+nov.N])
+		if err != nil {
+			return Novelty{}, err
+		}
+		if !ok {
+			novel++
+			run = 0
+			continue
+		}
+		run++
+		nov.LongestOverlap = max(nov.LongestOverlap, run+nov.N-1)
+	}
+	nov.NovelFraction = float64(novel) / float64(grams)
+	return nov, nil
+}
+
+// GenerateWithNovelty returns a string of at most n words generated from the
+// Chain, as GenerateString would, along with the Novelty of the words
+// generated, not counting those of a prompt.
+func (c *Chain[T]) GenerateWithNovelty(n int, opts ...GenerateOption) (string, Novelty, error) {
+	cfg, err := newGenerateConfig(opts)
+	if err != nil {
+		return "", Novelty{}, err
+	}
+	// Words are scored as generated, before they are capitalized.
+	sub := *cfg
+	sub.sentenceCase = false
+	var words []T
+	err = c.walk(context.Background(), n, &sub, func(word T) bool {
+		words = append(words, word)
+		return true
+	})
+	if err != nil {
+		return "", Novelty{}, err
+	}
+
+	var b strings.Builder
+	bw := bufio.NewWriter(&b)
+	var caser *sentenceCaser[T]
+	if cfg.sentenceCase {
+		var prompt []T
+		if !c.reversed {
+			prompt = c.split(cfg.prompt)
+		}
+		caser = c.newSentenceCaser(prompt, cfg.tidy)
+	}
+	space := c.spacer(cfg.tidy)
+	for _, word := range words {
+		if caser != nil {
+			word = caser.capitalize(word)
+		}
+		if _, err := bw.WriteString(space(word)); err != nil {
+			return "", Novelty{}, err
+		}
+		if err := c.writeToken(bw, word); err != nil {
+			return "", Novelty{}, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return "", Novelty{}, err
+	}
+
+	nov, err := c.Novelty(words)
+	if err != nil {
+		return "", Novelty{}, err
+	}
+	return b.String(), nov, nil
+}
+
+// NoveltyOf returns the Novelty of text, split into words as Build splits
+// its input.
+func (c *Chain[T]) NoveltyOf(text string) (Novelty, error) {
+	return c.Novelty(c.split(text))
+}
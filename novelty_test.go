@@ -0,0 +1,107 @@
+package markov
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestChainNovelty(t *testing.T) {
+	texts := []string{"the cat sat on the mat", "the dog lay on the rug"}
+	tests := []struct {
+		name  string
+		opts  []Option
+		words string
+		want  Novelty
+	}{
+		{"counts", nil, "the cat sat on the rug", Novelty{N: 2, LongestOverlap: 6, NovelFraction: 0}},
+		{"counts novel", nil, "the cat lay on a mat", Novelty{N: 2, LongestOverlap: 2, NovelFraction: 0.6}},
+		{"unseen word", nil, "a bird", Novelty{N: 2, LongestOverlap: 0, NovelFraction: 1}},
+		{"too short", nil, "cat", Novelty{N: 2}},
+		{"index", []Option{WithNgramIndex(3)}, "the cat sat on the dog", Novelty{N: 3, LongestOverlap: 5, NovelFraction: 0.25}},
+		{"index verbatim", []Option{WithNgramIndex(3)}, "dog lay on the rug", Novelty{N: 3, LongestOverlap: 5, NovelFraction: 0}},
+		{"lowercase", []Option{WithLowercase()}, "The Cat sat", Novelty{N: 2, LongestOverlap: 3, NovelFraction: 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := New[string](1, test.opts...)
+			for _, text := range texts {
+				if err := c.Build(strings.NewReader(text)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := c.Novelty(strings.Fields(test.words))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Novelty(%q) = %+v, want %+v", test.words, got, test.want)
+			}
+		})
+	}
+}
+
+func TestChainNoveltyOf(t *testing.T) {
+	c := New[string](1, WithPunctuation())
+	if err := c.Build(strings.NewReader("the cat sat, the dog ran.")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.NoveltyOf("the cat ran.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.Novelty([]string{"the", "cat", "ran", "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want || got.NovelFraction == 0 {
+		t.Errorf("NoveltyOf = %+v, want %+v", got, want)
+	}
+}
+
+func TestChainNoveltyReversed(t *testing.T) {
+	c := New[string](1, WithMarkers())
+	if err := c.Build(strings.NewReader("the cat sat on the mat.")); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := c.Reverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields("the cat sat on the mat.")
+	got, err := rev.Novelty(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Novelty{N: 2, LongestOverlap: 6}); got != want {
+		t.Errorf("Novelty(%q) = %+v, want %+v", words, got, want)
+	}
+}
+
+func TestChainGenerateWithNovelty(t *testing.T) {
+	c := New[string](1, WithNgramIndex(3), WithLowercase())
+	for _, text := range []string{"the cat sat on the mat", "a dog sat on a rug"} {
+		if err := c.Build(strings.NewReader(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := []GenerateOption{WithRand(rand.New(rand.NewSource(1))), WithSentenceCase()}
+	text, nov, err := c.GenerateWithNovelty(20, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := c.GenerateString(20, WithRand(rand.New(rand.NewSource(1))), WithSentenceCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != want {
+		t.Errorf("GenerateWithNovelty text = %q, want %q", text, want)
+	}
+	wantNov, err := c.Novelty(strings.Fields(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nov != wantNov {
+		t.Errorf("GenerateWithNovelty novelty = %+v, want %+v", nov, wantNov)
+	}
+}
@@ -0,0 +1,121 @@
+package markov
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// An Option configures a Chain.
+type Option func(*options)
+
+// options holds the settings applied by Options.
+type options struct {
+	mode          Mode
+	tokenizer     func(io.Reader) Tokenizer
+	punctuation   bool
+	segmented     bool
+	markers       bool
+	sentenceReset bool
+	normalize     bool
+	form          norm.Form
+	lowercase     bool
+	transforms    []func(string) string
+	stopWords     map[string]bool
+	collapse      bool
+	placeholder   string
+	backoff       bool
+	skipGram      []int
+	reversed      bool
+	ngramLen      int
+}
+
+// Begin and End are the labels a Chain of strings reports for the boundaries
+// of texts and sentences. A prefix of Begin words stands for the start of a
+// text, and End is recorded as the suffix of the last word of a text by a
+// Chain created WithMarkers. A text holding "<s>" or "</s>" records them as
+// ordinary words.
+const (
+	Begin = "<s>"
+	End   = "</s>"
+)
+
+// WithMarkers makes the Chain mark where texts and sentences end while it is
+// built. Every sentence starts from the same prefix as the start of a text,
+// and End is recorded as the suffix of its last word, so that Generate starts
+// from the beginning of a real sentence and stops at a natural ending rather
+// than mid-sentence. End is never written by Generate. Sentences end as
+// described by WithSentenceReset.
+func WithMarkers() Option {
+	return func(o *options) {
+		o.markers = true
+	}
+}
+
+// WithSentenceReset makes the Chain start every sentence from the same
+// prefix as the start of a text while it is built, so that no prefix spans
+// two sentences. Generate likewise starts afresh after each sentence it
+// ends, rather than stopping, unless the Chain was created WithMarkers.
+//
+// A sentence ends at a string word ending in '.', '!' or '?', optionally
+// followed by closing quotes or brackets. A period ending a common title or
+// abbreviation such as "Dr." or "etc.", a capital initial such as "J.", or a
+// dotted abbreviation such as "e.g." or "U.S." does not end a sentence.
+func WithSentenceReset() Option {
+	return func(o *options) {
+		o.sentenceReset = true
+	}
+}
+
+// resetsSentences reports whether the Chain's prefixes restart at each
+// sentence.
+func (o *options) resetsSentences() bool {
+	return o.markers || o.sentenceReset
+}
+
+// endsSentence reports whether word is a string that ends a sentence.
+func endsSentence[T comparable](word T) bool {
+	s, ok := any(word).(string)
+	return ok && isSentenceEnd(s)
+}
+
+// isSentenceEnd reports whether word ends a sentence.
+func isSentenceEnd(word string) bool {
+	word = strings.TrimRight(word, `"')]}»”’`)
+	switch {
+	case strings.HasSuffix(word, "!"), strings.HasSuffix(word, "?"):
+		return true
+	case strings.HasSuffix(word, "."):
+		return !isAbbreviation(word)
+	}
+	return false
+}
+
+// abbreviations are common abbreviations, in lower case and without their
+// final period, that are rarely the last word of a sentence.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true,
+	"sr": true, "jr": true, "st": true, "mt": true, "rev": true,
+	"gen": true, "col": true, "lt": true, "sgt": true, "capt": true,
+	"gov": true, "sen": true, "rep": true, "vs": true, "etc": true,
+	"cf": true, "fig": true, "no": true, "vol": true, "approx": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true,
+	"jul": true, "aug": true, "sep": true, "sept": true, "oct": true,
+	"nov": true, "dec": true,
+}
+
+// isAbbreviation reports whether word, which ends in a period, looks like an
+// abbreviation rather than the last word of a sentence.
+func isAbbreviation(word string) bool {
+	stem := strings.TrimLeft(strings.TrimSuffix(word, "."), `"'([{«“‘`)
+	if abbreviations[strings.ToLower(stem)] {
+		return true
+	}
+	if r, size := utf8.DecodeRuneInString(stem); size == len(stem) {
+		return unicode.IsUpper(r)
+	}
+	return strings.Contains(stem, ".") && !strings.Contains(stem, "..")
+}
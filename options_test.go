@@ -0,0 +1,31 @@
+package markov
+
+import "testing"
+
+func TestIsSentenceEnd(t *testing.T) {
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"end.", true},
+		{"what?", true},
+		{"stop!", true},
+		{`there."`, true},
+		{"(really.)", true},
+		{"wait...", true},
+		{"word", false},
+		{"comma,", false},
+		{"Dr.", false},
+		{"etc.", false},
+		{"J.", false},
+		{"a.", true},
+		{"e.g.", false},
+		{"U.S.", false},
+		{`"Mr.`, false},
+	}
+	for _, tt := range tests {
+		if got := isSentenceEnd(tt.word); got != tt.want {
+			t.Errorf("isSentenceEnd(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package markov
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"maps"
+)
+
+// ngramSeed seeds the hashes of the n-grams indexed by every Chain, so that
+// the indexes of different Chains can be merged.
+var ngramSeed = maphash.MakeSeed()
+
+// WithNgramIndex makes the Chain also keep the hash of each run of n words of
+// the texts it is built from, about 8 bytes for each, so that Generate can
+// keep from copying them WithMaxOverlap(n-1). Like prefixes, runs do not
+// span texts, or sentences if the Chain resets at them.
+//
+// The index is kept in memory only: it is not saved, and the Chains Shorten,
+// Reverse and Cluster derive have none. Clone copies it, and Merge and
+// BuildFiles merge the indexes of the Chains they combine.
+func WithNgramIndex(n int) Option {
+	return func(o *options) {
+		o.ngramLen = n
+	}
+}
+
+// WithMaxOverlap makes Generate avoid writing more than k consecutive words,
+// counting those of a prompt, that appear in that order in the texts the
+// Chain was built from, so that it recombines its corpus rather than
+// regurgitating it: a suffix that would complete k+1 such words is never
+// picked, and generation ends when every suffix of a prefix would. The
+// Chain must have been created WithNgramIndex(k+1), and since every word a
+// Chain generates follows the words before it in some text, k should exceed
+// its prefix length. Like WithRepetitionPenalty, it does not apply to beam
+// search.
+func WithMaxOverlap(k int) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.maxOverlap = k
+	}
+}
+
+// NgramIndex returns the number of words of the runs the Chain indexes
+// WithNgramIndex, or 0 if it holds no index, as a Chain loaded from a file
+// or derived from another by Shorten, Reverse or Cluster does not. Without
+// an index, Novelty compares n-grams of prefixLen+1 words, every one of
+// which a Chain generates is one it observed.
+func (c *Chain[T]) NgramIndex() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ngrams == nil {
+		return 0
+	}
+	return c.ngramLen
+}
+
+// checkOverlap returns an error if cfg limits overlaps with n-grams the
+// Chain does not index.
+func (c *Chain[T]) checkOverlap(cfg *generateConfig) error {
+	if cfg.maxOverlap > 0 && cfg.maxOverlap+1 != c.ngramLen {
+		return fmt.Errorf("markov: limiting overlaps to %d words needs a chain created WithNgramIndex(%d)", cfg.maxOverlap, cfg.maxOverlap+1)
+	}
+	return nil
+}
+
+// indexing returns observe, which records the words passed it by scan,
+// wrapped to also index each run of words it completes if the Chain
+// indexes n-grams.
+func (c *Chain[T]) indexing(observe func(Prefix[uint32], T) (uint32, error)) func(Prefix[uint32], T) (uint32, error) {
+	if c.ngramLen < 1 {
+		return observe
+	}
+	run := make([]uint64, 0, c.ngramLen) // the hashes of the last words of the text
+	return func(window Prefix[uint32], word T) (uint32, error) {
+		id, err := observe(window, word)
+		if err != nil {
+			return id, err
+		}
+		if isStart(window) {
+			run = run[:0]
+		}
+		if len(run) == c.ngramLen {
+			run = append(run[:0], run[1:]...)
+		}
+		run = append(run, wordHash(word))
+		if len(run) == c.ngramLen {
+			h := ngramHash(run)
+			c.mu.Lock()
+			if c.ngrams == nil {
+				c.ngrams = make(map[uint64]struct{})
+			}
+			c.ngrams[h] = struct{}{}
+			c.mu.Unlock()
+		}
+		return id, nil
+	}
+}
+
+// isStart reports whether window is the prefix of the first word of a text
+// or sentence.
+func isStart(window Prefix[uint32]) bool {
+	for _, id := range window {
+		if id != beginID {
+			return false
+		}
+	}
+	return true
+}
+
+// forbidOverlaps zeroes the weights w of s's words that would complete a run
+// of words the Chain indexes, following recent, the IDs of the most recently
+// generated words. c.mu must be held.
+func (c *Chain[T]) forbidOverlaps(w []float64, s *suffixes, recent []uint32, cfg *generateConfig) {
+	k := cfg.maxOverlap
+	if len(recent) < k || len(c.ngrams) == 0 {
+		return
+	}
+	run := make([]uint64, k+1)
+	for i, id := range recent[len(recent)-k:] {
+		if int(id) >= len(c.vocab.words) {
+			return
+		}
+		run[i] = wordHash(c.vocab.words[id])
+	}
+	for i, id := range s.words {
+		if id == endID || int(id) >= len(c.vocab.words) {
+			continue
+		}
+		run[k] = wordHash(c.vocab.words[id])
+		if _, ok := c.ngrams[ngramHash(run)]; ok {
+			w[i] = 0
+		}
+	}
+}
+
+// mergeNgrams adds ngrams, the index of another Chain, to the Chain's.
+// c.mu must be held for writing.
+func (c *Chain[T]) mergeNgrams(ngrams map[uint64]struct{}) {
+	if len(ngrams) == 0 {
+		return
+	}
+	if c.ngrams == nil {
+		c.ngrams = make(map[uint64]struct{}, len(ngrams))
+	}
+	maps.Copy(c.ngrams, ngrams)
+}
+
+// wordHash returns the hash of word for indexing n-grams. Words that are
+// not strings or integers are hashed by their default format, which equal
+// words share.
+func wordHash[T comparable](word T) uint64 {
+	if b, err := encodeWord(word); err == nil {
+		return maphash.Bytes(ngramSeed, b)
+	}
+	return maphash.String(ngramSeed, fmt.Sprint(word))
+}
+
+// ngramHash returns the hash of the run of words with the given hashes.
+func ngramHash(run []uint64) uint64 {
+	var (
+		h   maphash.Hash
+		buf [8]byte
+	)
+	h.SetSeed(ngramSeed)
+	for _, x := range run {
+		binary.LittleEndian.PutUint64(buf[:], x)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,110 @@
+package markov
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestChainMaxOverlap(t *testing.T) {
+	texts := []string{"the cat sat on the mat", "the dog sat on the rug", "a cat lay on a rug"}
+	c := NewChain(1, WithMarkers(), WithNgramIndex(3))
+	trigrams := make(map[string]bool)
+	for _, text := range texts {
+		mustBuild(t, c, text)
+		words := strings.Fields(text)
+		for i := 0; i+3 <= len(words); i++ {
+			trigrams[strings.Join(words[i:i+3], " ")] = true
+		}
+	}
+	if len(c.ngrams) != len(trigrams) {
+		t.Errorf("indexed %d trigrams, want %d", len(c.ngrams), len(trigrams))
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	generated := 0
+	for range 100 {
+		got, err := c.GenerateString(20, WithMaxOverlap(2), WithRand(rng))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		words := strings.Fields(got)
+		for i := 0; i+3 <= len(words); i++ {
+			if trigram := strings.Join(words[i:i+3], " "); trigrams[trigram] {
+				t.Fatalf("GenerateString = %q, which copies %q", got, trigram)
+			}
+		}
+		generated += len(words)
+	}
+	if generated == 0 {
+		t.Error("GenerateString generated nothing")
+	}
+
+	if _, err := c.GenerateString(20, WithMaxOverlap(3)); err == nil {
+		t.Error("GenerateString with an overlap the chain does not index succeeded, want error")
+	}
+	if _, err := c.GenerateString(20, WithMaxOverlap(-1)); err == nil {
+		t.Error("GenerateString with a negative overlap succeeded, want error")
+	}
+}
+
+func TestChainNgramIndexBuilds(t *testing.T) {
+	const text = "the cat sat on the mat. the dog sat on the rug."
+	want := NewChain(2, WithSentenceReset(), WithNgramIndex(3))
+	mustBuild(t, want, text)
+
+	parallel := NewChain(2, WithSentenceReset(), WithNgramIndex(3))
+	if err := parallel.BuildParallel(context.Background(), strings.NewReader(text), 2); err != nil {
+		t.Fatalf("BuildParallel error: %v", err)
+	}
+	external := NewChain(2, WithSentenceReset(), WithNgramIndex(3))
+	if err := external.BuildExternal(context.Background(), strings.NewReader(text), WithMemoryLimit(4), WithTempDir(t.TempDir())); err != nil {
+		t.Fatalf("BuildExternal error: %v", err)
+	}
+	clone, err := want.Clone()
+	if err != nil {
+		t.Fatalf("Clone error: %v", err)
+	}
+	merged := NewChain(2, WithSentenceReset(), WithNgramIndex(3))
+	mustBuild(t, merged, "the cat sat on the mat.")
+	other := NewChain(2, WithSentenceReset(), WithNgramIndex(3))
+	mustBuild(t, other, "the dog sat on the rug.")
+	if err := merged.Merge(other); err != nil {
+		t.Fatalf("Merge error: %v", err)
+	}
+
+	for name, c := range map[string]*Chain[string]{"parallel": parallel, "external": external, "clone": clone, "merged": merged} {
+		if !maps.Equal(c.ngrams, want.ngrams) {
+			t.Errorf("%s: indexed %d n-grams, want the %d Build indexes", name, len(c.ngrams), len(want.ngrams))
+		}
+	}
+}
+
+func TestChainNgramIndex(t *testing.T) {
+	c := NewChain(2, WithNgramIndex(3))
+	if got := c.NgramIndex(); got != 0 {
+		t.Errorf("NgramIndex before building = %d, want 0", got)
+	}
+	mustBuild(t, c, "the cat sat on the mat")
+	if got := c.NgramIndex(); got != 3 {
+		t.Errorf("NgramIndex = %d, want 3", got)
+	}
+
+	var b bytes.Buffer
+	if err := c.SaveBinary(&b); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loaded, err := Load[string](&b, WithNgramIndex(3))
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if got := loaded.NgramIndex(); got != 0 {
+		t.Errorf("NgramIndex of a loaded chain = %d, want 0", got)
+	}
+	if got := NewChain(2).NgramIndex(); got != 0 {
+		t.Errorf("NgramIndex without WithNgramIndex = %d, want 0", got)
+	}
+}
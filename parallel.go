@@ -0,0 +1,163 @@
+package markov
+
+import (
+	"context"
+	"hash/maphash"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// parallelBatchSize is the number of observations BuildParallel sends a
+// worker at a time.
+const parallelBatchSize = 4096
+
+// BuildParallel is like BuildContext but counts the suffixes of the words it
+// reads with workers goroutines, or runtime.GOMAXPROCS(0) if workers is less
+// than 1, so that a large corpus trains several times faster on a multi-core
+// machine. The calling goroutine reads the words while each worker counts
+// the suffixes of its own shard of the prefixes; the shards are merged into
+// the Chain once the Reader is exhausted. The result is the Chain Build
+// would have built.
+//
+// The Chain is locked only while new words are added to it and while the
+// shards are merged, so it can generate meanwhile, but it does not observe
+// any words of r until they are merged.
+func (c *Chain[T]) BuildParallel(ctx context.Context, r io.Reader, workers int) error {
+	if c.reversed {
+		return errReversed
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	keyLen := 4 * c.prefixLen
+
+	shards := make([]mapStore, workers)
+	batches := make([]chan shardBatch, workers)
+	var wg sync.WaitGroup
+	for i := range shards {
+		shards[i] = make(mapStore)
+		batches[i] = make(chan shardBatch, 4)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches[i] {
+				for j, id := range b.ids {
+					shards[i].record(b.keys[j*keyLen:(j+1)*keyLen], id, 1)
+				}
+			}
+		}()
+	}
+
+	// Every observation of a prefix goes to the same shard, in order, so
+	// that its suffixes are counted in the order they were observed.
+	seed := maphash.MakeSeed()
+	pending := make([]shardBatch, workers)
+	for i := range pending {
+		pending[i] = newShardBatch(keyLen)
+	}
+	send := func(window Prefix[uint32], id uint32) {
+		var buf [64]byte
+		k := appendKey(buf[:0], c.masked(window))
+		i := maphash.Bytes(seed, k) % uint64(workers)
+		b := &pending[i]
+		b.keys = append(b.keys, k...)
+		b.ids = append(b.ids, id)
+		if len(b.ids) == parallelBatchSize {
+			batches[i] <- *b
+			*b = newShardBatch(keyLen)
+		}
+	}
+
+	ids := make(map[T]uint32)
+	observe, add := c.backingOff(c.indexing(func(window Prefix[uint32], word T) (uint32, error) {
+		id, ok := ids[word]
+		if !ok {
+			c.mu.Lock()
+			var err error
+			id, err = c.intern(word)
+			c.mu.Unlock()
+			if err != nil {
+				return noID, err
+			}
+			ids[word] = id
+		}
+		send(window, id)
+		return id, nil
+	}), func(window Prefix[uint32], id uint32) error {
+		send(window, id)
+		return nil
+	})
+	err := c.scan(ctx, c.tokenize(r), observe, add)
+
+	for i, b := range pending {
+		if len(b.ids) > 0 {
+			batches[i] <- b
+		}
+		close(batches[i])
+	}
+	wg.Wait()
+
+	// Merge the words observed, even if reading them failed, as Build
+	// keeps the words it read before an error.
+	if mergeErr := c.mergeShards(shards); err == nil {
+		err = mergeErr
+	}
+	return err
+}
+
+// A shardBatch is a batch of observations sent to a shard: the keys of their
+// prefixes, one after another, and the IDs of the words observed following
+// them.
+type shardBatch struct {
+	keys []byte
+	ids  []uint32
+}
+
+// newShardBatch returns an empty shardBatch with room for
+// parallelBatchSize observations of prefixes with keys keyLen bytes long.
+func newShardBatch(keyLen int) shardBatch {
+	return shardBatch{
+		keys: make([]byte, 0, parallelBatchSize*keyLen),
+		ids:  make([]uint32, 0, parallelBatchSize),
+	}
+}
+
+// mergeShards adds the counts of the prefixes of shards, each of which
+// holds different prefixes, to the Chain.
+func (c *Chain[T]) mergeShards(shards []mapStore) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() {
+		if flushErr := c.store.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	for _, shard := range shards {
+		for k, s := range shard {
+			if err := c.mergePrefix(k, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergePrefix adds the counts s of the prefix with key k to the Chain, which
+// takes ownership of s. c.mu must be held for writing.
+func (c *Chain[T]) mergePrefix(k string, s *suffixes) error {
+	existing, err := c.store.get(k)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return c.store.put(k, s)
+	}
+	for i, id := range s.words {
+		if err := c.store.add(k, id, s.counts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainBuildParallel(t *testing.T) {
+	text := strings.Repeat("The cat sat on the mat. The dog sat on the cat! Did it? ", 50)
+	for _, workers := range []int{0, 1, 3} {
+		for _, opts := range [][]Option{nil, {WithMarkers(), WithSentenceReset()}} {
+			want := NewChain(2, opts...)
+			mustBuild(t, want, "The bird sang.")
+			mustBuild(t, want, text)
+
+			c := NewChain(2, opts...)
+			mustBuild(t, c, "The bird sang.")
+			if err := c.BuildParallel(context.Background(), strings.NewReader(text), workers); err != nil {
+				t.Fatalf("BuildParallel error: %v", err)
+			}
+			if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+				t.Errorf("%d workers: chain = %v, want %v", workers, got, want)
+			}
+
+			rng := func() GenerateOption { return WithRand(rand.New(rand.NewSource(1))) }
+			got, _ := c.GenerateString(30, rng())
+			if want, _ := want.GenerateString(30, rng()); got != want {
+				t.Errorf("%d workers: GenerateString = %q, want %q", workers, got, want)
+			}
+		}
+	}
+}
+
+func TestChainBuildParallelReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a b c "), errReader{readErr})
+
+	c := NewChain(1)
+	if err := c.BuildParallel(context.Background(), r, 2); !errors.Is(err, readErr) {
+		t.Errorf("BuildParallel error = %v, want %v", err, readErr)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 1 {
+		t.Errorf("Count({a}, b) = %d, want 1 for the words read before the error", got)
+	}
+}
+
+func BenchmarkChainBuildParallel(b *testing.B) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog and runs away\n", 10000)
+	b.SetBytes(int64(len(text)))
+	for range b.N {
+		c := NewChain(2)
+		if err := c.BuildParallel(context.Background(), strings.NewReader(text), 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
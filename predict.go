@@ -0,0 +1,53 @@
+package markov
+
+// A Prediction is a word that may follow a prefix and the probability of it
+// doing so, as returned by Chain.Predict.
+type Prediction[T comparable] struct {
+	Word        T
+	Probability float64
+}
+
+// Predict returns the n words most likely to follow prefix, most likely
+// first, with the probability of each, or every word observed following
+// prefix if n is less than 1, so that a Chain can suggest the next word as
+// text is typed. A prefix longer than the Chain's prefixes is cut to its last
+// words, and a shorter one stands for the words at the start of a text.
+//
+// The end of a text is left out of the predictions, so their probabilities
+// sum to less than 1 where the Chain may end the text. Predict returns no
+// predictions for a prefix never observed, or if the Chain's store fails.
+func (c *Chain[T]) Predict(prefix []T, n int) []Prediction[T] {
+	prefix = c.normalized(prefix)
+
+	c.mu.RLock()
+	s, err := c.get(c.window(c.ids(prefix)))
+	if err != nil || s == nil {
+		c.mu.RUnlock()
+		return nil
+	}
+	var (
+		ids   []uint32
+		probs []float64
+	)
+	for _, i := range s.ranked() {
+		if s.words[i] == endID {
+			continue
+		}
+		if n > 0 && len(ids) == n {
+			break
+		}
+		ids = append(ids, s.words[i])
+		probs = append(probs, float64(s.counts[i])/float64(s.total))
+	}
+	c.mu.RUnlock()
+
+	words, err := c.lookup(ids)
+	if err != nil {
+		return nil
+	}
+	predictions := make([]Prediction[T], len(words))
+	for i, word := range words {
+		predictions[i] = Prediction[T]{word, probs[i]}
+	}
+	return predictions
+}
@@ -0,0 +1,32 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChainPredict(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "I like cats. I like dogs. I like cats. I love you. You like me.")
+
+	for _, tt := range []struct {
+		prefix []string
+		n      int
+		want   []Prediction[string]
+	}{
+		{[]string{"I", "like"}, 0, []Prediction[string]{{"cats.", 2.0 / 3}, {"dogs.", 1.0 / 3}}},
+		{[]string{"well", "I", "like"}, 1, []Prediction[string]{{"cats.", 2.0 / 3}}},
+		{[]string{"I"}, 5, []Prediction[string]{{"like", 0.75}, {"love", 0.25}}},
+		{nil, 0, []Prediction[string]{{"I", 0.8}, {"You", 0.2}}},
+		{[]string{"like", "cats."}, 0, nil}, // only the end follows
+		{[]string{"no", "such"}, 0, nil},
+	} {
+		got := c.Predict(tt.prefix, tt.n)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Predict(%q, %d) = %v, want %v", tt.prefix, tt.n, got, tt.want)
+		}
+	}
+}
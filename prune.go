@@ -0,0 +1,77 @@
+package markov
+
+// Prune removes every suffix observed fewer than minCount times following
+// its prefix, and then every prefix left with no suffixes, so that the Chain
+// takes less space and no longer generates the typos and other words seen
+// only once or twice in its corpus. Generating from a pruned Chain stops
+// early wherever it reaches a prefix that was removed.
+//
+// Prune keeps every word observed starting a text, however rarely, so that
+// the Chain can still start generating.
+//
+// Words remain in the Chain's vocabulary, with their IDs, even if no suffix
+// of theirs remains.
+func (c *Chain[T]) Prune(minCount int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := key(c.masked(make(Prefix[uint32], c.prefixLen)))
+	return c.rewrite(func(k string, s *suffixes) *suffixes {
+		if k == start {
+			return s
+		}
+		var kept *suffixes
+		for i, id := range s.words {
+			if s.counts[i] >= minCount {
+				if kept == nil {
+					kept = newSuffixes()
+				}
+				kept.addCount(id, s.counts[i])
+			}
+		}
+		if kept != nil && len(kept.words) == len(s.words) {
+			return s
+		}
+		return kept
+	})
+}
+
+// rewrite replaces the suffixes s of each prefix with key k by fn(k, s),
+// removing the prefix if they are nil. fn returns s to leave them as they
+// are, and must not modify s. c.mu must be held for writing.
+func (c *Chain[T]) rewrite(fn func(k string, s *suffixes) *suffixes) (err error) {
+	defer func() {
+		if flushErr := c.store.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	// Collect the prefixes to change first, as a store need not allow
+	// changing them while they are iterated over.
+	type change struct {
+		k string
+		s *suffixes
+	}
+	var changes []change
+	err = c.store.each(func(k string, s *suffixes) bool {
+		if rewritten := fn(k, s); rewritten != s {
+			changes = append(changes, change{k, rewritten})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range changes {
+		if err := c.store.remove(ch.k); err != nil {
+			return err
+		}
+		if ch.s != nil {
+			if err := c.store.put(ch.k, ch.s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
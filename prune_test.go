@@ -0,0 +1,43 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChainPrune(t *testing.T) {
+	const text = "a b a b a c. a b a d."
+	want := map[string]map[string]int{
+		"":  {"a": 1}, // the start of a text is kept
+		"a": {"b": 3},
+		"b": {"a": 3},
+	}
+
+	chains := map[string]*Chain[string]{"new": NewChain(1), "long": NewChain(5)}
+	for _, tt := range testStores(t) {
+		c, err := OpenChain(tt.s, 1)
+		if err != nil {
+			t.Fatalf("%s: OpenChain error: %v", tt.name, err)
+		}
+		chains[tt.name] = c
+	}
+	for name, c := range chains {
+		mustBuild(t, c, text)
+		if err := c.Prune(2); err != nil {
+			t.Fatalf("%s: Prune error: %v", name, err)
+		}
+		if c.prefixLen > 1 {
+			// No prefix of five words is observed twice.
+			if got, want := chainCounts(c), want[""]; len(got) != 1 || !reflect.DeepEqual(got[""], want) {
+				t.Errorf("%s: pruned chain = %v, want only its start %v", name, got, want)
+			}
+			continue
+		}
+		if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: pruned chain = %v, want %v", name, got, want)
+		}
+		if got, _ := c.GenerateString(3, WithGreedy()); got != "a b a" {
+			t.Errorf("%s: GenerateString = %q, want %q", name, got, "a b a")
+		}
+	}
+}
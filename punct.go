@@ -0,0 +1,158 @@
+package markov
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WithPunctuation makes the Chain treat punctuation marks as words of their
+// own, split from the words they are attached to as by PunctTokenizer, and
+// makes Generate reattach them to the words they follow or precede, so that
+// it writes "Hello, world." rather than "Hello , world .".
+func WithPunctuation() Option {
+	return func(o *options) {
+		o.tokenizer = PunctTokenizer
+		o.punctuation = true
+	}
+}
+
+// PunctTokenizer returns a Tokenizer that splits text into
+// whitespace-separated words and then splits the punctuation marks leading
+// and trailing each word into words of their own. A run of the same mark,
+// such as an ellipsis, is a single word. Apostrophes and hyphens are kept
+// within words, so "isn't" and "well-known" remain whole, as is the period
+// of an abbreviation such as "Dr." (see WithSentenceReset).
+func PunctTokenizer(r io.Reader) Tokenizer {
+	return &punctTokenizer{next: ScanTokenizer(bufio.ScanWords)(r)}
+}
+
+// punctTokenizer is a Tokenizer splitting punctuation from words.
+type punctTokenizer struct {
+	next  Tokenizer
+	words []string
+}
+
+func (t *punctTokenizer) Next() (string, error) {
+	for len(t.words) == 0 {
+		field, err := t.next.Next()
+		if err != nil {
+			return "", err
+		}
+		t.words = splitPunct(field)
+	}
+	word := t.words[0]
+	t.words = t.words[1:]
+	return word, nil
+}
+
+// splitPunct splits the leading and trailing punctuation marks of field into
+// words of their own.
+func splitPunct(field string) []string {
+	var lead []string
+	for field != "" {
+		mark := leadingMark(field)
+		if mark == "" {
+			break
+		}
+		lead = append(lead, mark)
+		field = field[len(mark):]
+	}
+
+	var trail []string
+	for field != "" {
+		mark := trailingMark(field)
+		if mark == "" || mark == "." && isAbbreviation(field) {
+			break
+		}
+		trail = append(trail, mark)
+		field = field[:len(field)-len(mark)]
+	}
+
+	words := lead
+	if field != "" {
+		words = append(words, field)
+	}
+	for i := len(trail) - 1; i >= 0; i-- {
+		words = append(words, trail[i])
+	}
+	return words
+}
+
+// leadingMark returns the run of the punctuation mark starting s, if any.
+func leadingMark(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if !isMark(r) {
+		return ""
+	}
+	n := size
+	for strings.HasPrefix(s[n:], s[:size]) {
+		n += size
+	}
+	return s[:n]
+}
+
+// trailingMark returns the run of the punctuation mark ending s, if any.
+func trailingMark(s string) string {
+	r, size := utf8.DecodeLastRuneInString(s)
+	if !isMark(r) {
+		return ""
+	}
+	n := size
+	for strings.HasSuffix(s[:len(s)-n], s[len(s)-size:]) {
+		n += size
+	}
+	return s[len(s)-n:]
+}
+
+// isMark reports whether r is a punctuation mark split from words.
+func isMark(r rune) bool {
+	switch r {
+	case '\'', '’', '-':
+		return false
+	}
+	return unicode.IsPunct(r)
+}
+
+// detokenizer decides the spacing between punctuation marks and words.
+type detokenizer struct {
+	prev      string
+	quoteOpen bool
+
+	// whole is whether only words wholly of punctuation marks are spaced
+	// as marks, for words not split WithPunctuation.
+	whole bool
+}
+
+// space returns the text to write before word.
+func (d *detokenizer) space(word string) string {
+	prev := d.prev
+	d.prev = word
+
+	closes := strings.ContainsAny(firstRune(word), `.,;:!?%)]}»”…`) && d.mark(word)
+	if word == `"` {
+		closes = d.quoteOpen
+		d.quoteOpen = !d.quoteOpen
+		if !closes {
+			// Remember that this quote opened, not closed, a quotation.
+			d.prev = "“"
+		}
+	}
+	if prev == "" || closes || strings.ContainsAny(firstRune(prev), "([{«“¿¡") && d.mark(prev) {
+		return ""
+	}
+	return " "
+}
+
+// mark reports whether word may be spaced as a punctuation mark.
+func (d *detokenizer) mark(word string) bool {
+	return !d.whole || strings.IndexFunc(word, func(r rune) bool { return !isMark(r) }) < 0
+}
+
+// firstRune returns the first rune of s as a string.
+func firstRune(s string) string {
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
@@ -0,0 +1,74 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPunct(t *testing.T) {
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{"word", []string{"word"}},
+		{"word.", []string{"word", "."}},
+		{`"Hello,`, []string{`"`, "Hello", ","}},
+		{`there."`, []string{"there", ".", `"`}},
+		{"(wait...)", []string{"(", "wait", "...", ")"}},
+		{"really?!", []string{"really", "?", "!"}},
+		{"isn't", []string{"isn't"}},
+		{"well-known,", []string{"well-known", ","}},
+		{"--", []string{"--"}},
+		{"¿qué?", []string{"¿", "qué", "?"}},
+		{"...", []string{"..."}},
+		{"Dr.", []string{"Dr."}},
+		{`U.S."`, []string{"U.S.", `"`}},
+	}
+	for _, tt := range tests {
+		if got := splitPunct(tt.field); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPunct(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestDetokenizer(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"Hello", ",", "world", "."}, "Hello, world."},
+		{[]string{`"`, "Hi", ",", `"`, "she", "said", "."}, `"Hi," she said.`},
+		{[]string{"a", "(", "b", ")", "c", "..."}, "a (b) c..."},
+		{[]string{"¿", "qué", "?"}, "¿qué?"},
+		{[]string{",", "start"}, ", start"},
+	}
+	for _, tt := range tests {
+		var d detokenizer
+		var b strings.Builder
+		for _, word := range tt.words {
+			b.WriteString(d.space(word))
+			b.WriteString(word)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("detokenized %q = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestWithPunctuation(t *testing.T) {
+	c := NewChain(2, WithPunctuation())
+	text := `"Hello, world," she said. (Quietly.)`
+	mustBuild(t, c, text)
+
+	if got := c.Count(Prefix[string]{"Hello", ","}, "world"); got != 1 {
+		t.Errorf("Count(Hello ,, world) = %d, want 1", got)
+	}
+	got, err := c.GenerateString(100)
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if got != text {
+		t.Errorf("GenerateString = %q, want %q", got, text)
+	}
+}
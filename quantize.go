@@ -0,0 +1,48 @@
+package markov
+
+import "math"
+
+// Counts up to quantizeExact are quantized exactly, and larger ones to the
+// nearest of the counts growing by a factor of quantizeBase from it, so that
+// no count is off by more than 6% and the largest bucket stands for about
+// 10^11.
+const (
+	quantizeExact = 16
+	quantizeBase  = 1.1
+)
+
+// A SaveOption configures how SaveFrozen writes a Chain.
+type SaveOption func(*saveConfig)
+
+// saveConfig holds the settings of SaveOptions.
+type saveConfig struct {
+	quantize bool
+}
+
+// WithQuantizedCounts makes SaveFrozen write each count in a single byte
+// rather than eight, as a bucket on a logarithmic scale, which cuts the size
+// of most models by half or more. Counts up to 16 are kept exactly, and
+// larger ones rounded to within 6%, which changes the probabilities of the
+// suffixes generated hardly at all.
+func WithQuantizedCounts() SaveOption {
+	return func(cfg *saveConfig) {
+		cfg.quantize = true
+	}
+}
+
+// quantizeCount returns the bucket of the count n, which must be positive.
+func quantizeCount(n int) uint8 {
+	if n <= quantizeExact {
+		return uint8(n)
+	}
+	b := quantizeExact + math.Round(math.Log(float64(n)/quantizeExact)/math.Log(quantizeBase))
+	return uint8(min(b, math.MaxUint8))
+}
+
+// dequantizeCount returns the count the bucket b stands for.
+func dequantizeCount(b uint8) int {
+	if b <= quantizeExact {
+		return int(b)
+	}
+	return int(math.Round(quantizeExact * math.Pow(quantizeBase, float64(b-quantizeExact))))
+}
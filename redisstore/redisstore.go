@@ -0,0 +1,225 @@
+// Package redisstore keeps Markov chains of package markov in Redis, shared
+// by every process that opens them.
+package redisstore
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/saclark/markov"
+	"github.com/saclark/markov/internal/batch"
+)
+
+// intern atomically returns the ID of the word ARGV[1] in the hash of IDs
+// KEYS[1], assigning it the next ID, counting from ARGV[2], if it has none,
+// and recording it in the hash of words KEYS[2].
+var intern = redis.NewScript(`
+local id = redis.call('HGET', KEYS[1], ARGV[1])
+if id then
+	return tonumber(id)
+end
+id = redis.call('HLEN', KEYS[1]) + tonumber(ARGV[2])
+redis.call('HSET', KEYS[1], ARGV[1], id)
+redis.call('HSET', KEYS[2], id, ARGV[1])
+return id
+`)
+
+// OpenChain is like markov.OpenSQLChain but keeps the Chain in Redis, under
+// keys starting with name, so that any number of processes can generate
+// from and train the same Chain at once. The Chain is kept in these keys:
+//
+//	name:meta      its prefix length and options
+//	name:ids       a hash of each word to its ID
+//	name:words     a hash of each ID to its word
+//	name:prefixes  a set of the keys of its prefixes
+//	name:p:<key>   for each prefix, a hash of each suffix's ID to its count
+//
+// Build sends the counts it observes to Redis in pipelined batches, and new
+// words as they are observed. Words added by other processes are learned as
+// they are generated. Suffixes of a prefix are ordered by ID rather than by
+// first observation.
+func OpenChain(client redis.UniversalClient, name string, prefixLen int, opts ...markov.Option) (*markov.Chain[string], error) {
+	return Open[string](client, name, prefixLen, opts...)
+}
+
+// Open is like OpenChain but returns a Chain of words of type T, which must
+// be strings or integers.
+func Open[T comparable](client redis.UniversalClient, name string, prefixLen int, opts ...markov.Option) (*markov.Chain[T], error) {
+	return markov.Open[T](New(client, name), prefixLen, opts...)
+}
+
+// New returns a Store that keeps a Chain in Redis under keys starting with
+// name, as OpenChain does.
+func New(client redis.UniversalClient, name string) markov.Store {
+	return &store{client: client, name: name}
+}
+
+// store is the Store returned by New.
+type store struct {
+	client  redis.UniversalClient
+	name    string
+	pending batch.Batch
+}
+
+// key returns the Redis key of the given part of the Chain.
+func (s *store) key(part string) string {
+	return s.name + ":" + part
+}
+
+// prefixKey returns the Redis key of the prefix with key k.
+func (s *store) prefixKey(k string) string {
+	return s.name + ":p:" + k
+}
+
+func (s *store) Get(prefix string) ([]markov.Suffix, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	fields, err := s.client.HGetAll(context.Background(), s.prefixKey(prefix)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeSuffixes(fields)
+}
+
+func (s *store) Increment(prefix string, id uint32, n int) error {
+	return s.pending.Add(prefix, id, n, s.write)
+}
+
+func (s *store) Intern(word []byte, next uint32) (uint32, error) {
+	keys := []string{s.key("ids"), s.key("words")}
+	id, err := intern.Run(context.Background(), s.client, keys, word, markov.FirstID).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if id < int64(markov.FirstID) || id >= math.MaxUint32 {
+		return 0, fmt.Errorf("word ID %d out of range", id)
+	}
+	return uint32(id), nil
+}
+
+func (s *store) Words(from uint32) ([][]byte, error) {
+	ctx := context.Background()
+	n, err := s.client.HLen(ctx, s.key("words")).Result()
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for i := int64(max(from, markov.FirstID)); i < n+int64(markov.FirstID); i++ {
+		fields = append(fields, strconv.FormatInt(i, 10))
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	values, err := s.client.HMGet(ctx, s.key("words"), fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	words := make([][]byte, len(values))
+	for i, v := range values {
+		word, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("missing word with ID %s", fields[i])
+		}
+		words[i] = []byte(word)
+	}
+	return words, nil
+}
+
+func (s *store) Meta() ([]byte, error) {
+	data, err := s.client.Get(context.Background(), s.key("meta")).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *store) SetMeta(data []byte) error {
+	return s.client.Set(context.Background(), s.key("meta"), data, 0).Err()
+}
+
+func (s *store) Remove(prefix string) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.prefixKey(prefix))
+		pipe.SRem(ctx, s.key("prefixes"), prefix)
+		return nil
+	})
+	return err
+}
+
+func (s *store) Iterate(fn func(prefix string, suffixes []markov.Suffix) bool) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	iter := s.client.SScan(ctx, s.key("prefixes"), 0, "", 0).Iterator()
+	for iter.Next(ctx) {
+		k := iter.Val()
+		if seen[k] {
+			continue // SSCAN may return a member more than once
+		}
+		seen[k] = true
+		fields, err := s.client.HGetAll(ctx, s.prefixKey(k)).Result()
+		if err != nil {
+			return err
+		}
+		list, err := decodeSuffixes(fields)
+		if err != nil {
+			return err
+		}
+		if list != nil && !fn(k, list) {
+			return nil
+		}
+	}
+	return iter.Err()
+}
+
+func (s *store) Flush() error {
+	return s.pending.Flush(s.write)
+}
+
+// write sends the observations of b to Redis in a single pipeline.
+func (s *store) write(b *batch.Batch) error {
+	ctx := context.Background()
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, o := range b.Observations {
+			pipe.HIncrBy(ctx, s.prefixKey(o.Prefix), strconv.FormatUint(uint64(o.ID), 10), int64(b.Counts[i]))
+			pipe.SAdd(ctx, s.key("prefixes"), o.Prefix)
+		}
+		return nil
+	})
+	return err
+}
+
+// decodeSuffixes returns the suffixes of a prefix stored in Redis as the
+// hash fields, ordered by ID, or nil if there are none.
+func decodeSuffixes(fields map[string]string) ([]markov.Suffix, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	list := make([]markov.Suffix, 0, len(fields))
+	for field, value := range fields {
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil || id >= math.MaxUint32 {
+			return nil, fmt.Errorf("invalid suffix ID %q", field)
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("invalid count %q of suffix %d", value, id)
+		}
+		list = append(list, markov.Suffix{ID: uint32(id), Count: count})
+	}
+	slices.SortFunc(list, func(a, b markov.Suffix) int { return cmp.Compare(a.ID, b.ID) })
+	return list, nil
+}
@@ -0,0 +1,82 @@
+package redisstore
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/saclark/markov"
+)
+
+func mustBuild(t *testing.T, c *markov.Chain[string], text string) {
+	t.Helper()
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build(%q) error: %v", text, err)
+	}
+}
+
+func TestOpenChain(t *testing.T) {
+	server := miniredis.RunT(t)
+	open := func() *markov.Chain[string] {
+		t.Helper()
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		c, err := OpenChain(client, "test", 1, markov.WithMarkers())
+		if err != nil {
+			t.Fatalf("OpenChain error: %v", err)
+		}
+		return c
+	}
+
+	// Two processes share and train one chain.
+	a, b := open(), open()
+	mustBuild(t, a, "The cat sat.")
+	mustBuild(t, b, "The dog ran.")
+
+	mem := markov.NewChain(1, markov.WithMarkers())
+	mustBuild(t, mem, "The cat sat.")
+	mustBuild(t, mem, "The dog ran.")
+	if !b.Equal(mem) {
+		t.Error("chain differs from chain built in memory")
+	}
+
+	// a learns the words b added as it generates them.
+	texts := make(map[string]bool)
+	rng := rand.New(rand.NewSource(1))
+	for range 20 {
+		text, err := a.GenerateString(10, markov.WithRand(rng))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		texts[text] = true
+	}
+	if wantTexts := map[string]bool{"The cat sat.": true, "The dog ran.": true}; !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("generated %v, want %v", texts, wantTexts)
+	}
+	if got := a.Count(markov.Prefix[string]{"dog"}, "ran."); got != 1 {
+		t.Errorf("Count({dog}, ran.) = %d, want 1", got)
+	}
+
+	if c := open(); !c.Equal(mem) {
+		t.Error("reopened chain differs from chain built in memory")
+	}
+}
+
+func TestOpenChainPrefixLength(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	if _, err := OpenChain(client, "test", 2); err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	if _, err := OpenChain(client, "test", 3); err == nil {
+		t.Error("OpenChain with another prefix length succeeded, want error")
+	}
+	if _, err := OpenChain(client, "other", 3); err != nil {
+		t.Errorf("OpenChain of another name error: %v", err)
+	}
+}
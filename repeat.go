@@ -0,0 +1,52 @@
+package markov
+
+import "slices"
+
+// WithRepetitionPenalty discourages Generate from falling into loops such as
+// "and the and the". A suffix that would complete a sequence of ngram words
+// already generated within the last window words has its weight multiplied
+// by penalty, so a penalty of 0 forbids such repeats entirely and a penalty
+// of 1 has no effect. When every suffix of a prefix is forbidden, generation
+// ends. The penalty applies to sampled and greedy generation but not to beam
+// search. ngram must be positive, window at least ngram, and penalty in
+// [0, 1].
+func WithRepetitionPenalty(ngram, window int, penalty float64) GenerateOption {
+	return func(cfg *generateConfig) {
+		cfg.repeatNgram = ngram
+		cfg.repeatWindow = window
+		cfg.repeatPenalty = penalty
+	}
+}
+
+// penalizesRepeats reports whether cfg applies a repetition penalty.
+func (cfg *generateConfig) penalizesRepeats() bool {
+	return cfg.repeatNgram > 0 && cfg.repeatPenalty < 1
+}
+
+// penalizeRepeats scales the weights w of s's words by the repetition
+// penalty for each word that would repeat an n-gram found in recent, the IDs
+// of the most recently generated words.
+func (cfg *generateConfig) penalizeRepeats(w []float64, s *suffixes, recent []uint32) {
+	if len(recent) > cfg.repeatWindow {
+		recent = recent[len(recent)-cfg.repeatWindow:]
+	}
+
+	// Each earlier occurrence of the last ngram-1 words marks the word that
+	// followed it as a repeat.
+	tail := recent[max(len(recent)-(cfg.repeatNgram-1), 0):]
+	if len(tail) < cfg.repeatNgram-1 {
+		return
+	}
+	repeats := make(map[int]bool)
+	for j := 0; j+len(tail) < len(recent); j++ {
+		if !slices.Equal(recent[j:j+len(tail)], tail) {
+			continue
+		}
+		if i, ok := s.find(recent[j+len(tail)]); ok {
+			repeats[i] = true
+		}
+	}
+	for i := range repeats {
+		w[i] *= cfg.repeatPenalty
+	}
+}
@@ -0,0 +1,82 @@
+package markov
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRepetitionPenaltyForbidsLoops(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "the cat and the dog and the cat and the bird")
+
+	var b strings.Builder
+	if err := c.Generate(&b, 20, WithGreedy(), WithRepetitionPenalty(2, 10, 0)); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	// Once "the cat" has been generated, "the" must be followed by the next
+	// most frequent word, and once "and the" has been generated, "and" has
+	// no suffix left to pick.
+	if got, want := b.String(), "the cat and the dog and"; got != want {
+		t.Errorf("Generate = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateRepetitionPenaltyWindow(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c a b")
+
+	// Forbidding repeated unigrams within a window of one word only forbids
+	// generating the same word twice in a row.
+	for seed := int64(1); seed <= 10; seed++ {
+		var b strings.Builder
+		err := c.Generate(&b, 30,
+			WithRand(rand.New(rand.NewSource(seed))),
+			WithRepetitionPenalty(1, 1, 0))
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		words := strings.Fields(b.String())
+		for i := 1; i < len(words); i++ {
+			if words[i] == words[i-1] {
+				t.Fatalf("seed %d: %q repeats %q", seed, b.String(), words[i])
+			}
+		}
+	}
+}
+
+func TestPenalizeRepeats(t *testing.T) {
+	s := newSuffixes()
+	for _, word := range []uint32{'a', 'b', 'c'} {
+		s.add(word)
+	}
+
+	cfg, err := newGenerateConfig([]GenerateOption{WithRepetitionPenalty(2, 5, 0.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "x a" and "x c" occurred, but "x b" only outside the window.
+	w := []float64{1, 1, 1}
+	cfg.penalizeRepeats(w, s, []uint32{'x', 'b', 'x', 'a', 'x', 'c', 'x'})
+	if want := []float64{0.5, 1, 0.5}; !slices.Equal(w, want) {
+		t.Errorf("weights = %v, want %v", w, want)
+	}
+}
+
+func TestGenerateInvalidRepetitionPenalty(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b c")
+	for _, opt := range []GenerateOption{
+		WithRepetitionPenalty(-1, 4, 0.5),
+		WithRepetitionPenalty(3, 2, 0.5),
+		WithRepetitionPenalty(2, 4, -0.5),
+		WithRepetitionPenalty(2, 4, 1.5),
+	} {
+		if err := c.Generate(&strings.Builder{}, 10, opt); err == nil {
+			t.Error("Generate with invalid repetition penalty succeeded, want error")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errReversed is returned when building a Chain returned by Reverse.
+var errReversed = errors.New("markov: cannot build a reversed chain; build the chain it reverses and reverse it again")
+
+// Reverse returns a new Chain of the texts the Chain was built from read
+// backwards, from their last words to their first, whose counts are derived
+// from the Chain's without reading the texts again, so that text can be
+// generated backwards from how it ends. Given WithPrompt the words a text
+// should end with, such as "forever", the reversed Chain's Generate writes
+// words leading up to them, in reading order, starting at the beginning of
+// a text or sentence. A prompt is read in reading order too, and words are
+// written only once all are generated.
+//
+// The reversed Chain has the Chain's options and words, and is kept in
+// memory whatever Store holds the Chain. It cannot be built further, since
+// the texts it would read are not backwards, nor merged with a Chain that
+// is not reversed. Reverse returns an error unless the Chain was created
+// WithMarkers, without which it does not know how its texts end, or if it
+// was created WithSkipGram.
+func (c *Chain[T]) Reverse() (*Chain[T], error) {
+	if !c.markers {
+		return nil, errors.New("markov: cannot reverse a chain without markers")
+	}
+	if c.skipGram != nil {
+		return nil, errors.New("markov: cannot reverse a chain skipping words")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rev, err := c.emptyWithWords(c.prefixLen)
+	if err != nil {
+		return nil, err
+	}
+	rev.reversed = !c.reversed
+
+	// Each word following a prefix makes a gram of prefixLen+1 words, which
+	// read backwards is a word following a prefix of the reversed Chain.
+	// The beginning of a text is the end of a reversed one, and a gram
+	// ending a text also holds the start of the reversed text.
+	n := c.prefixLen
+	gram := make(Prefix[uint32], n+1)
+	window := make(Prefix[uint32], n)
+	var addErr error
+	add := func(id uint32, count int) {
+		if id == beginID {
+			id = endID
+		}
+		addErr = rev.addCount(window, id, count)
+	}
+	err = c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			return true
+		}
+		copy(gram, unkey(k))
+		for i, id := range s.words {
+			gram[n] = id
+			if id == endID || c.isBreak(c.vocab.words[id]) {
+				// The reversed text starts from the last words of this one.
+				clear(window)
+				for j := n - 1; j >= 0 && addErr == nil; j-- {
+					add(gram[j], s.counts[i])
+					if gram[j] == beginID {
+						break
+					}
+					window.Shift(gram[j])
+				}
+				continue
+			}
+			if n > 1 && gram[1] == beginID {
+				// More of the start of the text than a gram reversed holds.
+				continue
+			}
+			for j := range window {
+				window[j] = gram[n-j]
+			}
+			if add(gram[0], s.counts[i]); addErr != nil {
+				break
+			}
+		}
+		return addErr == nil
+	})
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("markov: reversing chain: %w", err)
+	}
+	return rev, nil
+}
@@ -0,0 +1,110 @@
+package markov
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChainReverse(t *testing.T) {
+	texts := []string{"the cat sat on the mat", "the dog sat on the cat", "a cat", "cat"}
+	for name, opts := range map[string][]Option{
+		"markers": {WithMarkers()},
+		"backoff": {WithMarkers(), WithBackoff()},
+	} {
+		for _, prefixLen := range []int{1, 2, 3} {
+			c := NewChain(prefixLen, opts...)
+			want := NewChain(prefixLen, opts...)
+			for _, text := range texts {
+				mustBuild(t, c, text)
+				words := strings.Fields(text)
+				slices.Reverse(words)
+				mustBuild(t, want, strings.Join(words, " "))
+			}
+
+			rev, err := c.Reverse()
+			if err != nil {
+				t.Fatalf("%s: Reverse error: %v", name, err)
+			}
+			if got, want := chainCounts(rev), chainCounts(want); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Reverse of prefix length %d = %v, want %v", name, prefixLen, got, want)
+			}
+			again, err := rev.Reverse()
+			if err != nil {
+				t.Fatalf("%s: Reverse of reversed chain error: %v", name, err)
+			}
+			if got, want := chainCounts(again), chainCounts(c); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Reverse of reversed chain of prefix length %d = %v, want %v", name, prefixLen, got, want)
+			}
+		}
+	}
+}
+
+func TestChainReverseGenerate(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "i will love you forever")
+	rev, err := c.Reverse()
+	if err != nil {
+		t.Fatalf("Reverse error: %v", err)
+	}
+	if got, err := rev.GenerateString(10, WithPrompt("you forever")); err != nil || got != "i will love" {
+		t.Errorf("GenerateString(WithPrompt(you forever)) = %q, %v, want %q", got, err, "i will love")
+	}
+	if got, err := rev.GenerateString(10, WithSentenceCase()); err != nil || got != "I will love you forever" {
+		t.Errorf("GenerateString(WithSentenceCase()) = %q, %v, want %q", got, err, "I will love you forever")
+	}
+	if got := slices.Collect(rev.Tokens(2)); !slices.Equal(got, []string{"you", "forever"}) {
+		t.Errorf("Tokens(2) = %q, want %q", got, []string{"you", "forever"})
+	}
+	if got, err := rev.GenerateString(10, WithStop("love")); err != nil || got != "love you forever" {
+		t.Errorf("GenerateString(WithStop(love)) = %q, %v, want %q", got, err, "love you forever")
+	}
+
+	// A prompt ending a sentence is where the text generated backwards
+	// ends, not where it starts.
+	c = NewChain(2, WithMarkers())
+	mustBuild(t, c, "Hello there. We were young forever.")
+	rev, err = c.Reverse()
+	if err != nil {
+		t.Fatalf("Reverse error: %v", err)
+	}
+	if got, err := rev.GenerateString(10, WithPrompt("forever.")); err != nil || got != "We were young" {
+		t.Errorf("GenerateString(WithPrompt(forever.)) = %q, %v, want %q", got, err, "We were young")
+	}
+}
+
+func TestChainReverseInvalid(t *testing.T) {
+	if _, err := NewChain(2).Reverse(); err == nil {
+		t.Error("Reverse of a chain without markers succeeded, want error")
+	}
+	if _, err := NewChain(2, WithMarkers(), WithSkipGram(2)).Reverse(); err == nil {
+		t.Error("Reverse of a chain skipping words succeeded, want error")
+	}
+
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, "a b c")
+	rev, err := c.Reverse()
+	if err != nil {
+		t.Fatalf("Reverse error: %v", err)
+	}
+	if err := rev.Build(strings.NewReader("c b a")); err == nil {
+		t.Error("Build of a reversed chain succeeded, want error")
+	}
+	if err := c.Merge(rev); err == nil {
+		t.Error("Merge of a reversed chain succeeded, want error")
+	}
+
+	var buf bytes.Buffer
+	if err := rev.SaveBinary(&buf); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loaded, err := LoadChain(&buf)
+	if err != nil {
+		t.Fatalf("LoadChain error: %v", err)
+	}
+	if got, err := loaded.GenerateString(10, WithPrompt("c")); err != nil || got != "a b" {
+		t.Errorf("loaded GenerateString(WithPrompt(c)) = %q, %v, want %q", got, err, "a b")
+	}
+}
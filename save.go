@@ -0,0 +1,303 @@
+package markov
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// model is the content of a Chain as it is saved.
+type model[T comparable] struct {
+	PrefixLen int
+	Options   savedOptions
+
+	// Words holds every word of the Chain, indexed by ID.
+	Words []T
+
+	// Prefixes holds every prefix of the Chain, ordered by key.
+	Prefixes []savedPrefix
+}
+
+// savedPrefix is a prefix of a Chain and its suffixes, as saved.
+type savedPrefix struct {
+	Words  []uint32 // the IDs of the prefix's words
+	Next   []uint32 // the IDs of its suffixes, in first-observed order
+	Counts []int
+}
+
+// savedOptions holds the options of a Chain that are saved with it. Options
+// holding functions, such as those set WithTokenizer, cannot be saved.
+type savedOptions struct {
+	Mode          Mode     `json:"mode"`
+	Punctuation   bool     `json:"punctuation,omitempty"`
+	Segmented     bool     `json:"segmented,omitempty"`
+	Markers       bool     `json:"markers,omitempty"`
+	SentenceReset bool     `json:"sentenceReset,omitempty"`
+	Normalization string   `json:"normalization,omitempty"`
+	Lowercase     bool     `json:"lowercase,omitempty"`
+	StopWords     []string `json:"stopWords,omitempty"`
+	Collapse      bool     `json:"collapseStopWords,omitempty"`
+	Placeholder   string   `json:"placeholder,omitempty"`
+	Backoff       bool     `json:"backoff,omitempty"`
+	SkipGram      []int    `json:"skipGram,omitempty"`
+	Reversed      bool     `json:"reversed,omitempty"`
+}
+
+// formNames holds the name of each Unicode normalization form.
+var formNames = []string{
+	norm.NFC:  "NFC",
+	norm.NFD:  "NFD",
+	norm.NFKC: "NFKC",
+	norm.NFKD: "NFKD",
+}
+
+// Save writes the Chain to w in a form LoadChain, or Load, reads back, so
+// that a Chain can be built once and generated from many times.
+//
+// The Chain's options are saved with it, except for those holding
+// functions: WithTokenizer, WithSegmenter's SegmentFunc and
+// WithTokenTransform. Those must be given to LoadChain again. The tables
+// built by Compile are not saved either.
+func (c *Chain[T]) Save(w io.Writer) error {
+	m, err := c.model()
+	if err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(&m); err != nil {
+		return fmt.Errorf("markov: saving chain: %w", err)
+	}
+	return nil
+}
+
+// LoadChain reads a Chain of strings written by Save, SaveBinary or
+// SaveCompressed from r. The saved options are applied first, followed by
+// opts.
+func LoadChain(r io.Reader, opts ...Option) (*Chain[string], error) {
+	return Load[string](r, opts...)
+}
+
+// Load is like LoadChain but reads a Chain of words of type T, which must be
+// the type of the saved Chain's words.
+func Load[T comparable](r io.Reader, opts ...Option) (*Chain[T], error) {
+	return LoadContext[T](context.Background(), r, opts...)
+}
+
+// LoadChainContext is like LoadChain but stops reading and returns ctx.Err()
+// if ctx is done before the Chain is loaded.
+//
+// A Chain written by SaveBinary or SaveCompressed is loaded a prefix at a
+// time, so that loading even a huge Chain needs little more memory than the
+// Chain itself. A Chain written by Save is decoded all at once.
+func LoadChainContext(ctx context.Context, r io.Reader, opts ...Option) (*Chain[string], error) {
+	return LoadContext[string](ctx, r, opts...)
+}
+
+// LoadContext is like LoadChainContext but reads a Chain of words of type T.
+func LoadContext[T comparable](ctx context.Context, r io.Reader, opts ...Option) (*Chain[T], error) {
+	br, release, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("markov: loading chain: %w", err)
+	}
+	defer release()
+
+	if magic, _ := br.Peek(len(binaryMagic)); string(magic) == binaryMagic {
+		return loadBinary[T](ctx, br, opts)
+	}
+
+	var m model[T]
+	if err := gob.NewDecoder(br).Decode(&m); err != nil {
+		return nil, fmt.Errorf("markov: loading chain: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.chain(opts)
+}
+
+// model returns the content of the Chain.
+func (c *Chain[T]) model() (model[T], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m := model[T]{
+		PrefixLen: c.prefixLen,
+		Options:   c.options.saved(),
+		Words:     slices.Clone(c.vocab.words),
+	}
+	err := c.store.each(func(k string, s *suffixes) bool {
+		m.Prefixes = append(m.Prefixes, savedPrefix{
+			Words:  unkey(k),
+			Next:   slices.Clone(s.words),
+			Counts: slices.Clone(s.counts),
+		})
+		return true
+	})
+	slices.SortFunc(m.Prefixes, func(a, b savedPrefix) int {
+		return strings.Compare(key(a.Words), key(b.Words))
+	})
+	return m, err
+}
+
+// chain returns the Chain m describes, configured by its options followed
+// by opts, or an error if m is inconsistent.
+func (m *model[T]) chain(opts []Option) (*Chain[T], error) {
+	c, err := m.empty(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range m.Prefixes {
+		if err := c.insert(p); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// empty returns a Chain of the prefix length, options and words of m, but
+// none of its prefixes, configured by its options followed by opts.
+func (m *model[T]) empty(opts []Option) (*Chain[T], error) {
+	if m.PrefixLen < 1 {
+		return nil, fmt.Errorf("markov: invalid prefix length %d", m.PrefixLen)
+	}
+	if len(m.Words) < int(firstID) {
+		return nil, errors.New("markov: missing reserved words")
+	}
+
+	saved, err := m.Options.options()
+	if err != nil {
+		return nil, err
+	}
+	c := New[T](m.PrefixLen, append(saved, opts...)...)
+	for i, word := range m.Words[firstID:] {
+		if id := c.vocab.intern(word); id != firstID+uint32(i) {
+			return nil, fmt.Errorf("markov: duplicate word %v", word)
+		}
+	}
+	return c, nil
+}
+
+// insert adds the saved prefix p and its suffixes to c, whose vocabulary
+// must be complete, or returns an error if p is inconsistent. It does not
+// lock c.
+func (c *Chain[T]) insert(p savedPrefix) error {
+	valid := func(id uint32) bool { return int(id) < len(c.vocab.words) }
+	if len(p.Words) != c.prefixLen || !all(p.Words, valid) {
+		return fmt.Errorf("markov: invalid prefix %v", p.Words)
+	}
+	if len(p.Next) != len(p.Counts) || len(p.Next) == 0 || !all(p.Next, valid) {
+		return fmt.Errorf("markov: invalid suffixes of prefix %v", p.Words)
+	}
+	k := key(p.Words)
+	if s, err := c.store.get(k); err != nil {
+		return err
+	} else if s != nil {
+		return fmt.Errorf("markov: duplicate prefix %v", p.Words)
+	}
+	s := newSuffixes()
+	for i, id := range p.Next {
+		if p.Counts[i] < 1 {
+			return fmt.Errorf("markov: invalid count %d", p.Counts[i])
+		}
+		if _, ok := s.find(id); ok {
+			return fmt.Errorf("markov: duplicate suffix %d of prefix %v", id, p.Words)
+		}
+		s.addCount(id, p.Counts[i])
+	}
+	return c.store.put(k, s)
+}
+
+// all reports whether f holds for every element of s.
+func all[E any](s []E, f func(E) bool) bool {
+	for _, e := range s {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// saved returns the options of o that can be saved.
+func (o *options) saved() savedOptions {
+	s := savedOptions{
+		Mode:          o.mode,
+		Punctuation:   o.punctuation,
+		Segmented:     o.segmented,
+		Markers:       o.markers,
+		SentenceReset: o.sentenceReset,
+		Lowercase:     o.lowercase,
+		Collapse:      o.collapse,
+		Placeholder:   o.placeholder,
+		Backoff:       o.backoff,
+		SkipGram:      slices.Clone(o.skipGram),
+		Reversed:      o.reversed,
+	}
+	if o.normalize {
+		s.Normalization = formNames[o.form]
+	}
+	for word := range o.stopWords {
+		s.StopWords = append(s.StopWords, word)
+	}
+	slices.Sort(s.StopWords)
+	return s
+}
+
+// options returns the Options that restore s, or an error if s is invalid.
+func (s savedOptions) options() ([]Option, error) {
+	opts := []Option{WithMode(s.Mode)}
+	if s.Punctuation {
+		opts = append(opts, WithPunctuation())
+	}
+	if s.Segmented {
+		opts = append(opts, WithSegmenter(nil))
+	}
+	if s.Markers {
+		opts = append(opts, WithMarkers())
+	}
+	if s.SentenceReset {
+		opts = append(opts, WithSentenceReset())
+	}
+	if s.Normalization != "" {
+		i := slices.Index(formNames, s.Normalization)
+		if i < 0 {
+			return nil, fmt.Errorf("markov: unknown normalization form %q", s.Normalization)
+		}
+		opts = append(opts, WithNormalization(norm.Form(i)))
+	}
+	if s.Lowercase {
+		opts = append(opts, WithLowercase())
+	}
+	if s.Backoff {
+		opts = append(opts, WithBackoff())
+	}
+	if len(s.SkipGram) > 0 {
+		opts = append(opts, WithSkipGram(s.SkipGram...))
+	}
+	if s.Reversed {
+		opts = append(opts, func(o *options) { o.reversed = true })
+	}
+	if s.Collapse {
+		opts = append(opts, WithCollapsedStopWords(s.Placeholder, s.StopWords...))
+	} else if len(s.StopWords) > 0 {
+		opts = append(opts, WithStopWords(s.StopWords...))
+	}
+	return opts, nil
+}
+
+// replace replaces the content and saved options of c with those of loaded,
+// keeping the options of c that cannot be saved. c.mu must be held for
+// writing.
+func (c *Chain[T]) replace(loaded *Chain[T]) {
+	tokenizer, transforms := c.tokenizer, c.transforms
+	c.store, c.vocab, c.prefixLen, c.options = loaded.store, loaded.vocab, loaded.prefixLen, loaded.options
+	if c.tokenizer == nil {
+		c.tokenizer = tokenizer
+	}
+	c.transforms = transforms
+}
@@ -0,0 +1,126 @@
+package markov
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestSaveLoadChain(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithLowercase(), WithNormalization(norm.NFC), WithStopWords("a"))
+	mustBuild(t, c, "The quick brown fox jumps. A lazy dog sleeps! The dog jumps.")
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	loaded, err := LoadChain(&buf)
+	if err != nil {
+		t.Fatalf("LoadChain error: %v", err)
+	}
+
+	if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded chain = %v, want %v", got, want)
+	}
+	if got, want := loaded.options.saved(), c.options.saved(); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded options = %+v, want %+v", got, want)
+	}
+	generate := func(c *Chain[string]) string {
+		text, err := c.GenerateString(20, WithRand(rand.New(rand.NewSource(1))))
+		if err != nil {
+			t.Fatalf("GenerateString error: %v", err)
+		}
+		return text
+	}
+	if got, want := generate(loaded), generate(c); got != want {
+		t.Errorf("loaded chain generated %q, want %q", got, want)
+	}
+
+	// The loaded Chain keeps its vocabulary consistent as it grows.
+	mustBuild(t, loaded, "A fox sleeps.")
+	if got := loaded.Count(Prefix[string]{"fox"}, "sleeps."); got != 1 {
+		t.Errorf("Count(fox, sleeps.) after Build = %d, want 1", got)
+	}
+}
+
+func TestLoadGeneric(t *testing.T) {
+	c := New[int](1)
+	c.Add(1, 2, 3, 1, 2, 4)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	saved := buf.String()
+
+	loaded, err := Load[int](strings.NewReader(saved))
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if got := loaded.Count(Prefix[int]{2}, 4); got != 1 {
+		t.Errorf("Count({2}, 4) = %d, want 1", got)
+	}
+
+	if _, err := LoadChain(strings.NewReader(saved)); err == nil {
+		t.Error("LoadChain of a Chain of ints succeeded, want error")
+	}
+}
+
+func TestLoadChainInvalid(t *testing.T) {
+	if _, err := LoadChain(strings.NewReader("not a chain")); err == nil {
+		t.Error("LoadChain of garbage succeeded, want error")
+	}
+
+	tests := []model[string]{
+		{PrefixLen: 0, Words: []string{Begin, End}},
+		{PrefixLen: 1, Words: []string{Begin}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a", "a"}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a"}, Prefixes: []savedPrefix{{Words: []uint32{0, 0}, Next: []uint32{2}, Counts: []int{1}}}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a"}, Prefixes: []savedPrefix{{Words: []uint32{0}, Next: []uint32{3}, Counts: []int{1}}}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a"}, Prefixes: []savedPrefix{{Words: []uint32{0}, Next: []uint32{2}, Counts: []int{0}}}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a"}, Prefixes: []savedPrefix{{Words: []uint32{0}, Next: []uint32{2, 2}, Counts: []int{1, 1}}}},
+		{PrefixLen: 1, Words: []string{Begin, End, "a"}, Prefixes: []savedPrefix{
+			{Words: []uint32{0}, Next: []uint32{2}, Counts: []int{1}},
+			{Words: []uint32{0}, Next: []uint32{2}, Counts: []int{1}},
+		}},
+	}
+	for i, m := range tests {
+		if _, err := m.chain(nil); err == nil {
+			t.Errorf("%d: loading %+v succeeded, want error", i, m)
+		}
+	}
+}
+
+func TestLoadChainContext(t *testing.T) {
+	c := NewChain(2)
+	var words []string
+	for i := range 5000 {
+		words = append(words, strconv.Itoa(i%997), strconv.Itoa(i%991))
+	}
+	mustBuild(t, c, strings.Join(words, " "))
+	var buf bytes.Buffer
+	if err := c.SaveCompressed(&buf, Zstd); err != nil {
+		t.Fatalf("SaveCompressed error: %v", err)
+	}
+
+	loaded, err := LoadChainContext(context.Background(), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadChainContext error: %v", err)
+	}
+	if got, want := chainCounts(loaded), chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded chain differs from saved chain")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LoadChainContext(ctx, bytes.NewReader(buf.Bytes())); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadChainContext with canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,150 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+)
+
+// A ScoreOption configures how Perplexity and Score estimate the
+// probabilities of the words they score.
+type ScoreOption func(*scoreConfig)
+
+// scoreConfig holds the settings applied by ScoreOptions.
+type scoreConfig struct {
+	unseen   float64
+	smoother Smoother
+}
+
+// newScoreConfig returns the scoreConfig described by opts, or an error if
+// they are invalid.
+func newScoreConfig(opts []ScoreOption) (*scoreConfig, error) {
+	cfg := &scoreConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if !(cfg.unseen >= 0 && cfg.unseen < 1) {
+		return nil, errors.New("markov: probability of unseen words must be in [0, 1)")
+	}
+	return cfg, nil
+}
+
+// WithUnseenProbability gives every word never observed following its
+// prefix the probability p, rather than the probability zero that makes the
+// perplexity of any text containing it infinite. The probabilities of the
+// words observed are left as they are, so p should be small. Given
+// WithSmoother too, p is the probability of the words to which the Smoother
+// gives probability zero.
+func WithUnseenProbability(p float64) ScoreOption {
+	return func(cfg *scoreConfig) {
+		cfg.unseen = p
+	}
+}
+
+// Perplexity returns the perplexity of the Chain on the text read from r:
+// the inverse of the geometric mean of the probabilities the Chain gives
+// each of its words following the words before it, counting the end of each
+// text or sentence if the Chain has markers. The lower it is, the better the
+// Chain predicts the text, so the perplexities of Chains on the same
+// held-out text compare how well they model it.
+//
+// The text is split into words as Build would split it. The perplexity is
+// infinite if the Chain never observed some word following its prefix,
+// unless WithSmoother or WithUnseenProbability is given. Perplexity returns
+// an error if the text has no words.
+func (c *Chain[T]) Perplexity(r io.Reader, opts ...ScoreOption) (float64, error) {
+	cfg, err := newScoreConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	sum, n, err := c.score(c.tokenize(r), cfg)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, errors.New("markov: no words to score")
+	}
+	return math.Exp(-sum / float64(n)), nil
+}
+
+// Score returns the natural logarithm of the probability of the Chain
+// generating tokens as a text of their own, or as the start of one if the
+// Chain has no markers: the sum of the log probabilities of each token
+// following the tokens before it, and of the end of each text or sentence if
+// the Chain has markers. Ranking candidate sentences by their scores ranks
+// them by how likely the Chain finds them, and a line scoring far lower than
+// most stands out as anomalous.
+//
+// The score is -Inf if the Chain never observed some token following its
+// prefix, unless WithSmoother or WithUnseenProbability is given, and 0 for
+// no tokens. It is NaN if opts are invalid or the Chain's store fails.
+func (c *Chain[T]) Score(tokens []T, opts ...ScoreOption) float64 {
+	cfg, err := newScoreConfig(opts)
+	if err != nil {
+		return math.NaN()
+	}
+	tokens = c.normalized(tokens)
+	next := func() (T, error) {
+		if len(tokens) == 0 {
+			var zero T
+			return zero, io.EOF
+		}
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token, nil
+	}
+	sum, _, err := c.score(c.filter(next), cfg)
+	if err != nil {
+		return math.NaN()
+	}
+	return sum
+}
+
+// score returns the sum of the log probabilities of the words returned by
+// next, each following the words before it, and the number of words scored,
+// including the ends of texts or sentences if the Chain has markers.
+func (c *Chain[T]) score(next func() (T, error), cfg *scoreConfig) (sum float64, n int, err error) {
+	smoother := cfg.smoother
+	if smoother == nil {
+		smoother = unsmoothed{}
+	}
+	c.mu.RLock()
+	est, err := smoother.estimator(c.source())
+	c.mu.RUnlock()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	add := func(window Prefix[uint32], id uint32) error {
+		lp, err := c.logProb(est, c.masked(window), id, cfg)
+		sum += lp
+		n++
+		return err
+	}
+	err = c.scan(context.Background(), next, func(window Prefix[uint32], word T) (uint32, error) {
+		c.mu.RLock()
+		id := c.vocab.id(word)
+		c.mu.RUnlock()
+		return id, add(window, id)
+	}, add)
+	return sum, n, err
+}
+
+// logProb returns the natural logarithm of the probability est gives the
+// word with the given ID, or noID, following window, or of the probability
+// of unseen words if it gives none.
+func (c *Chain[T]) logProb(est estimator, window Prefix[uint32], id uint32, cfg *scoreConfig) (float64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, err := est.prob(window, id)
+	if err != nil {
+		return 0, err
+	}
+	if p == 0 {
+		return math.Log(cfg.unseen), nil
+	}
+	return math.Log(p), nil
+}
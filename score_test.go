@@ -0,0 +1,83 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChainPerplexity(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a b. a c.")
+
+	for _, tt := range []struct {
+		text string
+		opts []ScoreOption
+		want float64
+	}{
+		// a follows the start always, b and c follow a half the time and
+		// end a sentence always.
+		{"a b.", nil, math.Pow(2, 1.0/3)},
+		{"a c. a b.", nil, math.Pow(2, 1.0/3)},
+		{"b", nil, math.Inf(1)},
+		{"a d.", []ScoreOption{WithUnseenProbability(0.01)}, math.Pow(0.01*0.01, -1.0/3)},
+	} {
+		got, err := c.Perplexity(strings.NewReader(tt.text), tt.opts...)
+		if err != nil {
+			t.Errorf("Perplexity(%q) error: %v", tt.text, err)
+		} else if math.Abs(got-tt.want) > 1e-9 && !(math.IsInf(got, 1) && math.IsInf(tt.want, 1)) {
+			t.Errorf("Perplexity(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if _, err := c.Perplexity(strings.NewReader("")); err == nil {
+		t.Error("Perplexity of no words succeeded, want error")
+	}
+	if _, err := c.Perplexity(strings.NewReader("a"), WithUnseenProbability(1)); err == nil {
+		t.Error("Perplexity with an unseen probability of 1 succeeded, want error")
+	}
+}
+
+func TestChainPerplexityRanksTexts(t *testing.T) {
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, strings.Repeat("the cat sat on the mat. the dog sat on the log. ", 3))
+
+	fluent, err := c.Perplexity(strings.NewReader("the dog sat on the mat."), WithUnseenProbability(1e-6))
+	if err != nil {
+		t.Fatalf("Perplexity error: %v", err)
+	}
+	scrambled, err := c.Perplexity(strings.NewReader("mat the on sat dog the."), WithUnseenProbability(1e-6))
+	if err != nil {
+		t.Fatalf("Perplexity error: %v", err)
+	}
+	if !(fluent < scrambled) {
+		t.Errorf("perplexities = %v and %v, want the fluent text's lower", fluent, scrambled)
+	}
+}
+
+func TestChainScore(t *testing.T) {
+	c := NewChain(1, WithMarkers(), WithLowercase())
+	mustBuild(t, c, "a b. a c.")
+
+	if got, want := c.Score([]string{"A", "b."}), math.Log(0.5); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Score(A b.) = %v, want %v", got, want)
+	}
+	if got := c.Score([]string{"b", "a"}); !math.IsInf(got, -1) {
+		t.Errorf("Score(b a) = %v, want -Inf", got)
+	}
+	if got, want := c.Score([]string{"a", "d."}, WithUnseenProbability(0.1)), 2*math.Log(0.1); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Score(a d.) = %v, want %v", got, want)
+	}
+	if got := c.Score(nil); got != 0 {
+		t.Errorf("Score(nil) = %v, want 0", got)
+	}
+	if got := c.Score([]string{"a"}, WithUnseenProbability(-1)); !math.IsNaN(got) {
+		t.Errorf("Score with invalid options = %v, want NaN", got)
+	}
+
+	// Sentences the chain has seen rank above those it has not.
+	seen, unseen := c.Score([]string{"a", "c."}, WithUnseenProbability(1e-3)), c.Score([]string{"c.", "a"}, WithUnseenProbability(1e-3))
+	if !(seen > unseen) {
+		t.Errorf("scores = %v and %v, want the seen sentence's higher", seen, unseen)
+	}
+}
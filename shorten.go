@@ -0,0 +1,52 @@
+package markov
+
+import "fmt"
+
+// Shorten returns a new Chain with prefixes of the last prefixLen words of
+// the Chain's, whose counts are those the Chain's prefixes ending in the
+// same words add up to, so that, built once, a Chain can generate from
+// shorter prefixes too without being built again. The counts are those a
+// Chain with prefixes of prefixLen words would have counted from the same
+// texts, the shorter contexts counted WithBackoff included. The new Chain
+// has the Chain's options and words, and is kept in memory whatever Store
+// holds the Chain.
+//
+// Shorten returns an error if prefixLen is not between 1 and the Chain's
+// prefix length, or if the Chain skips the word prefixLen before each word,
+// which the new Chain would need.
+func (c *Chain[T]) Shorten(prefixLen int) (*Chain[T], error) {
+	if prefixLen < 1 || prefixLen > c.prefixLen {
+		return nil, fmt.Errorf("markov: cannot shorten prefixes of %d words to %d", c.prefixLen, prefixLen)
+	}
+	if c.skips(prefixLen) {
+		return nil, fmt.Errorf("markov: cannot shorten prefixes to %d words, the first of which they skip", prefixLen)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	short, err := c.emptyWithWords(prefixLen)
+	if err != nil {
+		return nil, err
+	}
+	var addErr error
+	err = c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			return true
+		}
+		window := unkey(k)[c.prefixLen-prefixLen:]
+		for i, id := range s.words {
+			if addErr = short.addCount(window, id, s.counts[i]); addErr != nil {
+				break
+			}
+		}
+		return addErr == nil
+	})
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("markov: shortening chain: %w", err)
+	}
+	return short, nil
+}
@@ -0,0 +1,49 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChainShorten(t *testing.T) {
+	const text = "The cat sat on the mat. The dog sat on the cat. The cat ran."
+	for name, opts := range map[string][]Option{
+		"plain":     nil,
+		"markers":   {WithMarkers()},
+		"backoff":   {WithMarkers(), WithBackoff()},
+		"skip-gram": {WithSkipGram(3, 1), WithBackoff()},
+	} {
+		c := NewChain(3, opts...)
+		mustBuild(t, c, text)
+		for prefixLen := 1; prefixLen <= 3; prefixLen++ {
+			if c.skips(prefixLen) {
+				continue
+			}
+			short, err := c.Shorten(prefixLen)
+			if err != nil {
+				t.Fatalf("%s: Shorten(%d) error: %v", name, prefixLen, err)
+			}
+			want := NewChain(prefixLen, opts...)
+			mustBuild(t, want, text)
+			if got, want := chainCounts(short), chainCounts(want); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Shorten(%d) = %v, want %v", name, prefixLen, got, want)
+			}
+			if got, err := short.GenerateString(20); err != nil || got == "" {
+				t.Errorf("%s: Shorten(%d).GenerateString = %q, %v, want text", name, prefixLen, got, err)
+			}
+		}
+	}
+}
+
+func TestChainShortenInvalid(t *testing.T) {
+	c := NewChain(3)
+	mustBuild(t, c, "a b c d")
+	for _, prefixLen := range []int{0, 4} {
+		if _, err := c.Shorten(prefixLen); err == nil {
+			t.Errorf("Shorten(%d) succeeded, want error", prefixLen)
+		}
+	}
+	if _, err := NewChain(3, WithSkipGram(3, 1)).Shorten(2); err == nil {
+		t.Error("Shorten(2) of a chain skipping the second word succeeded, want error")
+	}
+}
@@ -0,0 +1,177 @@
+package markov
+
+import (
+	"container/heap"
+	"math"
+)
+
+// The sizes of the Store of NewSketchStore by default.
+const (
+	defaultSketchWidth = 1 << 20
+	defaultSketchDepth = 4
+	defaultSketchTopK  = 16
+)
+
+// NewSketchStore returns an empty Store that keeps a Chain in memory in
+// bounded space, for corpora so large that exact counts are not worth the
+// memory they take. It estimates how often each word follows each prefix
+// with a count-min sketch of depth rows of width counters, and keeps only
+// the topK words estimated to follow each prefix most often, in a heap.
+//
+// A Chain kept in the Store generates much as one built by Build would, but
+// its counts are estimates, never less than the true counts and further
+// above them the fuller the sketch, and it knows only the topK most frequent
+// words following each prefix. The sketch takes 4*width*depth bytes, and each
+// prefix space for at most topK words. Values less than 1 select a width of
+// 1<<20, a depth of 4 and a topK of 16.
+func NewSketchStore(width, depth, topK int) Store {
+	if width < 1 {
+		width = defaultSketchWidth
+	}
+	if depth < 1 {
+		depth = defaultSketchDepth
+	}
+	if topK < 1 {
+		topK = defaultSketchTopK
+	}
+	return builtinStore{&sketchStore{
+		memoryStore: &memoryStore{},
+		width:       width,
+		depth:       depth,
+		topK:        topK,
+		counters:    make([]uint32, width*depth),
+		prefixes:    make(map[string]*topSuffixes),
+	}}
+}
+
+// sketchStore is the store of NewSketchStore. Its memoryStore keeps only its
+// words and metadata.
+type sketchStore struct {
+	*memoryStore
+	width    int
+	depth    int
+	topK     int
+	counters []uint32 // depth rows of width counters, one after another
+	prefixes map[string]*topSuffixes
+}
+
+func (s *sketchStore) get(k string) (*suffixes, error) {
+	if t := s.prefixes[k]; t != nil {
+		return t.suffixes, nil
+	}
+	return nil, nil
+}
+
+func (s *sketchStore) add(k string, id uint32, n int) error {
+	est := s.increment(k, id, n)
+	t := s.prefixes[k]
+	if t == nil {
+		t = &topSuffixes{suffixes: newSuffixes()}
+		s.prefixes[k] = t
+	}
+
+	if i, ok := t.find(id); ok {
+		t.raise(i, est)
+		heap.Fix(t, t.at[i])
+		return nil
+	}
+	if len(t.words) < s.topK {
+		t.addCount(id, est)
+		heap.Push(t, len(t.words)-1)
+		return nil
+	}
+	// Evict the least frequent word kept if this one is now more frequent.
+	if i := t.heap[0]; est > t.counts[i] {
+		t.replace(i, id, est)
+		heap.Fix(t, 0)
+	}
+	return nil
+}
+
+func (s *sketchStore) put(k string, suf *suffixes) error {
+	for i, id := range suf.words {
+		if err := s.add(k, id, suf.counts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sketchStore) remove(k string) error {
+	delete(s.prefixes, k)
+	return nil
+}
+
+func (s *sketchStore) each(fn func(k string, s *suffixes) bool) error {
+	for k, t := range s.prefixes {
+		if !fn(k, t.suffixes) {
+			break
+		}
+	}
+	return nil
+}
+
+// increment adds n to the estimated count of the word with the given ID
+// following the prefix with key k, and returns its new estimate. It raises
+// only the counters that would otherwise fall below the estimate, a
+// conservative update that keeps estimates closer to the true counts.
+func (s *sketchStore) increment(k string, id uint32, n int) int {
+	h1, h2 := sketchHash(k, id)
+	col := func(row int) int {
+		return row*s.width + int((h1+uint64(row)*h2)%uint64(s.width))
+	}
+	least := uint64(math.MaxUint32)
+	for row := range s.depth {
+		least = min(least, uint64(s.counters[col(row)]))
+	}
+	est := min(least+uint64(n), math.MaxUint32)
+	for row := range s.depth {
+		s.counters[col(row)] = max(s.counters[col(row)], uint32(est))
+	}
+	return int(est)
+}
+
+// sketchHash returns two hashes of the prefix with key k and the word with
+// the given ID, from which the column of each row of a sketch is derived.
+// It is FNV-1a, so that a sketch counts the same words alike every time.
+func sketchHash(k string, id uint32) (uint64, uint64) {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := range len(k) {
+		h = (h ^ uint64(k[i])) * prime
+	}
+	for i := range 4 {
+		h = (h ^ uint64(byte(id>>(8*i)))) * prime
+	}
+	return h & math.MaxUint32, h>>32 | 1
+}
+
+// topSuffixes is the suffixes of a prefix of a sketchStore, with a heap of
+// their positions ordered from least to most frequent, so that the least
+// frequent can be evicted for a more frequent word.
+type topSuffixes struct {
+	*suffixes
+	heap []int // positions in suffixes, least frequent first
+	at   []int // the index in heap of each position
+}
+
+func (t *topSuffixes) Len() int { return len(t.heap) }
+func (t *topSuffixes) Less(i, j int) bool {
+	return t.counts[t.heap[i]] < t.counts[t.heap[j]]
+}
+func (t *topSuffixes) Swap(i, j int) {
+	t.heap[i], t.heap[j] = t.heap[j], t.heap[i]
+	t.at[t.heap[i]], t.at[t.heap[j]] = i, j
+}
+func (t *topSuffixes) Push(x any) {
+	t.at = append(t.at, len(t.heap))
+	t.heap = append(t.heap, x.(int))
+}
+func (t *topSuffixes) Pop() any {
+	i := t.heap[len(t.heap)-1]
+	t.heap = t.heap[:len(t.heap)-1]
+	return i
+}
@@ -0,0 +1,67 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSketchStoreExact(t *testing.T) {
+	// A sketch much wider than the corpus counts it exactly.
+	text := strings.Repeat("The cat sat on the mat. The dog sat on the cat! Did it? ", 20)
+	want := NewChain(2, WithMarkers())
+	mustBuild(t, want, text)
+
+	s := NewSketchStore(0, 0, 0)
+	c, err := OpenChain(s, 2, WithMarkers())
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, text)
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	reopened, err := OpenChain(s, 2)
+	if err != nil {
+		t.Fatalf("OpenChain again error: %v", err)
+	}
+	if got := reopened.Count(Prefix[string]{"The", "cat"}, "sat"); got != 20 {
+		t.Errorf("reopened Count({The cat}, sat) = %d, want 20", got)
+	}
+}
+
+func TestSketchStoreTopK(t *testing.T) {
+	var b strings.Builder
+	for i := range 200 {
+		// "a" is followed by "x" most often, then "y", then many others.
+		b.WriteString("a x a x a y a ")
+		b.WriteString("w" + string(rune('a'+i%26)) + " ")
+	}
+	want := NewChain(1)
+	mustBuild(t, want, b.String())
+
+	c, err := OpenChain(NewSketchStore(64, 2, 2), 1)
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, b.String())
+
+	counts := chainCounts(c)
+	for prefix, next := range counts {
+		if len(next) > 2 {
+			t.Errorf("%s has %d suffixes, want at most 2", prefix, len(next))
+		}
+		for word, n := range next {
+			if exact := chainCounts(want)[prefix][word]; n < exact {
+				t.Errorf("count of %s after %s = %d, want at least %d", word, prefix, n, exact)
+			}
+		}
+	}
+	if _, ok := counts["a"]["x"]; !ok {
+		t.Errorf("suffixes of a = %v, want x kept", counts["a"])
+	}
+	if _, ok := counts["a"]["y"]; !ok {
+		t.Errorf("suffixes of a = %v, want y kept", counts["a"])
+	}
+}
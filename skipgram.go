@@ -0,0 +1,70 @@
+package markov
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// WithSkipGram makes the Chain's prefixes hold only the words at the given
+// positions of the prefixLen words before each word, 1 being the word
+// immediately before it, skipping the others, so that texts sharing those
+// words share their prefixes however the words between them differ. On a
+// small corpus the prefixes of such a Chain are observed more often than
+// those of prefixLen words in a row, while they reach as far back as those
+// of prefixLen words. For example, a Chain from NewChain(3,
+// WithSkipGram(3, 1)) picks each word by the words three and one before it.
+//
+// The farthest word, prefixLen before, is always kept, since skipping it
+// would merely make the prefixes shorter, and positions outside 1 to
+// prefixLen are ignored. Prefixes passed to Count, Predict and the like
+// still hold prefixLen words, of which those skipped are ignored, and
+// Stats, TopPrefixes and the exports report each skipped word as End.
+func WithSkipGram(positions ...int) Option {
+	return func(o *options) {
+		o.skipGram = nil
+		for _, p := range positions {
+			if p >= 1 && !slices.Contains(o.skipGram, p) {
+				o.skipGram = append(o.skipGram, p)
+			}
+		}
+		slices.Sort(o.skipGram)
+	}
+}
+
+// skips reports whether the Chain's prefixes skip the word the given number
+// of positions before the word following them.
+func (c *Chain[T]) skips(position int) bool {
+	return c.skipGram != nil && position >= 1 && position < c.prefixLen && !slices.Contains(c.skipGram, position)
+}
+
+// masked returns the key window of window, whose skipped words are filled
+// with endID, which never precedes a word of a text, or window itself if
+// the Chain skips no words.
+func (c *Chain[T]) masked(window Prefix[uint32]) Prefix[uint32] {
+	if c.skipGram == nil {
+		return window
+	}
+	m := slices.Clone(window)
+	for i := range m {
+		if c.skips(len(m) - i) {
+			m[i] = endID
+		}
+	}
+	return m
+}
+
+// maskedKey is like masked for the key k of a window, copying it to dst,
+// which is as long as k, if the Chain skips any words.
+func (c *Chain[T]) maskedKey(dst, k []byte) []byte {
+	if c.skipGram == nil {
+		return k
+	}
+	copy(dst, k)
+	n := len(k) / 4
+	for i := range n {
+		if c.skips(n - i) {
+			binary.LittleEndian.PutUint32(dst[4*i:], endID)
+		}
+	}
+	return dst
+}
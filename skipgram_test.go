@@ -0,0 +1,155 @@
+package markov
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChainSkipGram(t *testing.T) {
+	c := NewChain(3, WithSkipGram(3, 1))
+	mustBuild(t, c, "a x b c")
+	mustBuild(t, c, "a y b c")
+	mustBuild(t, c, "a z b d")
+
+	// The second word before each word is skipped.
+	if got := c.Count(Prefix[string]{"a", "q", "b"}, "c"); got != 2 {
+		t.Errorf("Count({a q b}, c) = %d, want 2", got)
+	}
+	if got := c.Predict(Prefix[string]{"a", "x", "b"}, 1); len(got) != 1 || got[0].Word != "c" {
+		t.Errorf("Predict({a x b}, 1) = %v, want c first", got)
+	}
+	// The farthest word is kept even if not given.
+	if got := c.Count(Prefix[string]{"x", "y", "b"}, "c"); got != 0 {
+		t.Errorf("Count({x y b}, c) = %d, want 0", got)
+	}
+	if got := c.Count(Prefix[string]{"q", "a"}, "x"); got != 1 {
+		t.Errorf("Count({q a}, x) = %d, want 1", got)
+	}
+
+	for _, build := range []func(*Chain[string], string) error{
+		func(c *Chain[string], text string) error {
+			return c.BuildParallel(context.Background(), strings.NewReader(text), 2)
+		},
+		func(c *Chain[string], text string) error {
+			return c.BuildExternal(context.Background(), strings.NewReader(text), WithMemoryLimit(2), WithTempDir(t.TempDir()))
+		},
+	} {
+		other := NewChain(3, WithSkipGram(3, 1))
+		if err := build(other, "a x b c"); err != nil {
+			t.Fatalf("build error: %v", err)
+		}
+		want := NewChain(3, WithSkipGram(3, 1))
+		mustBuild(t, want, "a x b c")
+		if got, want := chainCounts(other), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("chain built in parallel or externally = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestChainSkipGramOptions(t *testing.T) {
+	c := NewChain(3, WithSkipGram(1, 0, 3, 1, -2))
+	if want := []int{1, 3}; !slices.Equal(c.skipGram, want) {
+		t.Errorf("positions = %v, want %v", c.skipGram, want)
+	}
+	if c.skips(3) || !c.skips(2) || c.skips(1) {
+		t.Errorf("skips(3, 2, 1) = %t, %t, %t, want false, true, false", c.skips(3), c.skips(2), c.skips(1))
+	}
+	if NewChain(3, WithSkipGram(2)).skips(3) {
+		t.Error("skips(3) = true without position 3, want false")
+	}
+	if NewChain(3).skips(2) {
+		t.Error("skips(2) = true without WithSkipGram, want false")
+	}
+}
+
+func TestChainSkipGramSave(t *testing.T) {
+	c := NewChain(3, WithSkipGram(3, 1), WithMarkers())
+	mustBuild(t, c, "a x b c. a y b d.")
+
+	var bin, gob bytes.Buffer
+	if err := c.SaveBinary(&bin); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	if err := c.Save(&gob); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("MarshalJSON error: %v", err)
+	}
+	fromJSON := NewChain(1)
+	if err := json.Unmarshal(data, fromJSON); err != nil {
+		t.Fatalf("UnmarshalJSON error: %v", err)
+	}
+	loaded := map[string]*Chain[string]{"json": fromJSON}
+	for name, r := range map[string]*bytes.Buffer{"binary": &bin, "gob": &gob} {
+		if loaded[name], err = LoadChain(r); err != nil {
+			t.Fatalf("%s: LoadChain error: %v", name, err)
+		}
+	}
+	for name, got := range loaded {
+		if !slices.Equal(got.skipGram, c.skipGram) {
+			t.Errorf("%s: loaded positions = %v, want %v", name, got.skipGram, c.skipGram)
+		}
+		if got, want := chainCounts(got), chainCounts(c); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: loaded chain = %v, want %v", name, got, want)
+		}
+		if n := got.Count(Prefix[string]{"a", "q", "b"}, "c."); n != 1 {
+			t.Errorf("%s: loaded Count({a q b}, c.) = %d, want 1", name, n)
+		}
+	}
+
+	if err := c.Merge(NewChain(3)); err == nil {
+		t.Error("Merge of a chain skipping no words succeeded, want error")
+	}
+}
+
+func TestChainSkipGramGenerate(t *testing.T) {
+	c := NewChain(3, WithSkipGram(3, 1))
+	mustBuild(t, c, "a x b c")
+	if got, err := c.GenerateString(10); err != nil || got != "a x b c" {
+		t.Errorf("GenerateString = %q, %v, want %q", got, err, "a x b c")
+	}
+
+	f, err := c.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze error: %v", err)
+	}
+	if got := f.Append(nil, 10, nil); strings.Join(got, " ") != "a x b c" {
+		t.Errorf("Frozen Append = %q, want %q", got, "a x b c")
+	}
+}
+
+func TestChainSkipGramBackoff(t *testing.T) {
+	c := NewChain(3, WithSkipGram(3, 1), WithBackoff())
+	mustBuild(t, c, "a b c d")
+
+	// Each of the 4 words follows its full prefix, the last word before it
+	// and no words; the context of its last two words skips the first of
+	// them and is that of the last word alone.
+	total := 0
+	c.store.each(func(k string, s *suffixes) bool {
+		total += s.total
+		return true
+	})
+	if total != 12 {
+		t.Errorf("total count = %d, want 12", total)
+	}
+
+	held := "a b c d a b d"
+	for _, s := range []Smoother{Additive(0.5), WittenBell(), KatzBackoff(), KneserNey()} {
+		p, err := c.Perplexity(strings.NewReader(held), WithSmoother(s), WithUnseenProbability(1e-6))
+		if err != nil {
+			t.Fatalf("%T: Perplexity error: %v", s, err)
+		}
+		if math.IsInf(p, 0) || math.IsNaN(p) {
+			t.Errorf("%T: Perplexity = %v, want finite", s, p)
+		}
+	}
+}
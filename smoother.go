@@ -0,0 +1,121 @@
+package markov
+
+// A Smoother estimates the probabilities of words following prefixes from
+// the counts of a Chain, for Perplexity and Score WithSmoother. Each trades
+// quality for computation differently: Additive is the simplest, KatzBackoff
+// and WittenBell back off to shorter prefixes, and KneserNey estimates small
+// corpora best.
+//
+// Only the Smoothers returned by Additive, KatzBackoff, KneserNey and
+// WittenBell implement it.
+type Smoother interface {
+	// estimator returns an estimator of the probabilities of the Chain src
+	// describes. The Chain's mu must be held.
+	estimator(src source) (estimator, error)
+}
+
+// An estimator estimates the probabilities of words following prefixes.
+type estimator interface {
+	// prob returns the probability of the word with the given ID, or noID,
+	// following window. The Chain's mu must be held.
+	prob(window Prefix[uint32], id uint32) (float64, error)
+}
+
+// source is the view of a Chain a Smoother estimates probabilities from.
+type source struct {
+	get       func(window Prefix[uint32]) (*suffixes, error)
+	each      func(fn func(k string, s *suffixes) bool) error
+	prefixLen int
+	words     int // the number of words that may follow a prefix, End included
+	backoff   bool
+	skips     func(position int) bool
+}
+
+// source returns the view of the Chain a Smoother estimates probabilities
+// from.
+func (c *Chain[T]) source() source {
+	words := len(c.vocab.words) - int(firstID)
+	if c.markers {
+		words++
+	}
+	return source{
+		get:       c.get,
+		each:      c.store.each,
+		prefixLen: c.prefixLen,
+		words:     words,
+		backoff:   c.backoff,
+		skips:     c.skips,
+	}
+}
+
+// WithSmoother makes Perplexity and Score estimate probabilities with s
+// rather than from the counts of the Chain alone, by which every word never
+// observed following its prefix has probability zero.
+func WithSmoother(s Smoother) ScoreOption {
+	return func(cfg *scoreConfig) {
+		cfg.smoother = s
+	}
+}
+
+// unsmoothed is the Smoother estimating probabilities from the counts of a
+// Chain alone.
+type unsmoothed struct{}
+
+func (unsmoothed) estimator(src source) (estimator, error) {
+	return unsmoothedEstimator{src}, nil
+}
+
+// unsmoothedEstimator is the estimator of unsmoothed.
+type unsmoothedEstimator struct {
+	src source
+}
+
+func (e unsmoothedEstimator) prob(window Prefix[uint32], id uint32) (float64, error) {
+	s, err := e.src.get(window)
+	if err != nil || s == nil || id == noID {
+		return 0, err
+	}
+	return float64(s.count(id)) / float64(s.total), nil
+}
+
+// shorterCounts returns the suffixes of each context shorter than the
+// prefixes of the Chain src describes, by number of words and key, as
+// counted by add from the full prefixes. For each word observed following a
+// prefix, add is passed the suffixes of each context ending the prefix,
+// longest first, the words of the context with the word before them and the
+// word after, the word's ID and its count, and reports whether to go on to
+// the next shorter context. Contexts whose first word the Chain skips are
+// left empty, being the next shorter ones.
+func shorterCounts(src source, add func(s *suffixes, gram Prefix[uint32], id uint32, n int) bool) ([]map[string]*suffixes, error) {
+	n := src.prefixLen
+	lower := make([]map[string]*suffixes, n)
+	for i := range lower {
+		lower[i] = make(map[string]*suffixes)
+	}
+	err := src.each(func(k string, s *suffixes) bool {
+		window := unkey(k)
+		if contextLen(window) != n {
+			// The shorter contexts of a Chain created WithBackoff.
+			return true
+		}
+		for j, id := range s.words {
+			gram := append(window, id)
+			for i := n - 1; i >= 0; i-- {
+				if src.skips(i) {
+					continue
+				}
+				ck := key(window[n-i:])
+				l := lower[i][ck]
+				if l == nil {
+					l = newSuffixes()
+					lower[i][ck] = l
+				}
+				if !add(l, gram[n-1-i:], id, s.counts[j]) {
+					break
+				}
+			}
+		}
+		return true
+	})
+	return lower, err
+}
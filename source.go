@@ -0,0 +1,190 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// A Source opens the corpora named by URLs, such as the objects of a cloud
+// storage bucket, for BuildURLs to stream into a Chain without downloading
+// them first. Open is called from several goroutines at once.
+type Source interface {
+	Open(ctx context.Context, u *url.URL) (io.ReadCloser, error)
+}
+
+// Sources is a Source opening each URL with the Source for its scheme,
+// such as "s3" for s3://bucket/key.
+type Sources map[string]Source
+
+// DefaultSources returns the Sources for the schemes http, https, s3 and
+// gs, which fetch the publicly readable corpora they name with
+// http.DefaultClient.
+func DefaultSources() Sources {
+	return Sources{
+		"http":  HTTPSource{},
+		"https": HTTPSource{},
+		"s3":    S3Source{},
+		"gs":    GCSSource{},
+	}
+}
+
+// Open opens u with the Source for its scheme.
+func (s Sources) Open(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	src, ok := s[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("%s: no source for scheme %q", u.Redacted(), u.Scheme)
+	}
+	return src.Open(ctx, u)
+}
+
+// BuildURLs is like BuildFiles but builds the Chain from the corpora named
+// by urls, each opened by src and streamed into a Chain of its own as it is
+// read.
+func (c *Chain[T]) BuildURLs(ctx context.Context, src Source, urls []string, opts ...BuildOption) error {
+	cfg, err := newFilesConfig(opts)
+	if err != nil {
+		return err
+	}
+	parsed := make(map[string]*url.URL, len(urls))
+	for _, name := range urls {
+		u, err := url.Parse(name)
+		if err != nil {
+			return fmt.Errorf("markov: %w", err)
+		}
+		parsed[name] = u
+	}
+	return c.buildFiles(ctx, urls, func(name string) (io.ReadCloser, error) {
+		return src.Open(ctx, parsed[name])
+	}, false, cfg)
+}
+
+// ErrTooLarge is returned by reading a corpus fetched by an HTTPSource,
+// S3Source or GCSSource that is larger than its MaxBytes.
+var ErrTooLarge = errors.New("markov: corpus is larger than the size limit")
+
+// An HTTPSource is a Source fetching corpora named by http and https URLs.
+type HTTPSource struct {
+	// Client fetches the corpora, with its Timeout limiting how long each
+	// may take. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// MaxBytes, if positive, is the size of the largest response to read.
+	MaxBytes int64
+}
+
+// Open fetches u, returning its response body as it arrives.
+func (s HTTPSource) Open(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "markov")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u.Redacted(), resp.Status)
+	}
+	if s.MaxBytes <= 0 {
+		return resp.Body, nil
+	}
+	if resp.ContentLength > s.MaxBytes {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %w", u.Redacted(), ErrTooLarge)
+	}
+	return &limitReader{resp.Body, s.MaxBytes}, nil
+}
+
+// A limitReader reads from its ReadCloser until n bytes remain, failing
+// with ErrTooLarge if it holds more, rather than stopping short as
+// io.LimitReader does, so that a truncated corpus is not taken for a whole
+// one.
+type limitReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Read a byte to tell a body of exactly the limit from a longer one.
+		var b [1]byte
+		n, err := l.ReadCloser.Read(b[:])
+		if n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// An S3Source is a Source fetching the objects of Amazon S3 buckets, or of
+// any storage with an S3-compatible API, named by URLs such as
+// s3://bucket/key. It sends the requests unsigned, and so reads only
+// publicly readable objects; a Source calling a storage SDK reads private
+// ones.
+type S3Source struct {
+	HTTPSource
+	// Endpoint, if not empty, is the base URL of the S3-compatible API,
+	// such as "http://localhost:9000", whose objects are addressed by path
+	// as Endpoint/bucket/key. If empty, the objects are fetched from
+	// https://bucket.s3.amazonaws.com/key.
+	Endpoint string
+}
+
+// Open fetches the object named by u.
+func (s S3Source) Open(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: no bucket", u.Redacted())
+	}
+	if s.Endpoint == "" {
+		return s.HTTPSource.Open(ctx, &url.URL{Scheme: "https", Host: u.Host + ".s3.amazonaws.com", Path: u.Path})
+	}
+	return openObject(ctx, s.HTTPSource, s.Endpoint, u)
+}
+
+// A GCSSource is a Source fetching the objects of Google Cloud Storage
+// buckets named by URLs such as gs://bucket/key. Like an S3Source, it reads
+// only publicly readable objects.
+type GCSSource struct {
+	HTTPSource
+	// Endpoint, if not empty, is the base URL of the API in place of
+	// https://storage.googleapis.com, whose objects are addressed by path
+	// as Endpoint/bucket/key.
+	Endpoint string
+}
+
+// Open fetches the object named by u.
+func (s GCSSource) Open(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("%s: no bucket", u.Redacted())
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	return openObject(ctx, s.HTTPSource, endpoint, u)
+}
+
+// openObject fetches the object named by u from the bucket u.Host under
+// the endpoint, which addresses objects by path.
+func openObject(ctx context.Context, src HTTPSource, endpoint string, u *url.URL) (io.ReadCloser, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return src.Open(ctx, base.JoinPath(u.Host, u.Path))
+}
@@ -0,0 +1,131 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serveCorpora serves each of corpora at its path, streaming it without a
+// Content-Length if the query asks for it chunked.
+func serveCorpora(t *testing.T, corpora map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		text, ok := corpora[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Has("chunked") {
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, text)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error: %v", s, err)
+	}
+	return u
+}
+
+func TestHTTPSource(t *testing.T) {
+	const text = "the cat sat on the mat"
+	srv := serveCorpora(t, map[string]string{"/corpus.txt": text})
+
+	tests := []struct {
+		path     string
+		maxBytes int64
+		wantErr  error
+	}{
+		{"/corpus.txt", 0, nil},
+		{"/corpus.txt", int64(len(text)), nil},
+		{"/corpus.txt", int64(len(text)) - 1, ErrTooLarge},
+		{"/corpus.txt?chunked", int64(len(text)), nil},
+		{"/corpus.txt?chunked", int64(len(text)) - 1, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		src := HTTPSource{MaxBytes: tt.maxBytes}
+		r, err := src.Open(context.Background(), mustParseURL(t, srv.URL+tt.path))
+		var got []byte
+		if err == nil {
+			got, err = io.ReadAll(r)
+			r.Close()
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s with MaxBytes %d: error = %v, want %v", tt.path, tt.maxBytes, err, tt.wantErr)
+		} else if err == nil && string(got) != text {
+			t.Errorf("%s with MaxBytes %d: read %q, want %q", tt.path, tt.maxBytes, got, text)
+		}
+	}
+
+	if _, err := (HTTPSource{}).Open(context.Background(), mustParseURL(t, srv.URL+"/missing")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Open of a missing corpus error = %v, want 404", err)
+	}
+}
+
+func TestBucketSources(t *testing.T) {
+	const text = "the cat sat on the mat"
+	srv := serveCorpora(t, map[string]string{"/bucket/dir/corpus one.txt": text})
+	sources := Sources{
+		"s3": S3Source{Endpoint: srv.URL},
+		"gs": GCSSource{Endpoint: srv.URL + "/"},
+	}
+	for _, name := range []string{"s3://bucket/dir/corpus%20one.txt", "gs://bucket/dir/corpus%20one.txt"} {
+		r, err := sources.Open(context.Background(), mustParseURL(t, name))
+		if err != nil {
+			t.Fatalf("Open(%q) error: %v", name, err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(got) != text {
+			t.Errorf("Open(%q) read %q, %v, want %q", name, got, err, text)
+		}
+	}
+
+	for _, name := range []string{"s3:///key", "gs:///key", "ftp://host/corpus.txt"} {
+		if _, err := sources.Open(context.Background(), mustParseURL(t, name)); err == nil {
+			t.Errorf("Open(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestChainBuildURLs(t *testing.T) {
+	texts := []string{"The cat sat.", "The dog ran. The cat ran.", "A bird sang"}
+	corpora := map[string]string{}
+	var urls []string
+	for i, text := range texts {
+		path := "/bucket/" + string(rune('a'+i)) + ".txt"
+		corpora[path] = text
+		urls = append(urls, "s3:/"+path)
+	}
+	srv := serveCorpora(t, corpora)
+	sources := Sources{"s3": S3Source{Endpoint: srv.URL}}
+
+	want := NewChain(1, WithMarkers())
+	for _, text := range texts {
+		mustBuild(t, want, text)
+	}
+	c := NewChain(1, WithMarkers())
+	if err := c.BuildURLs(context.Background(), sources, urls, WithWorkers(2)); err != nil {
+		t.Fatalf("BuildURLs error: %v", err)
+	}
+	if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	if err := c.BuildURLs(context.Background(), sources, []string{"s3://bucket/missing.txt"}); err == nil {
+		t.Error("BuildURLs of a missing object succeeded, want error")
+	}
+}
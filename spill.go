@@ -0,0 +1,325 @@
+package markov
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Rough costs, in bytes, of counts held in memory by BuildExternal.
+const (
+	spillPrefixCost = 160 // a map entry and its suffixes, beyond its key
+	spillSuffixCost = 24  // a word ID, its count and its index entry
+)
+
+// defaultMemoryLimit is the memory BuildExternal counts in by default.
+const defaultMemoryLimit = 256 << 20
+
+// WithMemoryLimit makes BuildExternal hold at most about n bytes of counts
+// in memory before spilling them to disk. The default is 256 MiB.
+func WithMemoryLimit(n int64) BuildOption {
+	return func(cfg *buildConfig) {
+		cfg.memoryLimit = n
+	}
+}
+
+// WithTempDir makes BuildExternal spill counts to files in dir rather than
+// in the default directory for temporary files.
+func WithTempDir(dir string) BuildOption {
+	return func(cfg *buildConfig) {
+		cfg.tempDir = dir
+	}
+}
+
+// BuildExternal is like BuildContext but trains on corpora whose counts do
+// not fit in memory. It counts the words it reads in memory until the counts
+// reach the limit set WithMemoryLimit, then writes them to a temporary file
+// sorted by prefix and starts afresh, and finally merges the files into the
+// Chain a prefix at a time. The result is the Chain Build would have built.
+//
+// Merged into a Chain kept in a database, as by OpenSQLChain, training
+// needs no more memory than the limit and the Chain's vocabulary, however
+// large the corpus. A Chain kept in memory still ends up holding every
+// count, but needs no more than the limit besides while training.
+//
+// If BuildExternal fails while reading r, none of its counts are recorded
+// in the Chain, but the words it read remain in the Chain's vocabulary, and
+// in its Store, as do the runs of words a Chain created WithNgramIndex
+// indexed. If it fails while merging the files, the counts of the prefixes
+// merged before remain in the Chain, which is then partly trained.
+func (c *Chain[T]) BuildExternal(ctx context.Context, r io.Reader, opts ...BuildOption) (err error) {
+	if c.reversed {
+		return errReversed
+	}
+	cfg := buildConfig{memoryLimit: defaultMemoryLimit}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.memoryLimit < 1 {
+		return fmt.Errorf("markov: invalid memory limit %d", cfg.memoryLimit)
+	}
+
+	s := &spiller{dir: cfg.tempDir, limit: cfg.memoryLimit, keyLen: 4 * c.prefixLen, counts: make(mapStore)}
+	defer func() {
+		if closeErr := s.close(); err == nil {
+			err = closeErr
+		}
+	}()
+	record := func(window Prefix[uint32], id uint32) error {
+		var buf [64]byte
+		return s.add(appendKey(buf[:0], c.masked(window)), id)
+	}
+	observe, add := c.backingOff(c.indexing(func(window Prefix[uint32], word T) (uint32, error) {
+		c.mu.Lock()
+		id, err := c.intern(word)
+		c.mu.Unlock()
+		if err != nil {
+			return noID, err
+		}
+		return id, record(window, id)
+	}), record)
+	err = c.scan(ctx, c.tokenize(r), observe, add)
+	if err != nil {
+		return err
+	}
+
+	if len(s.runs) == 0 {
+		return c.mergeShards([]mapStore{s.counts})
+	}
+	if err := s.spill(); err != nil {
+		return err
+	}
+	err = s.merge(func(k string, suf *suffixes) error {
+		// Lock the Chain for each prefix rather than throughout, so that it
+		// can generate while the runs are merged.
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.mergePrefix(k, suf)
+	})
+	if flushErr := c.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// A spiller counts observations in memory, spilling them to a temporary
+// file, a run, whenever they reach its limit.
+type spiller struct {
+	dir    string
+	limit  int64
+	keyLen int
+	size   int64
+	counts mapStore
+	runs   []*os.File
+}
+
+// add records one more occurrence of the word with the given ID following
+// the prefix with key k.
+func (s *spiller) add(k []byte, id uint32) error {
+	suf := s.counts.lookup(k)
+	if suf == nil {
+		suf = newSuffixes()
+		s.counts[string(k)] = suf
+		s.size += spillPrefixCost + int64(len(k))
+	}
+	n := len(suf.words)
+	suf.add(id)
+	if len(suf.words) > n {
+		s.size += spillSuffixCost
+	}
+	if s.size >= s.limit {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the counts to a new run, sorted by key, and forgets them. A
+// run holds, for each prefix, its key, the length of its encoded suffixes
+// and its suffixes encoded by encodeSuffixes.
+func (s *spiller) spill() error {
+	f, err := os.CreateTemp(s.dir, "markov-*.run")
+	if err != nil {
+		return fmt.Errorf("markov: spilling counts: %w", err)
+	}
+	s.runs = append(s.runs, f)
+
+	w := bufio.NewWriter(f)
+	var b []byte
+	for _, k := range slices.Sorted(maps.Keys(s.counts)) {
+		enc := encodeSuffixes(s.counts[k])
+		b = append(b[:0], k...)
+		b = binary.AppendUvarint(b, uint64(len(enc)))
+		w.Write(b)
+		w.Write(enc)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("markov: spilling counts: %w", err)
+	}
+	s.counts, s.size = make(mapStore), 0
+	return nil
+}
+
+// merge passes fn each prefix of the runs, in order of key, with the sum of
+// its suffixes in every run, in the order they were first observed.
+func (s *spiller) merge(fn func(k string, suf *suffixes) error) error {
+	var h runHeap
+	for i, f := range s.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("markov: merging counts: %w", err)
+		}
+		r := &runReader{r: bufio.NewReader(f), index: i, key: make([]byte, s.keyLen)}
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+
+	// The runs holding a prefix are popped in the order they were written,
+	// so its suffixes are merged in the order they were first observed.
+	for len(h) > 0 {
+		k := string(h[0].key)
+		merged := newSuffixes()
+		for len(h) > 0 && string(h[0].key) == k {
+			r := h[0]
+			for i, id := range r.suf.words {
+				merged.addCount(id, r.suf.counts[i])
+			}
+			ok, err := r.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+		}
+		if err := fn(k, merged); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close removes the runs.
+func (s *spiller) close() error {
+	var err error
+	for _, f := range s.runs {
+		f.Close()
+		if removeErr := os.Remove(f.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
+
+// A runReader reads the prefixes of a run in turn.
+type runReader struct {
+	r     *bufio.Reader
+	index int    // the position of the run among those spilled
+	key   []byte // the key of the current prefix
+	suf   *suffixes
+}
+
+// next reads the next prefix of the run, reporting false at its end.
+func (r *runReader) next() (bool, error) {
+	_, err := io.ReadFull(r.r, r.key)
+	if err == io.EOF {
+		return false, nil
+	}
+	var n uint64
+	if err == nil {
+		n, err = binary.ReadUvarint(r.r)
+	}
+	var enc []byte
+	if err == nil {
+		enc = make([]byte, n)
+		_, err = io.ReadFull(r.r, enc)
+	}
+	if err == nil {
+		r.suf, err = decodeSuffixes(enc)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return false, fmt.Errorf("markov: merging counts: %w", err)
+	}
+	return true, nil
+}
+
+// A runHeap orders runReaders by the key of their current prefix, and then
+// by the order the runs were spilled.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if c := bytes.Compare(h[i].key, h[j].key); c != 0 {
+		return c < 0
+	}
+	return h[i].index < h[j].index
+}
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)   { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// encodeSuffixes returns the encoding of s in a run: their number followed
+// by each suffix's ID and count, in the order they were first observed, all
+// as unsigned varints.
+func encodeSuffixes(s *suffixes) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(s.words)))
+	for i, id := range s.words {
+		b = binary.AppendUvarint(b, uint64(id))
+		b = binary.AppendUvarint(b, uint64(s.counts[i]))
+	}
+	return b
+}
+
+// decodeSuffixes returns the suffixes encoded as b by encodeSuffixes, or nil
+// if b is nil.
+func decodeSuffixes(b []byte) (*suffixes, error) {
+	if b == nil {
+		return nil, nil
+	}
+	next := func() uint64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			b = nil
+			return 0
+		}
+		b = b[n:]
+		return v
+	}
+	invalid := errors.New("invalid suffixes")
+	n := next()
+	if b == nil {
+		return nil, invalid
+	}
+	s := newSuffixes()
+	for ; n > 0; n-- {
+		id, count := next(), next()
+		if b == nil || id >= noID || count == 0 || count > 1<<62 {
+			return nil, invalid
+		}
+		s.addCount(uint32(id), int(count))
+	}
+	if len(b) != 0 {
+		return nil, invalid
+	}
+	return s, nil
+}
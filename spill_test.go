@@ -0,0 +1,65 @@
+package markov
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainBuildExternal(t *testing.T) {
+	text := strings.Repeat("The cat sat on the mat. The dog sat on the cat! Did it? ", 50)
+	want := NewChain(2, WithMarkers())
+	mustBuild(t, want, "The bird sang.")
+	mustBuild(t, want, text)
+
+	db := openTestDB(t)
+	sqlChain, err := OpenSQLChain(db, 2, WithMarkers())
+	if err != nil {
+		t.Fatalf("OpenSQLChain error: %v", err)
+	}
+	for name, c := range map[string]*Chain[string]{"memory": NewChain(2, WithMarkers()), "sql": sqlChain} {
+		// A limit this small spills the counts every few words.
+		dir := t.TempDir()
+		mustBuild(t, c, "The bird sang.")
+		if err := c.BuildExternal(context.Background(), strings.NewReader(text), WithMemoryLimit(1000), WithTempDir(dir)); err != nil {
+			t.Fatalf("%s: BuildExternal error: %v", name, err)
+		}
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: chain = %v, want %v", name, got, want)
+		}
+
+		rng := func() GenerateOption { return WithRand(rand.New(rand.NewSource(1))) }
+		got, _ := c.GenerateString(30, rng())
+		if want, _ := want.GenerateString(30, rng()); got != want {
+			t.Errorf("%s: GenerateString = %q, want %q", name, got, want)
+		}
+
+		if runs, _ := os.ReadDir(dir); len(runs) != 0 {
+			t.Errorf("%s: %d runs left in the temporary directory, want 0", name, len(runs))
+		}
+	}
+}
+
+func TestChainBuildExternalInMemory(t *testing.T) {
+	c := NewChain(1)
+	if err := c.BuildExternal(context.Background(), strings.NewReader("a b a c")); err != nil {
+		t.Fatalf("BuildExternal error: %v", err)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "c"); got != 1 {
+		t.Errorf("Count({a}, c) = %d, want 1", got)
+	}
+	if err := c.BuildExternal(context.Background(), strings.NewReader("a b"), WithMemoryLimit(0)); err == nil {
+		t.Error("BuildExternal with a memory limit of 0 succeeded, want error")
+	}
+}
+
+func TestDecodeSuffixesInvalid(t *testing.T) {
+	for _, b := range [][]byte{{}, {1}, {1, 2}, {1, 2, 0}, {1, 2, 3, 4}, {0x80}} {
+		if _, err := decodeSuffixes(b); err == nil {
+			t.Errorf("decodeSuffixes(%x) succeeded, want error", b)
+		}
+	}
+}
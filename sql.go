@@ -0,0 +1,263 @@
+package markov
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/saclark/markov/internal/batch"
+)
+
+// sqlSchema creates the tables OpenSQL keeps a Chain in. Every suffix row
+// keeps the rowid it was inserted with, so ordering a prefix's suffixes by
+// rowid yields them in the order they were first observed.
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS markov_meta (
+		name  TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS markov_words (
+		id   INTEGER PRIMARY KEY,
+		word BLOB NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS markov_suffixes (
+		prefix BLOB NOT NULL,
+		word   INTEGER NOT NULL,
+		count  INTEGER NOT NULL,
+		PRIMARY KEY (prefix, word)
+	)`,
+}
+
+// OpenSQLChain returns a Chain of strings with prefixes of prefixLen words
+// that keeps its prefixes and the counts of their suffixes in the SQLite
+// database db, so that it can be trained on and generate from models far
+// larger than memory. Only its vocabulary is kept in memory.
+//
+// OpenSQLChain creates the tables it needs in db if they do not exist, and
+// otherwise continues the Chain kept in them, which must have prefixes of
+// prefixLen words. The options of a new Chain are opts; those of an
+// existing Chain are its saved options followed by opts, and the result is
+// saved for the next time. Only one Chain at a time may use the tables.
+//
+// Build writes what it observes in batched transactions, and Generate looks
+// up each prefix by its index. Words recorded by Add are written once a
+// batch is full or Flush is called. Compile has no effect.
+func OpenSQLChain(db *sql.DB, prefixLen int, opts ...Option) (*Chain[string], error) {
+	return OpenSQL[string](db, prefixLen, opts...)
+}
+
+// OpenSQL is like OpenSQLChain but returns a Chain of words of type T, which
+// must be strings or integers.
+func OpenSQL[T comparable](db *sql.DB, prefixLen int, opts ...Option) (*Chain[T], error) {
+	s, err := newSQLStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	return open[T](s, prefixLen, opts)
+}
+
+// NewSQLStore returns a Store that keeps a Chain in the SQLite database db,
+// as OpenSQLChain does, creating the tables it needs if they do not exist.
+func NewSQLStore(db *sql.DB) (Store, error) {
+	s, err := newSQLStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("markov: %w", err)
+	}
+	return builtinStore{s}, nil
+}
+
+// newSQLStore returns the store of a Chain kept in db, creating its tables.
+func newSQLStore(db *sql.DB) (*sqlStore, error) {
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &sqlStore{db: db}, nil
+}
+
+// sqlStore is the store of a Chain opened by OpenSQL.
+type sqlStore struct {
+	db      *sql.DB
+	pending batch.Batch
+}
+
+func (s *sqlStore) meta() ([]byte, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM markov_meta WHERE name = 'chain'`).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return []byte(value), err
+}
+
+func (s *sqlStore) setMeta(data []byte) error {
+	_, err := s.db.Exec(`INSERT INTO markov_meta (name, value) VALUES ('chain', ?)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value`, string(data))
+	return err
+}
+
+func (s *sqlStore) get(k string) (*suffixes, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT word, count FROM markov_suffixes WHERE prefix = ? ORDER BY rowid`, []byte(k))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suf *suffixes
+	for rows.Next() {
+		var (
+			id    uint32
+			count int
+		)
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		if suf == nil {
+			suf = newSuffixes()
+		}
+		suf.addCount(id, count)
+	}
+	return suf, rows.Err()
+}
+
+func (s *sqlStore) add(k string, id uint32, n int) error {
+	return s.pending.Add(k, id, n, s.write)
+}
+
+func (s *sqlStore) intern(word []byte, next uint32) (uint32, error) {
+	s.pending.AddWord(next, word)
+	return next, nil
+}
+
+func (s *sqlStore) wordsFrom(id uint32) ([][]byte, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT id, word FROM markov_words WHERE id >= ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var words [][]byte
+	for rows.Next() {
+		var (
+			got  uint32
+			word []byte
+		)
+		if err := rows.Scan(&got, &word); err != nil {
+			return nil, err
+		}
+		if want := id + uint32(len(words)); got != want {
+			return nil, fmt.Errorf("missing word with ID %d", want)
+		}
+		words = append(words, word)
+	}
+	return words, rows.Err()
+}
+
+func (s *sqlStore) put(k string, suf *suffixes) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, id := range suf.words {
+		if _, err := tx.Exec(`INSERT INTO markov_suffixes (prefix, word, count) VALUES (?, ?, ?)`, []byte(k), id, suf.counts[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) remove(k string) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM markov_suffixes WHERE prefix = ?`, []byte(k))
+	return err
+}
+
+func (s *sqlStore) each(fn func(k string, s *suffixes) bool) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	rows, err := s.db.Query(`SELECT prefix, word, count FROM markov_suffixes ORDER BY prefix, rowid`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		k   string
+		suf *suffixes
+	)
+	for rows.Next() {
+		var (
+			prefix []byte
+			id     uint32
+			count  int
+		)
+		if err := rows.Scan(&prefix, &id, &count); err != nil {
+			return err
+		}
+		if suf == nil || string(prefix) != k {
+			if suf != nil && !fn(k, suf) {
+				return nil
+			}
+			k, suf = string(prefix), newSuffixes()
+		}
+		suf.addCount(id, count)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if suf != nil {
+		fn(k, suf)
+	}
+	return nil
+}
+
+func (s *sqlStore) flush() error {
+	return s.pending.Flush(s.write)
+}
+
+// write writes the words and observations of b in a single transaction.
+func (s *sqlStore) write(b *batch.Batch) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertWord, err := tx.Prepare(`INSERT INTO markov_words (id, word) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertWord.Close()
+	for _, w := range b.Words {
+		if _, err := insertWord.Exec(w.ID, w.Word); err != nil {
+			return err
+		}
+	}
+
+	addCount, err := tx.Prepare(`INSERT INTO markov_suffixes (prefix, word, count) VALUES (?, ?, ?)
+		ON CONFLICT (prefix, word) DO UPDATE SET count = count + excluded.count`)
+	if err != nil {
+		return err
+	}
+	defer addCount.Close()
+	for i, o := range b.Observations {
+		if _, err := addCount.Exec([]byte(o.Prefix), o.ID, b.Counts[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
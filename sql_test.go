@@ -0,0 +1,142 @@
+package markov
+
+import (
+	"bytes"
+	"database/sql"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/saclark/markov/internal/batch"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatalf("sql.Open error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestOpenSQLChain(t *testing.T) {
+	const text = "I am a c. I am a d. You are a c."
+	db := openTestDB(t)
+	c, err := OpenSQLChain(db, 2, WithMarkers(), WithLowercase())
+	if err != nil {
+		t.Fatalf("OpenSQLChain error: %v", err)
+	}
+	mustBuild(t, c, text)
+
+	mem := NewChain(2, WithMarkers(), WithLowercase())
+	mustBuild(t, mem, text)
+	if got, want := chainCounts(c), chainCounts(mem); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+	if got := c.Count(Prefix[string]{"am", "a"}, "c."); got != 1 {
+		t.Errorf("Count({am a}, c.) = %d, want 1", got)
+	}
+
+	rng := func() GenerateOption { return WithRand(rand.New(rand.NewSource(1))) }
+	got, err := c.GenerateN(10, 5, rng())
+	if err != nil {
+		t.Fatalf("GenerateN error: %v", err)
+	}
+	want, err := mem.GenerateN(10, 5, rng())
+	if err != nil {
+		t.Fatalf("GenerateN error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateN = %q, want %q as generated in memory", got, want)
+	}
+
+	var saved bytes.Buffer
+	if err := c.SaveBinary(&saved); err != nil {
+		t.Fatalf("SaveBinary error: %v", err)
+	}
+	loaded, err := LoadChain(&saved)
+	if err != nil {
+		t.Fatalf("LoadChain error: %v", err)
+	}
+	if got, want := chainCounts(loaded), chainCounts(mem); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded chain = %v, want %v", got, want)
+	}
+}
+
+func TestOpenSQLChainReopen(t *testing.T) {
+	db := openTestDB(t)
+	c, err := OpenSQLChain(db, 1, WithLowercase())
+	if err != nil {
+		t.Fatalf("OpenSQLChain error: %v", err)
+	}
+	mustBuild(t, c, "A b a c")
+
+	if _, err := OpenSQLChain(db, 2); err == nil {
+		t.Error("OpenSQLChain with another prefix length succeeded, want error")
+	}
+	if _, err := OpenSQL[int](db, 1); err == nil {
+		t.Error("OpenSQL[int] of a chain of strings succeeded, want error")
+	}
+
+	c, err = OpenSQLChain(db, 1)
+	if err != nil {
+		t.Fatalf("OpenSQLChain error reopening: %v", err)
+	}
+	if !c.lowercase {
+		t.Error("reopened chain does not lowercase, want saved options restored")
+	}
+	c.Add("A", "d")
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+
+	c, err = OpenSQLChain(db, 1)
+	if err != nil {
+		t.Fatalf("OpenSQLChain error reopening: %v", err)
+	}
+	want := map[string]map[string]int{
+		"":  {"a": 2},
+		"a": {"b": 1, "c": 1, "d": 1},
+		"b": {"a": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened chain = %v, want %v", got, want)
+	}
+}
+
+func TestOpenSQLChainBatches(t *testing.T) {
+	var words []string
+	for i := range 3 * batch.Size {
+		words = append(words, strconv.Itoa(i%(2*batch.Size)))
+	}
+	text := strings.Join(words, " ")
+
+	c, err := OpenSQL[int](openTestDB(t), 2)
+	if err != nil {
+		t.Fatalf("OpenSQL error: %v", err)
+	}
+	if err := c.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	mem := New[int](2)
+	if err := mem.Build(strings.NewReader(text)); err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+
+	if got := c.Count(Prefix[int]{2*batch.Size - 2, 2*batch.Size - 1}, 0); got != 1 {
+		t.Errorf("Count of the first word after the last = %d, want 1", got)
+	}
+	got, err := c.model()
+	if err != nil {
+		t.Fatalf("model error: %v", err)
+	}
+	want, _ := mem.model()
+	if !reflect.DeepEqual(got, want) {
+		t.Error("chain built in batches differs from chain built in memory")
+	}
+}
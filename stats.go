@@ -0,0 +1,64 @@
+package markov
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats summarizes the model of a Chain, as reported by Chain.Stats.
+type Stats struct {
+	Words       int // the number of distinct words
+	Prefixes    int // the number of distinct prefixes
+	Suffixes    int // the number of distinct words following each prefix, summed
+	Transitions int // the number of words observed following prefixes
+
+	// Branching is the mean number of distinct words following a prefix.
+	Branching float64
+
+	// Entropy is the entropy of the word following a prefix, in bits,
+	// averaged over the prefixes by how often they were observed: the
+	// number of bits the Chain needs to choose each word it generates.
+	Entropy float64
+}
+
+// Stats returns a summary of the Chain's model: the number of its words,
+// prefixes and transitions, how many ways it branches and how unpredictable
+// its choice of each word is.
+func (c *Chain[T]) Stats() (Stats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	st := Stats{Words: len(c.vocab.words) - int(firstID)}
+	var sum float64 // the sum of n log2 n over every prefix and suffix count n
+	err := c.store.each(func(k string, s *suffixes) bool {
+		if c.isBackoffContext(k) {
+			return true
+		}
+		st.Prefixes++
+		st.Suffixes += len(s.words)
+		st.Transitions += s.total
+		sum += xlog2(s.total)
+		for _, n := range s.counts {
+			sum -= xlog2(n)
+		}
+		return true
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("markov: summarizing chain: %w", err)
+	}
+	if st.Prefixes > 0 {
+		st.Branching = float64(st.Suffixes) / float64(st.Prefixes)
+	}
+	if st.Transitions > 0 {
+		st.Entropy = sum / float64(st.Transitions)
+	}
+	return st, nil
+}
+
+// xlog2 returns n log2 n, or 0 if n is 0.
+func xlog2(n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(n) * math.Log2(float64(n))
+}
@@ -0,0 +1,27 @@
+package markov
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChainStats(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c")
+
+	st, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats error: %v", err)
+	}
+	// The prefixes are the start, a and b; only a branches, evenly.
+	want := Stats{Words: 3, Prefixes: 3, Suffixes: 4, Transitions: 4, Branching: 4.0 / 3, Entropy: 0.5}
+	if st.Words != want.Words || st.Prefixes != want.Prefixes || st.Suffixes != want.Suffixes ||
+		st.Transitions != want.Transitions || math.Abs(st.Branching-want.Branching) > 1e-9 ||
+		math.Abs(st.Entropy-want.Entropy) > 1e-9 {
+		t.Errorf("Stats = %+v, want %+v", st, want)
+	}
+
+	if st, err := NewChain(2).Stats(); err != nil || st != (Stats{}) {
+		t.Errorf("Stats of an empty chain = %+v, %v, want zero", st, err)
+	}
+}
@@ -0,0 +1,67 @@
+package markov
+
+// WithStopWords makes the Chain drop each of words from its input, and from
+// the words given to Add, prompts and stop sequences, as if they had never
+// appeared. This suits keyword-style generation, in which function words
+// such as "the" and "of" only add noise. Words are compared after
+// WithNormalization and WithLowercase apply, so a Chain created WithLowercase
+// needs its stop words in lower case.
+func WithStopWords(words ...string) Option {
+	return func(o *options) {
+		o.addStopWords(words)
+		o.collapse = false
+	}
+}
+
+// WithCollapsedStopWords is like WithStopWords, but replaces each run of
+// consecutive stop words with the single word placeholder rather than
+// dropping it, so that generated text keeps a trace of where they stood.
+func WithCollapsedStopWords(placeholder string, words ...string) Option {
+	return func(o *options) {
+		o.addStopWords(words)
+		o.collapse = true
+		o.placeholder = placeholder
+	}
+}
+
+// addStopWords adds words to the set of stop words.
+func (o *options) addStopWords(words []string) {
+	if o.stopWords == nil {
+		o.stopWords = make(map[string]bool)
+	}
+	for _, word := range words {
+		o.stopWords[word] = true
+	}
+}
+
+// filter returns a function returning the words returned by next, with
+// stop words dropped or collapsed as configured WithStopWords or
+// WithCollapsedStopWords, and words a WithTokenTransform emptied dropped.
+func (c *Chain[T]) filter(next func() (T, error)) func() (T, error) {
+	if len(c.stopWords) == 0 && len(c.transforms) == 0 {
+		return next
+	}
+	placeholder, canCollapse := any(c.placeholder).(T)
+
+	inRun := false
+	return func() (T, error) {
+		for {
+			word, err := next()
+			if err != nil {
+				return word, err
+			}
+			s, ok := any(word).(string)
+			if ok && s == "" && len(c.transforms) > 0 {
+				continue
+			}
+			if !ok || !c.stopWords[s] {
+				inRun = false
+				return word, nil
+			}
+			if c.collapse && canCollapse && !inRun {
+				inRun = true
+				return placeholder, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithStopWords(t *testing.T) {
+	c := NewChain(1, WithStopWords("the", "of", "a"))
+	mustBuild(t, c, "the king of the north met a king")
+	c.Add("the", "north")
+
+	want := map[string]map[string]int{
+		"":      {"king": 1, "north": 1},
+		"king":  {"north": 1},
+		"north": {"met": 1},
+		"met":   {"king": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(2, WithPrompt("a king of"))
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "north met"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
+
+func TestWithCollapsedStopWords(t *testing.T) {
+	c := NewChain(1, WithCollapsedStopWords("_", "the", "of", "a"))
+	mustBuild(t, c, "king of the north met a king")
+
+	want := map[string]map[string]int{
+		"":      {"king": 1},
+		"king":  {"_": 1},
+		"_":     {"north": 1, "king": 1},
+		"north": {"met": 1},
+		"met":   {"_": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+}
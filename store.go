@@ -0,0 +1,602 @@
+package markov
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// A Store holds the prefixes of a Chain and the counts of the suffixes
+// observed following each of them, so that the Chain can be kept anywhere.
+// NewMemoryStore keeps them in memory, as New does, NewSketchStore keeps
+// estimates of them in bounded memory, and NewSQLStore keeps them in a
+// database, as do the Stores of the subpackages boltstore and redisstore.
+// Open returns a Chain kept in any Store.
+//
+// A Store knows words only by the IDs the Chain assigns them, and prefixes
+// only by opaque keys. The Chain keeps its vocabulary in memory, but
+// records each new word in the Store so that it can be reopened.
+//
+// A Store is used by one Chain at a time, which calls Get and Iterate
+// concurrently with each other but never with its other methods. Only a
+// Store whose Intern assigns IDs, as that of redisstore.New does, may be
+// shared by several Chains at once.
+type Store interface {
+	// Get returns the suffixes of the prefix with the given key, or none if
+	// it has none. Returning them in the order they were first observed
+	// keeps generating with a seeded random source reproducible.
+	Get(prefix string) ([]Suffix, error)
+
+	// Increment adds n to the count of the suffix with the given ID of the
+	// prefix with the given key, appending the suffix if it is new.
+	Increment(prefix string, id uint32, n int) error
+
+	// Remove removes the prefix with the given key and all its suffixes.
+	Remove(prefix string) error
+
+	// Iterate calls fn with every prefix and its suffixes, in any order,
+	// until fn returns false.
+	Iterate(fn func(prefix string, suffixes []Suffix) bool) error
+
+	// Intern records word, to which the Chain has not assigned an ID, and
+	// returns its ID. That is next, the lowest ID the Chain has not
+	// assigned, unless other Chains sharing the Store have assigned IDs.
+	Intern(word []byte, next uint32) (uint32, error)
+
+	// Words returns the words recorded by Intern with IDs from the given
+	// one on, in order of ID.
+	Words(from uint32) ([][]byte, error)
+
+	// Meta returns the data last recorded by SetMeta, or nil.
+	Meta() ([]byte, error)
+
+	// SetMeta records data describing the Chain, such as its options.
+	SetMeta(data []byte) error
+
+	// Flush writes any changes the Store has buffered.
+	Flush() error
+}
+
+// A Suffix is a word, by ID, observed following a prefix and the number of
+// times it was observed.
+type Suffix struct {
+	ID    uint32
+	Count int
+}
+
+// FirstID is the ID a Chain assigns the first word it observes, and so the
+// lowest ID of a word recorded by Intern. Lower IDs stand for the start and
+// end of a text.
+const FirstID = firstID
+
+// NewMemoryStore returns an empty Store that keeps a Chain in memory.
+func NewMemoryStore() Store {
+	return builtinStore{&memoryStore{prefixes: make(mapStore)}}
+}
+
+// OpenChain returns a Chain of strings with prefixes of prefixLen words kept
+// in s, configured as described by OpenSQLChain.
+func OpenChain(s Store, prefixLen int, opts ...Option) (*Chain[string], error) {
+	return Open[string](s, prefixLen, opts...)
+}
+
+// Open is like OpenChain but returns a Chain of words of type T, which must
+// be strings or integers.
+func Open[T comparable](s Store, prefixLen int, opts ...Option) (*Chain[T], error) {
+	if b, ok := s.(builtinStore); ok {
+		return open[T](b.store, prefixLen, opts)
+	}
+	return open[T](customStore{s}, prefixLen, opts)
+}
+
+// A store is the form of a Store a Chain uses, which the stores of this
+// package implement so that a Chain kept in memory can use its
+// suffixes as they are.
+//
+// The Chain's lock is held for reading while get and each are called, and
+// for writing while the other methods are, so a store must only be safe for
+// concurrent calls of get and each.
+type store interface {
+	// get returns the suffixes of the prefix with key k, or nil if it has
+	// none. The suffixes must not be modified.
+	get(k string) (*suffixes, error)
+
+	// add records n more occurrences of the word with the given ID
+	// following the prefix with key k.
+	add(k string, id uint32, n int) error
+
+	// intern, wordsFrom, meta, setMeta and flush are Intern, Words, Meta,
+	// SetMeta and Flush of a Store. Words are encoded by encodeWord.
+	intern(word []byte, next uint32) (uint32, error)
+	wordsFrom(id uint32) ([][]byte, error)
+	meta() ([]byte, error)
+	setMeta(data []byte) error
+	flush() error
+
+	// put sets the suffixes of the prefix with key k, which has none, to s.
+	put(k string, s *suffixes) error
+
+	// remove is Remove of a Store.
+	remove(k string) error
+
+	// each calls fn with every prefix and its suffixes, in no particular
+	// order, until fn returns false.
+	each(fn func(k string, s *suffixes) bool) error
+}
+
+// mapStore is the store of a Chain made by New, which keeps its prefixes in
+// memory and its words only in its vocabulary.
+type mapStore map[string]*suffixes
+
+func (m mapStore) get(k string) (*suffixes, error) {
+	return m[k], nil
+}
+
+func (m mapStore) add(k string, id uint32, n int) error {
+	s := m[k]
+	if s == nil {
+		s = newSuffixes()
+		m[k] = s
+	}
+	s.addCount(id, n)
+	return nil
+}
+
+func (m mapStore) intern(_ []byte, next uint32) (uint32, error) {
+	return next, nil
+}
+
+func (m mapStore) wordsFrom(uint32) ([][]byte, error) {
+	return nil, nil
+}
+
+func (m mapStore) meta() ([]byte, error) {
+	return nil, nil
+}
+
+func (m mapStore) setMeta([]byte) error {
+	return nil
+}
+
+func (m mapStore) put(k string, s *suffixes) error {
+	m[k] = s
+	return nil
+}
+
+func (m mapStore) remove(k string) error {
+	delete(m, k)
+	return nil
+}
+
+func (m mapStore) each(fn func(k string, s *suffixes) bool) error {
+	for k, s := range m {
+		if !fn(k, s) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m mapStore) flush() error {
+	return nil
+}
+
+// lookup is like get, but takes the key as bytes so that looking it up does
+// not allocate.
+func (m mapStore) lookup(k []byte) *suffixes {
+	return m[string(k)]
+}
+
+// record is like add, but takes the key as bytes so that it is only copied
+// if the prefix is new.
+func (m mapStore) record(k []byte, id uint32, n int) {
+	s := m[string(k)]
+	if s == nil {
+		s = newSuffixes()
+		m[string(k)] = s
+	}
+	s.addCount(id, n)
+}
+
+// arrayLen is the longest prefix an arrayStore holds.
+const arrayLen = 4
+
+// An arrayKey is the word IDs of a prefix of at most arrayLen words,
+// followed by zeros.
+type arrayKey [arrayLen]uint32
+
+// arrayStore is the store of a Chain made by New with prefixes of at most
+// arrayLen words, which keeps them in memory keyed by fixed-size arrays, so
+// that recording a new prefix does not allocate its key and each one takes
+// less memory than a string.
+type arrayStore struct {
+	prefixes  map[arrayKey]*suffixes
+	prefixLen int
+}
+
+// newMemoryStore returns the store of a Chain made by New with prefixes of
+// prefixLen words.
+func newMemoryStore(prefixLen int) store {
+	if prefixLen <= arrayLen {
+		return arrayStore{prefixes: make(map[arrayKey]*suffixes), prefixLen: prefixLen}
+	}
+	return make(mapStore)
+}
+
+// arrayKeyOf returns the arrayKey of the prefix with key k.
+func arrayKeyOf(k string) arrayKey {
+	var a arrayKey
+	for i := 0; i < arrayLen && 4*i < len(k); i++ {
+		a[i] = binary.LittleEndian.Uint32([]byte(k[4*i:]))
+	}
+	return a
+}
+
+// windowKey returns the arrayKey of window.
+func windowKey(window Prefix[uint32]) arrayKey {
+	var a arrayKey
+	copy(a[:], window)
+	return a
+}
+
+func (a arrayStore) get(k string) (*suffixes, error) {
+	return a.prefixes[arrayKeyOf(k)], nil
+}
+
+func (a arrayStore) add(k string, id uint32, n int) error {
+	a.record(arrayKeyOf(k), id, n)
+	return nil
+}
+
+func (a arrayStore) intern(_ []byte, next uint32) (uint32, error) {
+	return next, nil
+}
+
+func (a arrayStore) wordsFrom(uint32) ([][]byte, error) {
+	return nil, nil
+}
+
+func (a arrayStore) meta() ([]byte, error) {
+	return nil, nil
+}
+
+func (a arrayStore) setMeta([]byte) error {
+	return nil
+}
+
+func (a arrayStore) put(k string, s *suffixes) error {
+	a.prefixes[arrayKeyOf(k)] = s
+	return nil
+}
+
+func (a arrayStore) remove(k string) error {
+	delete(a.prefixes, arrayKeyOf(k))
+	return nil
+}
+
+func (a arrayStore) each(fn func(k string, s *suffixes) bool) error {
+	for k, s := range a.prefixes {
+		if !fn(key(k[:a.prefixLen]), s) {
+			break
+		}
+	}
+	return nil
+}
+
+func (a arrayStore) flush() error {
+	return nil
+}
+
+// record is like add, but takes the key as an arrayKey.
+func (a arrayStore) record(k arrayKey, id uint32, n int) {
+	s := a.prefixes[k]
+	if s == nil {
+		s = newSuffixes()
+		a.prefixes[k] = s
+	}
+	s.addCount(id, n)
+}
+
+// memoryStore is the store of NewMemoryStore, which unlike a mapStore keeps
+// words and metadata too, so that it can be opened again.
+type memoryStore struct {
+	prefixes mapStore
+	words    [][]byte
+	data     []byte
+}
+
+func (m *memoryStore) get(k string) (*suffixes, error) {
+	return m.prefixes.get(k)
+}
+
+func (m *memoryStore) add(k string, id uint32, n int) error {
+	return m.prefixes.add(k, id, n)
+}
+
+func (m *memoryStore) intern(word []byte, next uint32) (uint32, error) {
+	if int(next) != int(firstID)+len(m.words) {
+		return noID, fmt.Errorf("word ID %d out of sequence", next)
+	}
+	m.words = append(m.words, word)
+	return next, nil
+}
+
+func (m *memoryStore) wordsFrom(id uint32) ([][]byte, error) {
+	i := max(int(id)-int(firstID), 0)
+	if i >= len(m.words) {
+		return nil, nil
+	}
+	return m.words[i:], nil
+}
+
+func (m *memoryStore) meta() ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *memoryStore) setMeta(data []byte) error {
+	m.data = data
+	return nil
+}
+
+func (m *memoryStore) put(k string, s *suffixes) error {
+	return m.prefixes.put(k, s)
+}
+
+func (m *memoryStore) remove(k string) error {
+	return m.prefixes.remove(k)
+}
+
+func (m *memoryStore) each(fn func(k string, s *suffixes) bool) error {
+	return m.prefixes.each(fn)
+}
+
+func (m *memoryStore) flush() error {
+	return nil
+}
+
+// builtinStore is a store of this package as a Store.
+type builtinStore struct {
+	store
+}
+
+func (b builtinStore) Get(prefix string) ([]Suffix, error) {
+	s, err := b.get(prefix)
+	return s.list(), err
+}
+
+func (b builtinStore) Increment(prefix string, id uint32, n int) error {
+	return b.add(prefix, id, n)
+}
+
+func (b builtinStore) Remove(prefix string) error {
+	return b.remove(prefix)
+}
+
+func (b builtinStore) Iterate(fn func(prefix string, suffixes []Suffix) bool) error {
+	return b.each(func(k string, s *suffixes) bool {
+		return fn(k, s.list())
+	})
+}
+
+func (b builtinStore) Intern(word []byte, next uint32) (uint32, error) {
+	return b.intern(word, next)
+}
+
+func (b builtinStore) Words(from uint32) ([][]byte, error) {
+	return b.wordsFrom(from)
+}
+
+func (b builtinStore) Meta() ([]byte, error) {
+	return b.meta()
+}
+
+func (b builtinStore) SetMeta(data []byte) error {
+	return b.setMeta(data)
+}
+
+func (b builtinStore) Flush() error {
+	return b.flush()
+}
+
+// customStore is a Store implemented outside this package as a store.
+type customStore struct {
+	s Store
+}
+
+func (c customStore) get(k string) (*suffixes, error) {
+	list, err := c.s.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	return suffixesOf(list)
+}
+
+func (c customStore) add(k string, id uint32, n int) error {
+	return c.s.Increment(k, id, n)
+}
+
+func (c customStore) intern(word []byte, next uint32) (uint32, error) {
+	return c.s.Intern(word, next)
+}
+
+func (c customStore) wordsFrom(id uint32) ([][]byte, error) {
+	return c.s.Words(id)
+}
+
+func (c customStore) meta() ([]byte, error) {
+	return c.s.Meta()
+}
+
+func (c customStore) setMeta(data []byte) error {
+	return c.s.SetMeta(data)
+}
+
+func (c customStore) flush() error {
+	return c.s.Flush()
+}
+
+func (c customStore) put(k string, s *suffixes) error {
+	for i, id := range s.words {
+		if err := c.s.Increment(k, id, s.counts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c customStore) remove(k string) error {
+	return c.s.Remove(k)
+}
+
+func (c customStore) each(fn func(k string, s *suffixes) bool) error {
+	var err error
+	iterErr := c.s.Iterate(func(prefix string, list []Suffix) bool {
+		var s *suffixes
+		if s, err = suffixesOf(list); err != nil {
+			return false
+		}
+		return s == nil || fn(prefix, s)
+	})
+	if err != nil {
+		return err
+	}
+	return iterErr
+}
+
+// list returns the Suffixes of s, which may be nil.
+func (s *suffixes) list() []Suffix {
+	if s == nil {
+		return nil
+	}
+	list := make([]Suffix, len(s.words))
+	for i, id := range s.words {
+		list[i] = Suffix{ID: id, Count: s.counts[i]}
+	}
+	return list
+}
+
+// suffixesOf returns the suffixes listed, or nil if there are none.
+func suffixesOf(list []Suffix) (*suffixes, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	s := newSuffixes()
+	for _, suf := range list {
+		if suf.ID >= noID || suf.Count < 1 {
+			return nil, fmt.Errorf("invalid suffix %d with count %d", suf.ID, suf.Count)
+		}
+		s.addCount(suf.ID, suf.Count)
+	}
+	return s, nil
+}
+
+// Flush writes any observations buffered by the Chain's store, such as
+// those recorded by Add to a Chain kept in a database, and returns the first
+// error writing them, if any.
+func (c *Chain[T]) Flush() error {
+	return c.flush()
+}
+
+// storeMeta describes the Chain a store holds.
+type storeMeta struct {
+	PrefixLen int          `json:"prefixLength"`
+	WordKind  uint64       `json:"wordKind"`
+	Options   savedOptions `json:"options"`
+}
+
+// open returns a Chain of words of type T with prefixes of prefixLen words
+// kept in s, configured as described by OpenSQLChain.
+func open[T comparable](s store, prefixLen int, opts []Option) (*Chain[T], error) {
+	if prefixLen < 1 {
+		return nil, fmt.Errorf("markov: invalid prefix length %d", prefixLen)
+	}
+	kind, err := wordKind[T]()
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.meta()
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	if data != nil {
+		var meta storeMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("markov: opening chain: invalid chain metadata: %w", err)
+		}
+		if meta.PrefixLen != prefixLen {
+			return nil, fmt.Errorf("markov: opening chain: stored chain has prefixes of %d words, not %d", meta.PrefixLen, prefixLen)
+		}
+		if meta.WordKind != kind {
+			return nil, fmt.Errorf("markov: opening chain: stored words of kind %d cannot be read as %T", meta.WordKind, *new(T))
+		}
+		saved, err := meta.Options.options()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(saved, opts...)
+	}
+
+	c := New[T](prefixLen, opts...)
+	c.store = s
+	if err := c.learn(); err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(storeMeta{PrefixLen: prefixLen, WordKind: kind, Options: c.options.saved()})
+	if err == nil {
+		err = s.setMeta(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("markov: opening chain: %w", err)
+	}
+	return c, nil
+}
+
+// encodeWord returns the encoding of word in a store, or an error if word is
+// not a string or integer.
+func encodeWord(word any) ([]byte, error) {
+	switch w := word.(type) {
+	case string:
+		return []byte(w), nil
+	case int, int8, int16, int32, int64:
+		return binary.AppendVarint(nil, toInt64(w)), nil
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		return binary.AppendUvarint(nil, toUint64(w)), nil
+	}
+	return nil, fmt.Errorf("markov: cannot store words of type %T", word)
+}
+
+// internStored interns the word stored encoded as b in v, checking that it
+// is assigned the ID id it was stored with.
+func internStored[T comparable](v *vocab[T], id uint32, b []byte) error {
+	word, err := decodeWord[T](b)
+	if err != nil {
+		return err
+	}
+	if got := v.intern(word); got != id {
+		return fmt.Errorf("word %v has ID %d, want %d", word, got, id)
+	}
+	return nil
+}
+
+// decodeWord returns the word of type T encoded as b by encodeWord.
+func decodeWord[T comparable](b []byte) (T, error) {
+	var word T
+	switch p := any(&word).(type) {
+	case *string:
+		*p = string(b)
+		return word, nil
+	case *int, *int8, *int16, *int32, *int64:
+		v, n := binary.Varint(b)
+		if n != len(b) {
+			return word, fmt.Errorf("invalid word %x", b)
+		}
+		setInt(p, v)
+	default:
+		v, n := binary.Uvarint(b)
+		if n != len(b) {
+			return word, fmt.Errorf("invalid word %x", b)
+		}
+		setUint(p, v)
+	}
+	return word, nil
+}
@@ -0,0 +1,170 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testStore is a Store implemented as a user of the package would.
+type testStore struct {
+	prefixes map[string][]Suffix
+	words    [][]byte
+	data     []byte
+}
+
+func newTestStore() *testStore {
+	return &testStore{prefixes: make(map[string][]Suffix)}
+}
+
+func (s *testStore) Get(prefix string) ([]Suffix, error) {
+	return s.prefixes[prefix], nil
+}
+
+func (s *testStore) Increment(prefix string, id uint32, n int) error {
+	list := s.prefixes[prefix]
+	for i := range list {
+		if list[i].ID == id {
+			list[i].Count += n
+			return nil
+		}
+	}
+	s.prefixes[prefix] = append(list, Suffix{ID: id, Count: n})
+	return nil
+}
+
+func (s *testStore) Remove(prefix string) error {
+	delete(s.prefixes, prefix)
+	return nil
+}
+
+func (s *testStore) Iterate(fn func(prefix string, suffixes []Suffix) bool) error {
+	for k, list := range s.prefixes {
+		if !fn(k, list) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *testStore) Intern(word []byte, next uint32) (uint32, error) {
+	s.words = append(s.words, word)
+	return next, nil
+}
+
+func (s *testStore) Words(from uint32) ([][]byte, error) {
+	if i := int(from - firstID); i < len(s.words) {
+		return s.words[i:], nil
+	}
+	return nil, nil
+}
+
+func (s *testStore) Meta() ([]byte, error)     { return s.data, nil }
+func (s *testStore) SetMeta(data []byte) error { s.data = data; return nil }
+func (s *testStore) Flush() error              { return nil }
+
+// namedStore is a Store to test and its name.
+type namedStore struct {
+	name string
+	s    Store
+}
+
+// testStores returns an empty Store of each kind.
+func testStores(t *testing.T) []namedStore {
+	t.Helper()
+	sqlStore, err := NewSQLStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSQLStore error: %v", err)
+	}
+	return []namedStore{
+		{"custom", newTestStore()},
+		{"memory", NewMemoryStore()},
+		{"sql", sqlStore},
+	}
+}
+
+func TestOpenChain(t *testing.T) {
+	stores := testStores(t)
+
+	mem := NewChain(2, WithMarkers())
+	mustBuild(t, mem, "I am a c. I am a d. You are a c.")
+	mustBuild(t, mem, "I am a e.")
+	want := chainCounts(mem)
+	wantText, _ := mem.GenerateString(20, WithGreedy())
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := OpenChain(tt.s, 2, WithMarkers())
+			if err != nil {
+				t.Fatalf("OpenChain error: %v", err)
+			}
+			mustBuild(t, c, "I am a c. I am a d. You are a c.")
+
+			// Train further after reopening the store.
+			if _, err := OpenChain(tt.s, 3); err == nil {
+				t.Error("OpenChain with another prefix length succeeded, want error")
+			}
+			c, err = OpenChain(tt.s, 2)
+			if err != nil {
+				t.Fatalf("OpenChain error reopening: %v", err)
+			}
+			if !c.markers {
+				t.Error("reopened chain has no markers, want saved options restored")
+			}
+			mustBuild(t, c, "I am a e.")
+
+			if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+				t.Errorf("chain = %v, want %v", got, want)
+			}
+			if got, err := c.GenerateString(20, WithGreedy()); err != nil || got != wantText {
+				t.Errorf("GenerateString = %q, %v, want %q", got, err, wantText)
+			}
+			if got := c.Count(Prefix[string]{"am", "a"}, "c."); got != 1 {
+				t.Errorf("Count({am a}, c.) = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	s := NewMemoryStore()
+	c, err := OpenChain(s, 1)
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, "a b a c a b")
+
+	id := func(word string) uint32 { return c.vocab.id(word) }
+	got, err := s.Get(key(Prefix[uint32]{id("a")}))
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if want := []Suffix{{id("b"), 2}, {id("c"), 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get({a}) = %v, want %v", got, want)
+	}
+}
+
+func TestOpenChainInvalidSuffixes(t *testing.T) {
+	s := newTestStore()
+	c, err := OpenChain(s, 1)
+	if err != nil {
+		t.Fatalf("OpenChain error: %v", err)
+	}
+	mustBuild(t, c, "a b")
+	for k := range s.prefixes {
+		s.prefixes[k] = []Suffix{{ID: firstID, Count: 0}}
+	}
+	if _, err := c.GenerateString(2); err == nil {
+		t.Error("GenerateString with an invalid count succeeded, want error")
+	}
+}
+
+func TestOpenChainWordsOutOfSync(t *testing.T) {
+	// The store records "a" again at the ID after its own, as a store out
+	// of sync with the vocabulary it was built for would.
+	s := newTestStore()
+	s.words = [][]byte{[]byte("a"), []byte("a")}
+	_, err := OpenChain(s, 1)
+	if want := "markov: word a has ID 2, want 3"; err == nil || err.Error() != want {
+		t.Errorf("OpenChain error = %v, want %q", err, want)
+	}
+}
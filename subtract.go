@@ -0,0 +1,107 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Subtract removes the counts of other, a Chain with prefixes of as many
+// words, from those of the Chain, so that a Chain can unlearn documents
+// retracted from its corpus without training again on the rest: subtracting
+// a Chain built from those documents, with the same options, leaves the
+// Chain building the rest would have built.
+//
+// Counts fall no lower than zero. A suffix whose count reaches zero is
+// removed, as is a prefix left with no suffixes, as Prune removes them, and
+// words remain in the Chain's vocabulary, with their IDs. other is
+// unchanged, and may be the Chain itself, which Subtract then empties.
+func (c *Chain[T]) Subtract(other *Chain[T]) error {
+	if other.prefixLen != c.prefixLen {
+		return fmt.Errorf("markov: cannot subtract a chain with prefixes of %d words from one with prefixes of %d", other.prefixLen, c.prefixLen)
+	}
+	if !slices.Equal(other.skipGram, c.skipGram) {
+		return errors.New("markov: cannot subtract chains skipping different words of their prefixes")
+	}
+	if other.reversed != c.reversed {
+		return errors.New("markov: cannot subtract a reversed chain and one that is not")
+	}
+	if other.backoff != c.backoff {
+		return errors.New("markov: cannot subtract a chain backing off and one that is not")
+	}
+	m, err := other.model()
+	if err != nil {
+		return fmt.Errorf("markov: subtracting chain: %w", err)
+	}
+	return c.subtractModel(m)
+}
+
+// subtractModel removes the counts of the model m, of a Chain with the same
+// prefix length, from the Chain.
+func (c *Chain[T]) subtractModel(m model[T]) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() {
+		if flushErr := c.store.flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	// Words the Chain does not know, even as added by other Chains sharing
+	// its store, have no counts to subtract.
+	if err := c.learn(); err != nil {
+		return err
+	}
+	ids := make([]uint32, len(m.Words))
+	ids[beginID], ids[endID] = beginID, endID
+	for id := firstID; int(id) < len(ids); id++ {
+		ids[id] = c.vocab.id(m.Words[id])
+	}
+
+	prefix := make(Prefix[uint32], c.prefixLen)
+	for _, p := range m.Prefixes {
+		if slices.Max(p.Words) >= uint32(len(ids)) || slices.Max(p.Next) >= uint32(len(ids)) {
+			// Chains sharing the store m was read from have added words since.
+			return errors.New("markov: subtracting chain: chain changed while it was read")
+		}
+		for i, id := range p.Words {
+			prefix[i] = ids[id]
+		}
+		if slices.Contains(prefix, noID) {
+			continue
+		}
+		k := key(prefix)
+		s, err := c.store.get(k)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			continue
+		}
+
+		removed := newSuffixes()
+		for i, id := range p.Next {
+			if ids[id] != noID {
+				removed.addCount(ids[id], p.Counts[i])
+			}
+		}
+		kept := newSuffixes()
+		for i, id := range s.words {
+			if n := s.counts[i] - removed.count(id); n > 0 {
+				kept.addCount(id, n)
+			}
+		}
+		if kept.total == s.total {
+			continue
+		}
+		if err := c.store.remove(k); err != nil {
+			return err
+		}
+		if len(kept.words) > 0 {
+			if err := c.store.put(k, kept); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
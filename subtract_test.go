@@ -0,0 +1,64 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainSubtract(t *testing.T) {
+	kept, retracted := "The cat sat. The dog ran.", "The dog sat. A bird sang."
+	want := NewChain(2, WithMarkers())
+	mustBuild(t, want, kept)
+
+	retraction := NewChain(2, WithMarkers())
+	mustBuild(t, retraction, retracted)
+
+	chains := map[string]*Chain[string]{"memory": NewChain(2, WithMarkers())}
+	for _, tt := range testStores(t) {
+		c, err := OpenChain(tt.s, 2, WithMarkers())
+		if err != nil {
+			t.Fatalf("%s: OpenChain error: %v", tt.name, err)
+		}
+		chains[tt.name] = c
+	}
+	for name, c := range chains {
+		mustBuild(t, c, retracted)
+		mustBuild(t, c, kept)
+		if err := c.Subtract(retraction); err != nil {
+			t.Fatalf("%s: Subtract error: %v", name, err)
+		}
+		if got, want := chainCounts(c), chainCounts(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: chain = %v, want %v", name, got, want)
+		}
+	}
+	if got := retraction.Count(Prefix[string]{"A", "bird"}, "sang."); got != 1 {
+		t.Errorf("other chain Count({A bird}, sang.) = %d, want 1, unchanged", got)
+	}
+}
+
+func TestChainSubtractClamps(t *testing.T) {
+	c := NewChain(1)
+	mustBuild(t, c, "a b a c a c")
+	other := NewChain(1)
+	mustBuild(t, other, "a b a b z a c")
+	if err := c.Subtract(other); err != nil {
+		t.Fatalf("Subtract error: %v", err)
+	}
+	if got, want := c.Suffixes(Prefix[string]{"a"}), map[string]int{"c": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suffixes({a}) = %v, want %v", got, want)
+	}
+	if _, ok := c.vocab.ids["z"]; ok {
+		t.Error("Subtract added a word only the other chain knows")
+	}
+
+	if err := c.Subtract(c); err != nil {
+		t.Fatalf("Subtract of itself error: %v", err)
+	}
+	if got := chainCounts(c); len(got) != 0 {
+		t.Errorf("chain subtracted from itself = %v, want empty", got)
+	}
+	if err := c.Subtract(NewChain(2)); err == nil || !strings.Contains(err.Error(), "prefixes") {
+		t.Errorf("Subtract of another order error = %v, want an error", err)
+	}
+}
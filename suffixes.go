@@ -0,0 +1,217 @@
+package markov
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// suffixes counts the words, by ID, observed to follow a single prefix.
+// Words are kept in the order they were first observed so that sampling with
+// a seeded random source is reproducible.
+//
+// Most prefixes of a large corpus are followed by only a word or two, so
+// words are found by scanning them until there are more than indexMin, and
+// only then by an index.
+type suffixes struct {
+	words  []uint32
+	counts []int
+	index  map[uint32]int // the position of each word, once indexed
+	total  int
+
+	// alias is built by Chain.Compile and discarded when the counts change.
+	alias *aliasTable
+}
+
+// indexMin is the number of words of a suffixes beyond which they are
+// indexed.
+const indexMin = 8
+
+// newSuffixes returns an empty suffixes.
+func newSuffixes() *suffixes {
+	return &suffixes{}
+}
+
+// find returns the position of word in s.words, reporting false if s has
+// not observed it.
+func (s *suffixes) find(word uint32) (int, bool) {
+	if s.index != nil {
+		i, ok := s.index[word]
+		return i, ok
+	}
+	for i, w := range s.words {
+		if w == word {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// add records one more occurrence of word.
+func (s *suffixes) add(word uint32) {
+	s.addCount(word, 1)
+}
+
+// addCount records n more occurrences of word.
+func (s *suffixes) addCount(word uint32, n int) {
+	i, ok := s.find(word)
+	if !ok {
+		i = len(s.words)
+		s.words = append(s.words, word)
+		s.counts = append(s.counts, 0)
+		switch {
+		case s.index != nil:
+			s.index[word] = i
+		case len(s.words) > indexMin:
+			s.index = make(map[uint32]int, len(s.words))
+			for j, w := range s.words {
+				s.index[w] = j
+			}
+		}
+	}
+	s.counts[i] += n
+	s.total += n
+	s.alias = nil
+}
+
+// count returns the number of times word has been observed.
+func (s *suffixes) count(word uint32) int {
+	if i, ok := s.find(word); ok {
+		return s.counts[i]
+	}
+	return 0
+}
+
+// mostFrequent returns the index of s's most frequent word, breaking ties by
+// the order in which the words were first observed.
+func (s *suffixes) mostFrequent() int {
+	best := 0
+	for i, n := range s.counts {
+		if n > s.counts[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// ranked returns the indices of s's words ordered from most to least
+// frequent, breaking ties by the order in which the words were first observed.
+func (s *suffixes) ranked() []int {
+	idx := make([]int, len(s.counts))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return s.counts[idx[a]] > s.counts[idx[b]]
+	})
+	return idx
+}
+
+// pick returns the word whose cumulative count range contains r, which must
+// be in [0, s.total). Drawing r uniformly therefore picks each word with
+// probability proportional to its count.
+func (s *suffixes) pick(r int) uint32 {
+	for i, n := range s.counts {
+		if r < n {
+			return s.words[i]
+		}
+		r -= n
+	}
+	panic("markov: suffix pick out of range")
+}
+
+// aliasTable samples a suffixes' words in constant time using Vose's alias
+// method. Column i holds word i with weight prob[i] out of total, and its
+// alias with the remaining weight. Weights are kept as integers so that the
+// sampled distribution is exactly that of the counts.
+type aliasTable struct {
+	prob  []int
+	alias []int
+	total int
+}
+
+// compile builds the alias table for s.
+func (s *suffixes) compile() {
+	n := len(s.counts)
+	t := &aliasTable{
+		prob:  make([]int, n),
+		alias: make([]int, n),
+		total: s.total,
+	}
+
+	// Scale each count so that the average column weight is total.
+	var small, large []int
+	for i, c := range s.counts {
+		t.prob[i] = c * n
+		if t.prob[i] < t.total {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	for len(small) > 0 && len(large) > 0 {
+		l := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		t.alias[l] = g
+		t.prob[g] -= t.total - t.prob[l]
+		if t.prob[g] < t.total {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+
+	// Whatever remains fills its column completely.
+	for _, i := range append(small, large...) {
+		t.prob[i] = t.total
+	}
+
+	s.alias = t
+}
+
+// pick returns the word of the column col, or its alias, depending on
+// whether r falls within the column's own weight. col must be in
+// [0, len(t.prob)) and r in [0, t.total).
+func (t *aliasTable) pick(col, r int) int {
+	if r < t.prob[col] {
+		return col
+	}
+	return t.alias[col]
+}
+
+// raise raises the count of the word at position i to n, which is at least
+// its count.
+func (s *suffixes) raise(i, n int) {
+	s.total += n - s.counts[i]
+	s.counts[i] = n
+	s.alias = nil
+}
+
+// replace replaces the word at position i with word, which s has not
+// observed, observed n times.
+func (s *suffixes) replace(i int, word uint32, n int) {
+	if s.index != nil {
+		delete(s.index, s.words[i])
+		s.index[word] = i
+	}
+	s.words[i] = word
+	s.total += n - s.counts[i]
+	s.counts[i] = n
+	s.alias = nil
+}
+
+// clone returns a copy of s sharing none of its slices and maps. Its alias
+// table, which is never modified, is shared.
+func (s *suffixes) clone() *suffixes {
+	return &suffixes{
+		words:  slices.Clone(s.words),
+		counts: slices.Clone(s.counts),
+		index:  maps.Clone(s.index),
+		total:  s.total,
+		alias:  s.alias,
+	}
+}
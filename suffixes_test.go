@@ -0,0 +1,70 @@
+package markov
+
+import "testing"
+
+func TestAliasTableDistribution(t *testing.T) {
+	tests := [][]int{
+		{1},
+		{1, 1},
+		{3, 1},
+		{1, 2, 3, 4},
+		{7, 1, 1, 1, 10, 2},
+		{100, 1},
+	}
+	for _, counts := range tests {
+		s := newSuffixes()
+		for i, n := range counts {
+			for j := 0; j < n; j++ {
+				s.add(uint32(i))
+			}
+		}
+		s.compile()
+
+		// Enumerating every (column, r) pair must hit each word exactly as
+		// many times per column as it was counted.
+		hits := make([]int, len(counts))
+		for col := range s.words {
+			for r := 0; r < s.total; r++ {
+				hits[s.alias.pick(col, r)]++
+			}
+		}
+		for i, n := range counts {
+			if want := n * len(counts); hits[i] != want {
+				t.Errorf("counts %v: word %d hit %d times, want %d", counts, i, hits[i], want)
+			}
+		}
+	}
+}
+
+func TestSuffixesAddDiscardsAlias(t *testing.T) {
+	s := newSuffixes()
+	s.add('a')
+	s.compile()
+	s.add('b')
+	if s.alias != nil {
+		t.Error("alias table kept after add")
+	}
+}
+
+func TestSuffixesIndex(t *testing.T) {
+	s := newSuffixes()
+	for round := 1; round <= 2; round++ {
+		for id := uint32(20); id > 0; id-- {
+			s.add(id)
+			if indexed := len(s.words) > indexMin; (s.index != nil) != indexed {
+				t.Fatalf("with %d words, index = %v, want indexed %v", len(s.words), s.index, indexed)
+			}
+		}
+	}
+	for i, id := range s.words {
+		if want := uint32(20 - i); id != want {
+			t.Errorf("words[%d] = %d, want %d in the order first observed", i, id, want)
+		}
+		if got := s.count(id); got != 2 {
+			t.Errorf("count(%d) = %d, want 2", id, got)
+		}
+	}
+	if got := s.count(21); got != 0 {
+		t.Errorf("count(21) = %d, want 0", got)
+	}
+}
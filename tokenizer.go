@@ -0,0 +1,154 @@
+package markov
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A Tokenizer breaks text into words.
+type Tokenizer interface {
+	// Next returns the next word, or io.EOF once the text is exhausted.
+	Next() (string, error)
+}
+
+// WithTokenizer makes the Chain break its input, as well as prompts and stop
+// sequences, into words with the Tokenizer newTokenizer returns for each
+// text, in place of the splitting done by its Mode. The Mode still decides
+// how generated words are separated.
+//
+// A Tokenizer produces strings, so Build fails for Chains of any other type
+// of word.
+func WithTokenizer(newTokenizer func(r io.Reader) Tokenizer) Option {
+	return func(o *options) {
+		o.tokenizer = newTokenizer
+	}
+}
+
+// ScanTokenizer returns a function for WithTokenizer whose Tokenizers split
+// text as a bufio.Scanner does with split, such as bufio.ScanWords or
+// bufio.ScanLines.
+func ScanTokenizer(split bufio.SplitFunc) func(r io.Reader) Tokenizer {
+	return func(r io.Reader) Tokenizer {
+		s := bufio.NewScanner(r)
+		s.Split(split)
+		return scanTokenizer{s}
+	}
+}
+
+// RegexpTokenizer returns a function for WithTokenizer whose Tokenizers
+// split text into the successive non-overlapping, non-empty matches of re,
+// skipping the text between them. For example, `[\w'-]+` keeps hyphenated words
+// together and `[A-Z]?[a-z]+|\d+` splits camelCase identifiers.
+// Text is matched a line at a time, so matches never span lines.
+func RegexpTokenizer(re *regexp.Regexp) func(r io.Reader) Tokenizer {
+	return func(r io.Reader) Tokenizer {
+		return &regexpTokenizer{re: re, r: bufio.NewReader(r)}
+	}
+}
+
+// regexpTokenizer is a Tokenizer of the matches of a regular expression.
+type regexpTokenizer struct {
+	re      *regexp.Regexp
+	r       *bufio.Reader
+	matches []string
+	err     error
+}
+
+func (t *regexpTokenizer) Next() (string, error) {
+	for {
+		for len(t.matches) > 0 {
+			word := t.matches[0]
+			t.matches = t.matches[1:]
+			if word != "" {
+				return word, nil
+			}
+		}
+		if t.err != nil {
+			return "", t.err
+		}
+
+		var line string
+		line, t.err = t.r.ReadString('\n')
+		t.matches = t.re.FindAllString(line, -1)
+	}
+}
+
+// maxWordSize is the longest word, in bytes, that Build reads in the Words
+// Mode, far longer than any word of natural text.
+const maxWordSize = 1 << 20
+
+// newWordScanner returns a Scanner of the whitespace-separated words of r,
+// which reads them far faster than fmt.Fscan.
+func newWordScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxWordSize)
+	s.Split(bufio.ScanWords)
+	return s
+}
+
+// scanTokenizer is a Tokenizer backed by a bufio.Scanner.
+type scanTokenizer struct {
+	s *bufio.Scanner
+}
+
+func (t scanTokenizer) Next() (string, error) {
+	if t.s.Scan() {
+		return t.s.Text(), nil
+	}
+	if err := t.s.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// tokenize returns a function returning the successive words of r, up to
+// an io.EOF, as split by the Chain's Tokenizer or else its Mode, made
+// canonical and filtered.
+func (c *Chain[T]) tokenize(r io.Reader) func() (T, error) {
+	r = c.normalizeReader(r)
+	if _, ok := any(*new(T)).(string); ok && c.tokenizer == nil && c.mode == Words {
+		t := scanTokenizer{newWordScanner(r)}
+		return c.filter(func() (T, error) {
+			s, err := t.Next()
+			return c.canonical(any(s).(T)), err
+		})
+	}
+	if c.tokenizer == nil {
+		bufReader := bufio.NewReader(r)
+		return c.filter(func() (T, error) {
+			word, err := c.readToken(bufReader)
+			return c.canonical(word), err
+		})
+	}
+
+	t := c.tokenizer(r)
+	return c.filter(func() (T, error) {
+		var word T
+		s, err := t.Next()
+		if err != nil {
+			return word, err
+		}
+		word, ok := any(s).(T)
+		if !ok {
+			return word, fmt.Errorf("markov: cannot tokenize text into words of type %T", word)
+		}
+		return c.canonical(word), nil
+	})
+}
+
+// split splits text into words the same way Build splits its input,
+// stopping at the first word that cannot be read.
+func (c *Chain[T]) split(text string) []T {
+	next := c.tokenize(strings.NewReader(text))
+	var words []T
+	for {
+		word, err := next()
+		if err != nil {
+			return words
+		}
+		words = append(words, word)
+	}
+}
@@ -0,0 +1,135 @@
+package markov
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// commaTokenizer splits text at commas.
+type commaTokenizer struct {
+	fields []string
+}
+
+func newCommaTokenizer(r io.Reader) Tokenizer {
+	b, _ := io.ReadAll(r)
+	if len(b) == 0 {
+		return &commaTokenizer{}
+	}
+	return &commaTokenizer{strings.Split(string(b), ",")}
+}
+
+func (t *commaTokenizer) Next() (string, error) {
+	if len(t.fields) == 0 {
+		return "", io.EOF
+	}
+	word := t.fields[0]
+	t.fields = t.fields[1:]
+	return word, nil
+}
+
+func TestWithTokenizer(t *testing.T) {
+	c := NewChain(1, WithTokenizer(newCommaTokenizer))
+	mustBuild(t, c, "new york,is,big,new york,is,old")
+
+	want := map[string]map[string]int{
+		"":         {"new york": 1},
+		"new york": {"is": 2},
+		"is":       {"big": 1, "old": 1},
+		"big":      {"new york": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+
+	got, err := c.GenerateString(3, WithPrompt("big"), WithGreedy())
+	if err != nil {
+		t.Fatalf("GenerateString error: %v", err)
+	}
+	if want := "new york is big"; got != want {
+		t.Errorf("GenerateString = %q, want %q", got, want)
+	}
+}
+
+func TestScanTokenizer(t *testing.T) {
+	tok := ScanTokenizer(bufio.ScanLines)(strings.NewReader("one line\nanother line\n"))
+
+	var got []string
+	for {
+		word, err := tok.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		got = append(got, word)
+	}
+	if want := []string{"one line", "another line"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("words = %q, want %q", got, want)
+	}
+}
+
+func TestScanTokenizerReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	c := NewChain(1, WithTokenizer(ScanTokenizer(bufio.ScanWords)))
+	if err := c.Build(errReader{readErr}); !errors.Is(err, readErr) {
+		t.Errorf("Build error = %v, want %v", err, readErr)
+	}
+}
+
+func TestWithTokenizerNotStrings(t *testing.T) {
+	c := New[int](1, WithTokenizer(ScanTokenizer(bufio.ScanWords)))
+	if err := c.Build(strings.NewReader("1 2 3")); err == nil {
+		t.Error("Build of ints with a Tokenizer succeeded, want error")
+	}
+}
+
+func TestRegexpTokenizer(t *testing.T) {
+	tests := []struct {
+		expr string
+		text string
+		want []string
+	}{
+		{`[\w'-]+`, "a well-known fact, isn't it?\nyes", []string{"a", "well-known", "fact", "isn't", "it", "yes"}},
+		{`[A-Z]?[a-z]+|\d+`, "parseHtmlHeader2 readAll", []string{"parse", "Html", "Header", "2", "read", "All"}},
+		{`x*`, "axxbx", []string{"xx", "x"}},
+	}
+	for _, tt := range tests {
+		c := NewChain(1, WithTokenizer(RegexpTokenizer(regexp.MustCompile(tt.expr))))
+		if got := c.split(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: split(%q) = %q, want %q", tt.expr, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRegexpTokenizerReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a b"), errReader{readErr})
+	c := NewChain(1, WithTokenizer(RegexpTokenizer(regexp.MustCompile(`\w+`))))
+	if err := c.Build(r); !errors.Is(err, readErr) {
+		t.Errorf("Build error = %v, want %v", err, readErr)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 1 {
+		t.Errorf("Count(a, b) = %d, want 1", got)
+	}
+}
+
+func TestChainBuildLongWords(t *testing.T) {
+	long := strings.Repeat("x", bufio.MaxScanTokenSize*2)
+	c := NewChain(1)
+	mustBuild(t, c, "a\t"+long+"\n a b ")
+	if got := c.Count(Prefix[string]{"a"}, long); got != 1 {
+		t.Errorf("Count({a}, long word) = %d, want 1", got)
+	}
+	if got := c.Count(Prefix[string]{long}, "a"); got != 1 {
+		t.Errorf("Count({long word}, a) = %d, want 1", got)
+	}
+	if got := c.Count(Prefix[string]{"a"}, "b"); got != 1 {
+		t.Errorf("Count({a}, b) = %d, want 1 with Unicode spaces between them", got)
+	}
+}
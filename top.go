@@ -0,0 +1,112 @@
+package markov
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// A PrefixCount is a prefix and the number of times it was observed, as
+// returned by Chain.TopPrefixes.
+type PrefixCount[T comparable] struct {
+	// Prefix holds the words of the prefix, leaving out the padding of a
+	// prefix at the start of a text, so that it is shorter than the Chain's
+	// prefixes there, as WithPrompt and Count expect.
+	Prefix Prefix[T]
+	Count  int
+}
+
+// TopPrefixes returns the k prefixes observed most often, most often first,
+// with the number of times each was observed, or every prefix if k is less
+// than 1. Prefixes observed equally often are ordered by when their words
+// were first observed. Only k prefixes are held at a time, however large
+// the Chain.
+func (c *Chain[T]) TopPrefixes(k int) ([]PrefixCount[T], error) {
+	c.mu.RLock()
+	var h prefixHeap
+	err := c.store.each(func(key string, s *suffixes) bool {
+		if c.isBackoffContext(key) {
+			return true
+		}
+		p := keyCount{key, s.total}
+		switch {
+		case k < 1 || h.Len() < k:
+			heap.Push(&h, p)
+		case h.less(h[0], p):
+			h[0] = p
+			heap.Fix(&h, 0)
+		}
+		return true
+	})
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("markov: reading chain: %w", err)
+	}
+
+	slices.SortFunc(h, func(a, b keyCount) int {
+		if h.less(a, b) {
+			return 1
+		}
+		return -1
+	})
+	top := make([]PrefixCount[T], len(h))
+	for i, p := range h {
+		var ids []uint32
+		for _, id := range unkey(p.key) {
+			if id != beginID {
+				ids = append(ids, id)
+			}
+		}
+		words, err := c.lookup(ids)
+		if err != nil {
+			return nil, err
+		}
+		top[i] = PrefixCount[T]{Prefix[T](words), p.count}
+	}
+	return top, nil
+}
+
+// A keyCount is the key of a prefix and the number of times it was
+// observed.
+type keyCount struct {
+	key   string
+	count int
+}
+
+// A prefixHeap holds keyCounts, the least frequently observed first.
+type prefixHeap []keyCount
+
+// less reports whether a ranks below b: whether it was observed less often,
+// or as often and its words have greater IDs.
+func (prefixHeap) less(a, b keyCount) bool {
+	if c := cmp.Compare(a.count, b.count); c != 0 {
+		return c < 0
+	}
+	return compareKeys(a.key, b.key) > 0
+}
+
+// compareKeys compares the word IDs of the prefixes with keys a and b, of
+// the same length, in order.
+func compareKeys(a, b string) int {
+	for i := 0; i+4 <= len(a); i += 4 {
+		x := binary.LittleEndian.Uint32([]byte(a[i : i+4]))
+		y := binary.LittleEndian.Uint32([]byte(b[i : i+4]))
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (h prefixHeap) Len() int           { return len(h) }
+func (h prefixHeap) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h prefixHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *prefixHeap) Push(x any)        { *h = append(*h, x.(keyCount)) }
+func (h *prefixHeap) Pop() any {
+	old := *h
+	p := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return p
+}
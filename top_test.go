@@ -0,0 +1,37 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChainTopPrefixes(t *testing.T) {
+	c := NewChain(1, WithMarkers())
+	mustBuild(t, c, "a b. a c. b a b.")
+
+	// Ties are broken by the order the words were first observed.
+	want := []PrefixCount[string]{
+		{Prefix[string]{}, 3},
+		{Prefix[string]{"a"}, 3},
+		{Prefix[string]{"b."}, 2},
+		{Prefix[string]{"c."}, 1},
+		{Prefix[string]{"b"}, 1},
+	}
+
+	all, err := c.TopPrefixes(0)
+	if err != nil {
+		t.Fatalf("TopPrefixes error: %v", err)
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("TopPrefixes(0) = %v, want %v", all, want)
+	}
+	for k := 1; k <= len(want); k++ {
+		got, err := c.TopPrefixes(k)
+		if err != nil {
+			t.Fatalf("TopPrefixes error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want[:k]) {
+			t.Errorf("TopPrefixes(%d) = %v, want %v", k, got, want[:k])
+		}
+	}
+}
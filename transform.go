@@ -0,0 +1,32 @@
+package markov
+
+// WithTokenTransform makes the Chain replace each word of its input, and
+// each word given to Add, Count, prompts and stop sequences, with fn(word),
+// which allows stemming, accent stripping or any other canonicalization
+// without writing a Tokenizer. Several transforms apply in the order given,
+// after WithNormalization and WithLowercase and before WithStopWords. A word
+// transformed into the empty string is dropped.
+//
+// Transforms apply only to Chains of strings.
+func WithTokenTransform(fn func(word string) string) Option {
+	return func(o *options) {
+		o.transforms = append(o.transforms, fn)
+	}
+}
+
+// canonical returns word as the Chain records it: folded to lower case and
+// transformed as configured WithLowercase and WithTokenTransform.
+func (c *Chain[T]) canonical(word T) T {
+	word = c.fold(word)
+	if len(c.transforms) == 0 {
+		return word
+	}
+	s, ok := any(word).(string)
+	if !ok {
+		return word
+	}
+	for _, fn := range c.transforms {
+		s = fn(s)
+	}
+	return any(s).(T)
+}
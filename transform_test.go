@@ -0,0 +1,32 @@
+package markov
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithTokenTransform(t *testing.T) {
+	stem := func(word string) string { return strings.TrimSuffix(word, "s") }
+	noDigits := func(word string) string {
+		if strings.ContainsAny(word, "0123456789") {
+			return ""
+		}
+		return word
+	}
+	c := NewChain(1, WithLowercase(), WithTokenTransform(stem), WithTokenTransform(noDigits), WithStopWords("the"))
+	mustBuild(t, c, "Cats chase 3 dogs the cat chases dog2")
+
+	want := map[string]map[string]int{
+		"":      {"cat": 1},
+		"cat":   {"chase": 2},
+		"chase": {"dog": 1},
+		"dog":   {"cat": 1},
+	}
+	if got := chainCounts(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+	if got := c.Count(Prefix[string]{"Cats"}, "chases"); got != 2 {
+		t.Errorf("Count(Cats, chases) = %d, want 2", got)
+	}
+}
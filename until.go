@@ -0,0 +1,32 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotAccepted is returned by GenerateUntil when none of the texts it
+// generated was accepted.
+var ErrNotAccepted = errors.New("markov: no generated text was accepted")
+
+// GenerateUntil generates texts of at most n words, as GenerateString would,
+// until accept reports true of one, such as one long enough, holding a
+// keyword or passing a filter, and returns that text. It returns
+// ErrNotAccepted if accept rejects all of maxAttempts texts. The same opts
+// apply to each text, a random source given WithRand being drawn from in
+// turn as GenerateN draws from it.
+func (c *Chain[T]) GenerateUntil(n int, accept func(string) bool, maxAttempts int, opts ...GenerateOption) (string, error) {
+	if maxAttempts < 1 {
+		return "", fmt.Errorf("markov: invalid number of attempts %d", maxAttempts)
+	}
+	for range maxAttempts {
+		text, err := c.GenerateString(n, opts...)
+		if err != nil {
+			return "", err
+		}
+		if accept(text) {
+			return text, nil
+		}
+	}
+	return "", ErrNotAccepted
+}
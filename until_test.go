@@ -0,0 +1,65 @@
+package markov
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestChainGenerateUntil(t *testing.T) {
+	c := New[string](1, WithMarkers())
+	for _, text := range []string{"the cat sat.", "the dog ran.", "the bird flew."} {
+		if err := c.Build(strings.NewReader(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attempts := 0
+	accept := func(text string) bool {
+		attempts++
+		return strings.Contains(text, "bird")
+	}
+	got, err := c.GenerateUntil(10, accept, 100, WithRand(rand.New(rand.NewSource(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "the bird flew."; got != want {
+		t.Errorf("GenerateUntil = %q, want %q", got, want)
+	}
+
+	// The texts generated are those GenerateN would generate.
+	texts, err := c.GenerateN(10, attempts, WithRand(rand.New(rand.NewSource(1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texts[attempts-1] != got {
+		t.Errorf("GenerateUntil accepted %q after %d attempts, but GenerateN generated %q", got, attempts, texts)
+	}
+	for _, text := range texts[:attempts-1] {
+		if accept(text) {
+			t.Errorf("GenerateUntil did not accept the earlier text %q", text)
+		}
+	}
+}
+
+func TestChainGenerateUntilNotAccepted(t *testing.T) {
+	c := New[string](1)
+	if err := c.Build(strings.NewReader("the cat sat")); err != nil {
+		t.Fatal(err)
+	}
+	attempts := 0
+	_, err := c.GenerateUntil(10, func(string) bool {
+		attempts++
+		return false
+	}, 3)
+	if !errors.Is(err, ErrNotAccepted) {
+		t.Errorf("GenerateUntil error = %v, want ErrNotAccepted", err)
+	}
+	if attempts != 3 {
+		t.Errorf("GenerateUntil made %d attempts, want 3", attempts)
+	}
+	if _, err := c.GenerateUntil(10, func(string) bool { return true }, 0); err == nil {
+		t.Error("GenerateUntil with no attempts succeeded")
+	}
+}
@@ -0,0 +1,100 @@
+package markov
+
+import (
+	"encoding/binary"
+	"math"
+	"unique"
+)
+
+// Word IDs with a fixed meaning in every Chain.
+const (
+	// beginID pads the prefixes of the words at the start of a text.
+	beginID uint32 = iota
+
+	// endID is recorded as the suffix of the last word of a text or
+	// sentence.
+	endID
+
+	// firstID is the ID of the first word a Chain observes.
+	firstID
+)
+
+// noID stands for a word a Chain has never observed.
+const noID = math.MaxUint32
+
+// vocab assigns each distinct word of a Chain a small integer ID, so that
+// prefixes of any type of word can be used as map keys.
+//
+// Words that are strings are interned process-wide, so that every Chain
+// built from the same language, and every copy of a Chain, shares the
+// storage of the words they have in common. The handles keep them interned.
+type vocab[T comparable] struct {
+	ids     map[T]uint32
+	words   []T
+	handles []unique.Handle[string]
+}
+
+// newVocab returns a vocab holding only the reserved IDs. For words of type
+// string, the Begin and End markers label the reserved IDs without being
+// their words, so that a text holding "<s>" or "</s>" records them as any
+// other word.
+func newVocab[T comparable]() vocab[T] {
+	v := vocab[T]{
+		ids:   make(map[T]uint32),
+		words: make([]T, firstID),
+	}
+	if begin, ok := any(Begin).(T); ok {
+		v.words[beginID] = begin
+	}
+	if end, ok := any(End).(T); ok {
+		v.words[endID] = end
+	}
+	return v
+}
+
+// intern returns the ID of word, assigning it the next ID if it has none.
+func (v *vocab[T]) intern(word T) uint32 {
+	id, ok := v.ids[word]
+	if !ok {
+		if s, isString := any(word).(string); isString {
+			h := unique.Make(s)
+			v.handles = append(v.handles, h)
+			word = any(h.Value()).(T)
+		}
+		id = uint32(len(v.words))
+		v.ids[word] = id
+		v.words = append(v.words, word)
+	}
+	return id
+}
+
+// id returns the ID of word, or noID if word has never been interned.
+func (v *vocab[T]) id(word T) uint32 {
+	if id, ok := v.ids[word]; ok {
+		return id
+	}
+	return noID
+}
+
+// key returns the map key of a prefix of word IDs.
+func key(p Prefix[uint32]) string {
+	return string(appendKey(make([]byte, 0, 4*len(p)), p))
+}
+
+// appendKey appends the map key of a prefix of word IDs to b, so that it
+// can be looked up in a mapStore without allocating.
+func appendKey(b []byte, p Prefix[uint32]) []byte {
+	for _, id := range p {
+		b = binary.LittleEndian.AppendUint32(b, id)
+	}
+	return b
+}
+
+// unkey returns the prefix of word IDs whose map key is k.
+func unkey(k string) Prefix[uint32] {
+	p := make(Prefix[uint32], len(k)/4)
+	for i := range p {
+		p[i] = binary.LittleEndian.Uint32([]byte(k[4*i:]))
+	}
+	return p
+}
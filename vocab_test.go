@@ -0,0 +1,20 @@
+package markov
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestVocabInternsStrings(t *testing.T) {
+	a, b := NewChain(1), NewChain(1)
+	mustBuild(t, a, "the cat sat")
+	mustBuild(t, b, strings.Clone("the dog sat"))
+
+	for _, word := range []string{"the", "sat"} {
+		wa, wb := a.vocab.words[a.vocab.id(word)], b.vocab.words[b.vocab.id(word)]
+		if unsafe.StringData(wa) != unsafe.StringData(wb) {
+			t.Errorf("chains hold separate copies of %q, want them shared", word)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package markov
+
+// WittenBell returns a Smoother estimating probabilities by interpolated
+// Witten-Bell smoothing. The probability of a word following a prefix is
+// mixed with its probability following the prefix one word shorter, and so
+// on down to every word alike, each weighing the shorter prefix by how many
+// different words were observed following the prefix, out of that and the
+// number of times any word was: a prefix followed by many different words
+// is likely to be followed by yet another.
+//
+// It is simpler than KneserNey, needs no discounts estimated, and does
+// nearly as well on all but the smallest corpora. The Chain need not be
+// created WithBackoff; the shorter prefixes are derived from the full ones.
+// Only words never observed at all are left to WithUnseenProbability.
+// Scoring with WittenBell first reads every prefix of the Chain to count the
+// words observed following the shorter prefixes.
+func WittenBell() Smoother {
+	return wittenBellSmoother{}
+}
+
+// wittenBellSmoother is the Smoother of WittenBell.
+type wittenBellSmoother struct{}
+
+func (wittenBellSmoother) estimator(src source) (estimator, error) {
+	lower, err := shorterCounts(src, func(s *suffixes, _ Prefix[uint32], id uint32, n int) bool {
+		s.addCount(id, n)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &wittenBell{src, lower}, nil
+}
+
+// wittenBell is the estimator of WittenBell.
+type wittenBell struct {
+	src source
+
+	// lower holds the suffixes of each context shorter than the Chain's
+	// prefixes by its number of words and key.
+	lower []map[string]*suffixes
+}
+
+func (wb *wittenBell) prob(window Prefix[uint32], id uint32) (float64, error) {
+	if id == noID {
+		return 0, nil
+	}
+	p := 1 / float64(wb.src.words)
+	for i := 0; i <= len(window); i++ {
+		var s *suffixes
+		if i == len(window) {
+			var err error
+			if s, err = wb.src.get(window); err != nil {
+				return 0, err
+			}
+		} else {
+			s = wb.lower[i][key(window[len(window)-i:])]
+		}
+		if s == nil {
+			continue
+		}
+		types := float64(len(s.words))
+		p = (float64(s.count(id)) + types*p) / (float64(s.total) + types)
+	}
+	return p, nil
+}
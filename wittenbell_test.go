@@ -0,0 +1,85 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChainWittenBell(t *testing.T) {
+	text := "the cat sat on the mat. the dog sat on the log. a cat ate the fish. the dog ate."
+	c := NewChain(2, WithMarkers())
+	mustBuild(t, c, text)
+	wb, err := WittenBell().estimator(c.source())
+	if err != nil {
+		t.Fatalf("estimator error: %v", err)
+	}
+
+	// The probabilities of every word following a context, seen or not, sum
+	// to 1.
+	ids := func(words ...string) Prefix[uint32] {
+		window := make(Prefix[uint32], len(words))
+		for i, w := range words {
+			window[i] = c.vocab.id(w)
+		}
+		return window
+	}
+	for _, window := range []Prefix[uint32]{ids("the", "cat"), ids("sat", "on"), ids("fish.", "the"), ids("z", "dog"), {beginID, beginID}} {
+		sum := 0.0
+		for id := endID; int(id) < len(c.vocab.words); id++ {
+			p, err := wb.prob(window, id)
+			if err != nil {
+				t.Fatalf("prob error: %v", err)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("probabilities following %v sum to %v, want 1", c.words(window), sum)
+		}
+	}
+
+	// A prefix followed by one word only is less likely to be followed by
+	// another than one followed by several.
+	once, _ := wb.prob(ids("sat", "on"), c.vocab.id("mat."))
+	many, _ := wb.prob(ids("on", "the"), c.vocab.id("fish."))
+	if !(once < many) {
+		t.Errorf("P(mat. | sat on) = %v, want below P(fish. | on the) = %v", once, many)
+	}
+
+	unseen := strings.Fields("the cat ate the log.")
+	if got := c.Score(unseen); !math.IsInf(got, -1) {
+		t.Errorf("Score(%q) = %v, want -Inf without smoothing", unseen, got)
+	}
+	smoothed := c.Score(unseen, WithSmoother(WittenBell()))
+	if math.IsInf(smoothed, -1) || math.IsNaN(smoothed) {
+		t.Errorf("Score(%q) with WittenBell = %v, want finite", unseen, smoothed)
+	}
+	if seen := c.Score(strings.Fields("the cat sat on the mat."), WithSmoother(WittenBell())); !(seen > smoothed) {
+		t.Errorf("Score of a seen text with WittenBell = %v, want above %v", seen, smoothed)
+	}
+}
+
+func TestChainSmoothers(t *testing.T) {
+	c := NewChain(2, WithMarkers(), WithBackoff())
+	mustBuild(t, c, strings.Repeat("the cat sat on the mat. the dog sat on the log. ", 3)+"a cat ate the fish. the dog ate.")
+
+	// Every Smoother gives a held-out text a finite perplexity that the
+	// counts alone would not.
+	heldOut := "the cat ate the fish. the dog sat on the mat."
+	if p, _ := c.Perplexity(strings.NewReader(heldOut)); !math.IsInf(p, 1) {
+		t.Errorf("Perplexity without smoothing = %v, want +Inf", p)
+	}
+	for name, s := range map[string]Smoother{
+		"Additive":    Additive(0.1),
+		"KatzBackoff": KatzBackoff(),
+		"KneserNey":   KneserNey(),
+		"WittenBell":  WittenBell(),
+	} {
+		p, err := c.Perplexity(strings.NewReader(heldOut), WithSmoother(s))
+		if err != nil {
+			t.Errorf("%s: Perplexity error: %v", name, err)
+		} else if math.IsInf(p, 1) || math.IsNaN(p) || p < 1 {
+			t.Errorf("%s: Perplexity = %v, want finite", name, p)
+		}
+	}
+}